@@ -0,0 +1,334 @@
+package gobatis
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// aggregatePrefixes 是按方法名生成聚合语句时识别的前缀，方法名的格式为 前缀 + 字段名 + [By...]，
+// 如 SumAmountByUserID、MaxCreatedAt、DistinctStatus，Exists 没有字段名，如 ExistsByUsername
+var aggregatePrefixes = []string{"Sum", "Max", "Min", "Avg", "Exists", "Distinct"}
+
+// SplitAggregateMethod 从方法名中取出聚合函数(小写)和字段名，方法名不是聚合方法时返回 false
+func SplitAggregateMethod(name string) (fn, field string, ok bool) {
+	for _, prefix := range aggregatePrefixes {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		rest := name[len(prefix):]
+		if rest != "" && !unicode.IsUpper(rune(rest[0])) {
+			continue
+		}
+		fn = strings.ToLower(prefix)
+
+		for idx := 0; idx+2 <= len(rest); idx++ {
+			if rest[idx:idx+2] != "By" {
+				continue
+			}
+			if idx+2 == len(rest) || unicode.IsUpper(rune(rest[idx+2])) {
+				rest = rest[:idx]
+				break
+			}
+		}
+
+		if fn == "exists" {
+			return fn, "", true
+		}
+		if rest == "" {
+			return "", "", false
+		}
+		return fn, rest, true
+	}
+	return "", "", false
+}
+
+// GenerateAggregateSQL 按方法名生成 sum/max/min/avg/exists/distinct 语句，条件的生成规则与 GenerateCountSQL 相同，
+// resultType 是方法的返回值类型，它必须与字段的类型兼容
+func GenerateAggregateSQL(dbType Dialect, mapper *Mapper, rType reflect.Type, method string, resultType reflect.Type, names []string, argTypes []reflect.Type, filters []Filter) (string, error) {
+	fn, fieldName, ok := SplitAggregateMethod(method)
+	if !ok {
+		return "", errors.New("method '" + method + "' isnot a aggregate method")
+	}
+
+	tableName, err := ReadTableName(mapper, rType)
+	if err != nil {
+		return "", err
+	}
+
+	var column string
+	if fn != "exists" {
+		field, _, err := toFieldName(mapper.TypeMap(rType), fieldName, nil)
+		if err != nil {
+			return "", err
+		}
+		if err := checkAggregateType(fn, field.Field.Type, resultType); err != nil {
+			return "", errors.New("method '" + method + "' " + err.Error())
+		}
+		column = quoteName(dbType, mapper, field.Name)
+	} else if kind, _ := valueKind(resultType); kind != reflect.Bool {
+		return "", errors.New("method '" + method + "' result type '" + resultType.String() + "' isnot bool")
+	}
+
+	var sb strings.Builder
+	switch fn {
+	case "exists":
+		sb.WriteString("SELECT 1 FROM ")
+	case "distinct":
+		sb.WriteString("SELECT DISTINCT ")
+		sb.WriteString(column)
+		sb.WriteString(" FROM ")
+	default:
+		sb.WriteString("SELECT ")
+		sb.WriteString(fn)
+		sb.WriteString("(")
+		sb.WriteString(column)
+		sb.WriteString(") FROM ")
+	}
+	sb.WriteString(quoteName(dbType, mapper, tableName))
+
+	exprs := toFilters(filters, dbType)
+	if len(names) > 0 {
+		err := generateWhere(dbType, mapper, rType, names, argTypes, exprs, StatementTypeSelect, false, &sb)
+		if err != nil {
+			return "", err
+		}
+	} else {
+		writeConditions(&sb, noArgConditions(dbType, mapper, rType, exprs, true))
+	}
+
+	if fn == "exists" {
+		return dbType.Exists(sb.String()), nil
+	}
+	return sb.String(), nil
+}
+
+// checkAggregateType 检查聚合的结果能否保存到 resultType 中
+func checkAggregateType(fn string, fieldType, resultType reflect.Type) error {
+	if fn == "distinct" {
+		if resultType.Kind() != reflect.Slice {
+			return errors.New("result type '" + resultType.String() + "' isnot a slice")
+		}
+		resultType = resultType.Elem()
+	}
+
+	fieldKind, fieldValueType := valueKind(fieldType)
+	resultKind, resultValueType := valueKind(resultType)
+
+	switch fn {
+	case "sum", "avg":
+		if !isNumberKind(fieldKind) {
+			return errors.New("field type '" + fieldType.String() + "' isnot a number")
+		}
+		if !isNumberKind(resultKind) {
+			return errors.New("result type '" + resultType.String() + "' isnot a number")
+		}
+		if (fn == "avg" || isFloatKind(fieldKind)) && !isFloatKind(resultKind) {
+			return errors.New("result type '" + resultType.String() + "' isnot a float")
+		}
+		return nil
+	}
+
+	switch {
+	case isNumberKind(fieldKind) && isNumberKind(resultKind):
+		return nil
+	case fieldKind == reflect.String && resultKind == reflect.String:
+		return nil
+	case fieldKind == reflect.Bool && resultKind == reflect.Bool:
+		return nil
+	case fieldValueType == resultValueType:
+		return nil
+	}
+	return errors.New("result type '" + resultType.String() + "' isnot compatible with the field type '" + fieldType.String() + "'")
+}
+
+// valueKind 返回去掉指针和 sql.NullXXX 之类的包装后的值类型
+func valueKind(typ reflect.Type) (reflect.Kind, reflect.Type) {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return typ.Kind(), typ
+	}
+
+	// 如 null.Int 中只有一个嵌入的 sql.NullInt64
+	if typ.NumField() == 1 && typ.Field(0).Anonymous {
+		return valueKind(typ.Field(0).Type)
+	}
+
+	// 如 sql.NullInt64 和 pq.NullTime，它们由值和 Valid 组成
+	if typ.NumField() == 2 {
+		for idx := 0; idx < 2; idx++ {
+			if f := typ.Field(idx); f.Name == "Valid" && f.Type.Kind() == reflect.Bool {
+				return valueKind(typ.Field(1 - idx).Type)
+			}
+		}
+	}
+	return typ.Kind(), typ
+}
+
+func isNumberKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+func isFloatKind(kind reflect.Kind) bool {
+	return kind == reflect.Float32 || kind == reflect.Float64
+}
+
+// statsPrefixes 是按方法名生成分组统计语句时识别的前缀，方法名的格式为 前缀 + 字段名 + [And + 字段名]...，
+// 如 StatsByStatus、StatsByDeptAndStatus
+var statsPrefixes = []string{"StatsBy", "StatBy"}
+
+// SplitStatsMethod 从方法名中取出分组的字段名，方法名不是分组统计方法时返回 false
+func SplitStatsMethod(name string) ([]string, bool) {
+	for _, prefix := range statsPrefixes {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		rest := name[len(prefix):]
+		if rest == "" || !unicode.IsUpper(rune(rest[0])) {
+			continue
+		}
+
+		var fields []string
+		start := 0
+		for idx := 1; idx+3 < len(rest); idx++ {
+			if rest[idx:idx+3] == "And" && unicode.IsUpper(rune(rest[idx+3])) {
+				fields = append(fields, rest[start:idx])
+				start = idx + 3
+				idx += 2
+			}
+		}
+		return append(fields, rest[start:]), true
+	}
+	return nil, false
+}
+
+// GenerateStatsSQL 按方法名生成 SELECT col, count(*) ... GROUP BY col 语句，条件的生成规则与 GenerateCountSQL 相同，
+// resultType 是方法的返回值类型，只有一个分组字段时它可以是 map[值]数量，否则必须是结构的切片，
+// 结构中的字段与分组字段同名，另外还必须有一个数字字段用于保存数量
+func GenerateStatsSQL(dbType Dialect, mapper *Mapper, rType reflect.Type, method string, resultType reflect.Type, names []string, argTypes []reflect.Type, filters []Filter) (string, error) {
+	fieldNames, ok := SplitStatsMethod(method)
+	if !ok {
+		return "", errors.New("method '" + method + "' isnot a stats method")
+	}
+
+	tableName, err := ReadTableName(mapper, rType)
+	if err != nil {
+		return "", err
+	}
+
+	structType := mapper.TypeMap(rType)
+	fields := make([]*FieldInfo, 0, len(fieldNames))
+	for _, name := range fieldNames {
+		field, _, err := toFieldName(structType, name, nil)
+		if err != nil {
+			return "", err
+		}
+		fields = append(fields, field)
+	}
+
+	columns, err := statsColumns(dbType, mapper, fields, resultType)
+	if err != nil {
+		return "", errors.New("method '" + method + "' " + err.Error())
+	}
+
+	var sb strings.Builder
+	sb.WriteString("SELECT ")
+	sb.WriteString(strings.Join(columns, ", "))
+	sb.WriteString(" FROM ")
+	sb.WriteString(quoteName(dbType, mapper, tableName))
+
+	exprs := toFilters(filters, dbType)
+	if len(names) > 0 {
+		err := generateWhere(dbType, mapper, rType, names, argTypes, exprs, StatementTypeSelect, false, &sb)
+		if err != nil {
+			return "", err
+		}
+	} else {
+		writeConditions(&sb, noArgConditions(dbType, mapper, rType, exprs, true))
+	}
+
+	sb.WriteString(" GROUP BY ")
+	for idx, field := range fields {
+		if idx > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(quoteName(dbType, mapper, field.Name))
+	}
+	return sb.String(), nil
+}
+
+// statsColumns 检查 resultType 并返回 SELECT 的列，结果是结构时列名与结构中的字段对应
+func statsColumns(dbType Dialect, mapper *Mapper, fields []*FieldInfo, resultType reflect.Type) ([]string, error) {
+	var columns []string
+	switch resultType.Kind() {
+	case reflect.Map:
+		if len(fields) != 1 {
+			return nil, errors.New("result type '" + resultType.String() + "' is a map, but group by " + strconv.Itoa(len(fields)) + " fields")
+		}
+		if err := checkAggregateType("max", fields[0].Field.Type, resultType.Key()); err != nil {
+			return nil, err
+		}
+		if kind, _ := valueKind(resultType.Elem()); !isNumberKind(kind) {
+			return nil, errors.New("result type '" + resultType.String() + "' isnot a map of number")
+		}
+		return append(columns, quoteName(dbType, mapper, fields[0].Name), "count(*)"), nil
+	case reflect.Slice:
+	default:
+		return nil, errors.New("result type '" + resultType.String() + "' isnot a map or a slice")
+	}
+
+	elemType := resultType.Elem()
+	for elemType.Kind() == reflect.Ptr {
+		elemType = elemType.Elem()
+	}
+	if elemType.Kind() != reflect.Struct {
+		return nil, errors.New("result type '" + resultType.String() + "' isnot a slice of struct")
+	}
+
+	resultStruct := mapper.TypeMap(elemType)
+	used := map[*FieldInfo]struct{}{}
+	for _, field := range fields {
+		resultField, _, err := toFieldName(resultStruct, field.Field.Name, nil)
+		if err != nil {
+			return nil, errors.New("result type '" + resultType.String() + "' " + err.Error())
+		}
+		if err := checkAggregateType("max", field.Field.Type, resultField.Field.Type); err != nil {
+			return nil, err
+		}
+		used[resultField] = struct{}{}
+
+		column := quoteName(dbType, mapper, field.Name)
+		if resultField.Name != field.Name {
+			column = column + " AS " + quoteName(dbType, mapper, resultField.Name)
+		}
+		columns = append(columns, column)
+	}
+
+	var countField *FieldInfo
+	for _, field := range resultStruct.Index {
+		if _, ok := used[field]; ok || len(field.Index) != 1 {
+			continue
+		}
+		if kind, _ := valueKind(field.Field.Type); !isNumberKind(kind) {
+			continue
+		}
+		if countField != nil {
+			return nil, errors.New("result type '" + resultType.String() + "' has mult count fields")
+		}
+		countField = field
+	}
+	if countField == nil {
+		return nil, errors.New("result type '" + resultType.String() + "' count field is missing")
+	}
+	return append(columns, "count(*) AS "+quoteName(dbType, mapper, countField.Name)), nil
+}
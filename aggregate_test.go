@@ -0,0 +1,275 @@
+package gobatis
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type aggregateOrder struct {
+	TableName TableName `db:"aggregate_orders"`
+	ID        int64     `db:"id,pk,autoincr"`
+	UserID    int64     `db:"user_id"`
+	Username  string    `db:"username"`
+	Amount    float64   `db:"amount"`
+	Quantity  int       `db:"quantity"`
+	Status    int       `db:"status"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+func TestSplitAggregateMethod(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		fn    string
+		field string
+		ok    bool
+	}{
+		{name: "SumAmountByUserID", fn: "sum", field: "Amount", ok: true},
+		{name: "MaxCreatedAt", fn: "max", field: "CreatedAt", ok: true},
+		{name: "MinBytesByUserID", fn: "min", field: "Bytes", ok: true},
+		{name: "AvgAmountBy", fn: "avg", field: "Amount", ok: true},
+		{name: "ExistsByUsername", fn: "exists", ok: true},
+		{name: "Exists", fn: "exists", ok: true},
+		{name: "DistinctStatus", fn: "distinct", field: "Status", ok: true},
+		{name: "Summary", ok: false},
+		{name: "MaxByUserID", ok: false},
+		{name: "CountByUserID", ok: false},
+	} {
+		fn, field, ok := SplitAggregateMethod(test.name)
+		if fn != test.fn || field != test.field || ok != test.ok {
+			t.Error(test.name, "excepted is", test.fn, test.field, test.ok)
+			t.Error(test.name, "actual   is", fn, field, ok)
+		}
+	}
+}
+
+func TestGenerateAggregateSQL(t *testing.T) {
+	mapper := CreateMapper("", nil, nil)
+	rType := reflect.TypeOf(&aggregateOrder{})
+	int64Type := reflect.TypeOf(int64(0))
+	stringType := reflect.TypeOf("")
+
+	for idx, test := range []struct {
+		dialect    Dialect
+		method     string
+		resultType reflect.Type
+		names      []string
+		argTypes   []reflect.Type
+		excepted   string
+		err        string
+	}{
+		{
+			dialect:    DbTypePostgres,
+			method:     "SumAmountByUserID",
+			resultType: reflect.TypeOf(sql.NullFloat64{}),
+			names:      []string{"userID"},
+			argTypes:   []reflect.Type{int64Type},
+			excepted:   "SELECT sum(amount) FROM aggregate_orders WHERE user_id=#{userID}",
+		},
+		{
+			dialect:    DbTypePostgres,
+			method:     "SumQuantity",
+			resultType: int64Type,
+			excepted:   "SELECT sum(quantity) FROM aggregate_orders",
+		},
+		{
+			dialect:    DbTypePostgres,
+			method:     "MaxCreatedAtByUserID",
+			resultType: reflect.TypeOf(&time.Time{}),
+			names:      []string{"userID"},
+			argTypes:   []reflect.Type{int64Type},
+			excepted:   "SELECT max(created_at) FROM aggregate_orders WHERE user_id=#{userID}",
+		},
+		{
+			dialect:    DbTypePostgres,
+			method:     "ExistsByUsername",
+			resultType: reflect.TypeOf(true),
+			names:      []string{"username"},
+			argTypes:   []reflect.Type{stringType},
+			excepted:   "SELECT EXISTS(SELECT 1 FROM aggregate_orders WHERE username=#{username})",
+		},
+		{
+			dialect:    DbTypeMSSql,
+			method:     "ExistsByUsername",
+			resultType: reflect.TypeOf(true),
+			names:      []string{"username"},
+			argTypes:   []reflect.Type{stringType},
+			excepted:   "SELECT CASE WHEN EXISTS(SELECT 1 FROM aggregate_orders WHERE username=#{username}) THEN 1 ELSE 0 END",
+		},
+		{
+			dialect:    DbTypeOracle,
+			method:     "ExistsByUsername",
+			resultType: reflect.TypeOf(true),
+			names:      []string{"username"},
+			argTypes:   []reflect.Type{stringType},
+			excepted:   "SELECT CASE WHEN EXISTS(SELECT 1 FROM aggregate_orders WHERE username=#{username}) THEN 1 ELSE 0 END FROM DUAL",
+		},
+		{
+			dialect:    DbTypeMysql,
+			method:     "DistinctStatus",
+			resultType: reflect.TypeOf([]int{}),
+			excepted:   "SELECT DISTINCT status FROM aggregate_orders",
+		},
+		{
+			dialect:    DbTypePostgres,
+			method:     "SumUsername",
+			resultType: int64Type,
+			err:        "method 'SumUsername' field type 'string' isnot a number",
+		},
+		{
+			dialect:    DbTypePostgres,
+			method:     "AvgQuantity",
+			resultType: int64Type,
+			err:        "method 'AvgQuantity' result type 'int64' isnot a float",
+		},
+		{
+			dialect:    DbTypePostgres,
+			method:     "MaxCreatedAt",
+			resultType: int64Type,
+			err:        "method 'MaxCreatedAt' result type 'int64' isnot compatible with the field type 'time.Time'",
+		},
+		{
+			dialect:    DbTypePostgres,
+			method:     "DistinctStatus",
+			resultType: reflect.TypeOf(0),
+			err:        "method 'DistinctStatus' result type 'int' isnot a slice",
+		},
+		{
+			dialect:    DbTypePostgres,
+			method:     "ExistsByUsername",
+			resultType: int64Type,
+			names:      []string{"username"},
+			argTypes:   []reflect.Type{stringType},
+			err:        "method 'ExistsByUsername' result type 'int64' isnot bool",
+		},
+		{
+			dialect:    DbTypePostgres,
+			method:     "MaxPrice",
+			resultType: int64Type,
+			err:        "field 'Price' is missing",
+		},
+	} {
+		sqlStr, err := GenerateAggregateSQL(test.dialect, mapper, rType, test.method, test.resultType, test.names, test.argTypes, nil)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Error(idx, "excepted error is", test.err)
+				t.Error(idx, "actual   error is", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Error(idx, err)
+			continue
+		}
+		if sqlStr != test.excepted {
+			t.Error(idx, "excepted is", test.excepted)
+			t.Error(idx, "actual   is", sqlStr)
+		}
+	}
+}
+
+func TestSplitStatsMethod(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		fields []string
+		ok     bool
+	}{
+		{name: "StatsByStatus", fields: []string{"Status"}, ok: true},
+		{name: "StatByStatus", fields: []string{"Status"}, ok: true},
+		{name: "StatsByUserIDAndStatus", fields: []string{"UserID", "Status"}, ok: true},
+		{name: "StatsByBrandAndStatusAndUserID", fields: []string{"Brand", "Status", "UserID"}, ok: true},
+		{name: "StatsByAndroidVersion", fields: []string{"AndroidVersion"}, ok: true},
+		{name: "StatsBy", ok: false},
+		{name: "Stats", ok: false},
+	} {
+		fields, ok := SplitStatsMethod(test.name)
+		if ok != test.ok || !reflect.DeepEqual(fields, test.fields) {
+			t.Error(test.name, "excepted is", test.fields, test.ok)
+			t.Error(test.name, "actual   is", fields, ok)
+		}
+	}
+}
+
+func TestGenerateStatsSQL(t *testing.T) {
+	mapper := CreateMapper("", nil, nil)
+	rType := reflect.TypeOf(&aggregateOrder{})
+
+	type userStatusStat struct {
+		UserID int64 `db:"uid"`
+		Status int
+		Count  int64
+	}
+
+	for idx, test := range []struct {
+		dialect    Dialect
+		method     string
+		resultType reflect.Type
+		names      []string
+		argTypes   []reflect.Type
+		excepted   string
+		err        string
+	}{
+		{
+			dialect:    DbTypePostgres,
+			method:     "StatsByStatus",
+			resultType: reflect.TypeOf(map[int]int64{}),
+			excepted:   "SELECT status, count(*) FROM aggregate_orders GROUP BY status",
+		},
+		{
+			dialect:    DbTypePostgres,
+			method:     "StatsByStatus",
+			resultType: reflect.TypeOf(map[int]int64{}),
+			names:      []string{"userID"},
+			argTypes:   []reflect.Type{reflect.TypeOf(int64(0))},
+			excepted:   "SELECT status, count(*) FROM aggregate_orders WHERE user_id=#{userID} GROUP BY status",
+		},
+		{
+			dialect:    DbTypeMysql,
+			method:     "StatsByUserIDAndStatus",
+			resultType: reflect.TypeOf([]userStatusStat{}),
+			excepted:   "SELECT user_id AS uid, status, count(*) AS count FROM aggregate_orders GROUP BY user_id, status",
+		},
+		{
+			dialect:    DbTypeMysql,
+			method:     "StatsByUserIDAndStatus",
+			resultType: reflect.TypeOf(map[int]int64{}),
+			err:        "method 'StatsByUserIDAndStatus' result type 'map[int]int64' is a map, but group by 2 fields",
+		},
+		{
+			dialect:    DbTypeMysql,
+			method:     "StatsByStatus",
+			resultType: reflect.TypeOf(map[int]string{}),
+			err:        "method 'StatsByStatus' result type 'map[int]string' isnot a map of number",
+		},
+		{
+			dialect:    DbTypeMysql,
+			method:     "StatsByCreatedAt",
+			resultType: reflect.TypeOf(map[int]int64{}),
+			err:        "method 'StatsByCreatedAt' result type 'int' isnot compatible with the field type 'time.Time'",
+		},
+		{
+			dialect:    DbTypeMysql,
+			method:     "StatsByUsername",
+			resultType: reflect.TypeOf([]userStatusStat{}),
+			err:        "method 'StatsByUsername' result type '[]gobatis.userStatusStat' field 'Username' is missing",
+		},
+	} {
+		sqlStr, err := GenerateStatsSQL(test.dialect, mapper, rType, test.method, test.resultType, test.names, test.argTypes, nil)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Error(idx, "excepted error is", test.err)
+				t.Error(idx, "actual   error is", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Error(idx, err)
+			continue
+		}
+		if sqlStr != test.excepted {
+			t.Error(idx, "excepted is", test.excepted)
+			t.Error(idx, "actual   is", sqlStr)
+		}
+	}
+}
@@ -84,49 +84,102 @@ func ReadTableName(mapper *Mapper, rType reflect.Type) (string, error) {
 	return "", errors.New("struct '" + rType.Name() + "' TableName is missing")
 }
 
-func GenerateInsertSQL(dbType Dialect, mapper *Mapper, rType reflect.Type, noReturn bool) (string, error) {
-	var sb strings.Builder
-	sb.WriteString("INSERT INTO ")
-	tableName, err := ReadTableName(mapper, rType)
-	if err != nil {
-		return "", err
+// skipInsertField 判断字段是否不需要出现在 insert 语句中
+func skipInsertField(field *FieldInfo) bool {
+	if field.Field.Name == "TableName" {
+		return true
+	}
+	if field.Field.Anonymous {
+		return true
 	}
-	sb.WriteString(tableName)
-	sb.WriteString("(")
 
-	skip := func(field *FieldInfo) bool {
-		if field.Field.Name == "TableName" {
-			return true
-		}
-		if field.Field.Anonymous {
-			return true
-		}
+	if field.Parent != nil && len(field.Parent.Index) != 0 && !field.Parent.Field.Anonymous {
+		return true
+	}
 
-		if field.Parent != nil && len(field.Parent.Index) != 0 && !field.Parent.Field.Anonymous {
-			return true
-		}
+	if _, ok := field.Options["autoincr"]; ok {
+		return true
+	}
 
+	if _, ok := field.Options["-"]; ok {
+		return true
+	}
+
+	if _, ok := field.Options["<-"]; ok {
+		return true
+	}
+
+	if _, ok := field.Options["deleted"]; ok {
+		return true
+	}
+	return false
+}
+
+// generatedField 返回值由数据库生成的字段(有 autoincr 或 sequence 标记)，没有时返回 nil
+func generatedField(mapper *Mapper, rType reflect.Type) *FieldInfo {
+	for _, field := range mapper.TypeMap(rType).Index {
 		if _, ok := field.Options["autoincr"]; ok {
-			return true
+			return field
 		}
-
-		if _, ok := field.Options["-"]; ok {
-			return true
+		if _, ok := tagOptionArg(field, "sequence"); ok {
+			return field
 		}
+	}
+	return nil
+}
 
-		if _, ok := field.Options["<-"]; ok {
-			return true
-		}
+// writeReturning 在 insert 语句末尾加上返回生成值的子句
+func writeReturning(sb *strings.Builder, dbType Dialect, mapper *Mapper, field *FieldInfo) {
+	if field == nil {
+		return
+	}
+	switch dbType.Returning() {
+	case ReturningClause:
+		sb.WriteString(" RETURNING ")
+		sb.WriteString(quoteName(dbType, mapper, field.Name))
+	case ReturningInto:
+		sb.WriteString(" RETURNING ")
+		sb.WriteString(quoteName(dbType, mapper, field.Name))
+		sb.WriteString(" INTO ")
+		sb.WriteString(ReturningIntoParam)
+	}
+}
 
-		if _, ok := field.Options["deleted"]; ok {
-			return true
-		}
-		return false
+func GenerateInsertSQL(dbType Dialect, mapper *Mapper, rType reflect.Type, noReturn bool) (string, error) {
+	return generateInsertSQL(dbType, mapper, rType, noReturn, false)
+}
+
+// GenerateInsertReturningSQL 生成返回整行的 insert 语句(RETURNING * 或 OUTPUT INSERTED.*)，
+// 它用于 Connection.InsertReturning，数据库生成的值(如默认值和自增列)会读回到参数中
+func GenerateInsertReturningSQL(dbType Dialect, mapper *Mapper, rType reflect.Type) (string, error) {
+	if err := checkReturningAll(dbType); err != nil {
+		return "", err
 	}
+	return generateInsertSQL(dbType, mapper, rType, false, true)
+}
+
+// checkReturningAll 检查数据库是否支持返回整行
+func checkReturningAll(dbType Dialect) error {
+	switch dbType.Returning() {
+	case ReturningClause, ReturningOutput:
+		return nil
+	}
+	return errors.New("returning all columns is unsupported for the '" + dbType.Name() + "'")
+}
+
+func generateInsertSQL(dbType Dialect, mapper *Mapper, rType reflect.Type, noReturn, returnAll bool) (string, error) {
+	var sb strings.Builder
+	sb.WriteString("INSERT INTO ")
+	tableName, err := ReadTableName(mapper, rType)
+	if err != nil {
+		return "", err
+	}
+	sb.WriteString(quoteName(dbType, mapper, tableName))
+	sb.WriteString("(")
 
 	isFirst := true
 	for _, field := range mapper.TypeMap(rType).Index {
-		if skip(field) {
+		if skipInsertField(field) {
 			continue
 		}
 		if !isFirst {
@@ -135,19 +188,18 @@ func GenerateInsertSQL(dbType Dialect, mapper *Mapper, rType reflect.Type, noRet
 			isFirst = false
 		}
 
-		sb.WriteString(field.Name)
+		sb.WriteString(quoteName(dbType, mapper, field.Name))
 	}
 	sb.WriteString(")")
 
-	if dbType == DbTypeMSSql {
-		if !noReturn {
-			for _, field := range mapper.TypeMap(rType).Index {
-				if _, ok := field.Options["autoincr"]; ok {
-					sb.WriteString(" OUTPUT inserted.")
-					sb.WriteString(field.Name)
-					break
-				}
-			}
+	if returnAll {
+		if dbType.Returning() == ReturningOutput {
+			sb.WriteString(" OUTPUT INSERTED.*")
+		}
+	} else if !noReturn && dbType.Returning() == ReturningOutput {
+		if field := generatedField(mapper, rType); field != nil {
+			sb.WriteString(" OUTPUT inserted.")
+			sb.WriteString(quoteName(dbType, mapper, field.Name))
 		}
 	}
 
@@ -155,7 +207,7 @@ func GenerateInsertSQL(dbType Dialect, mapper *Mapper, rType reflect.Type, noRet
 
 	isFirst = true
 	for _, field := range mapper.TypeMap(rType).Index {
-		if skip(field) {
+		if skipInsertField(field) {
 			continue
 		}
 
@@ -166,11 +218,17 @@ func GenerateInsertSQL(dbType Dialect, mapper *Mapper, rType reflect.Type, noRet
 		}
 
 		if (AutoCreatedAt && field.Name == "created_at") || (AutoUpdatedAt && field.Name == "updated_at") {
-			if dbType == DbTypePostgres {
-				sb.WriteString("now()")
-			} else {
-				sb.WriteString("CURRENT_TIMESTAMP")
-			}
+			sb.WriteString(dbType.CurrentTimestamp())
+			continue
+		}
+
+		if _, ok := field.Options["tenant"]; ok {
+			sb.WriteString(tenantPlaceholder)
+			continue
+		}
+
+		if sequence, ok := tagOptionArg(field, "sequence"); ok {
+			sb.WriteString(dbType.NextSequenceValue(sequence))
 			continue
 		}
 
@@ -181,16 +239,12 @@ func GenerateInsertSQL(dbType Dialect, mapper *Mapper, rType reflect.Type, noRet
 
 	sb.WriteString(")")
 
-	if dbType == DbTypePostgres {
-		if !noReturn {
-			for _, field := range mapper.TypeMap(rType).Index {
-				if _, ok := field.Options["autoincr"]; ok {
-					sb.WriteString(" RETURNING ")
-					sb.WriteString(field.Name)
-					break
-				}
-			}
+	if returnAll {
+		if dbType.Returning() == ReturningClause {
+			sb.WriteString(" RETURNING *")
 		}
+	} else if !noReturn {
+		writeReturning(&sb, dbType, mapper, generatedField(mapper, rType))
 	}
 	return sb.String(), nil
 }
@@ -202,39 +256,9 @@ func GenerateInsertSQL2(dbType Dialect, mapper *Mapper, rType reflect.Type, fiel
 	if err != nil {
 		return "", err
 	}
-	sb.WriteString(tableName)
+	sb.WriteString(quoteName(dbType, mapper, tableName))
 	sb.WriteString("(")
 
-	skip := func(field *FieldInfo) bool {
-		if field.Field.Name == "TableName" {
-			return true
-		}
-		if field.Field.Anonymous {
-			return true
-		}
-
-		if field.Parent != nil && len(field.Parent.Index) != 0 && !field.Parent.Field.Anonymous {
-			return true
-		}
-
-		if _, ok := field.Options["autoincr"]; ok {
-			return true
-		}
-
-		if _, ok := field.Options["-"]; ok {
-			return true
-		}
-
-		if _, ok := field.Options["<-"]; ok {
-			return true
-		}
-
-		if _, ok := field.Options["deleted"]; ok {
-			return true
-		}
-		return false
-	}
-
 	isFirst := true
 	for _, field := range mapper.TypeMap(rType).Index {
 		foundIndex := -1
@@ -250,13 +274,40 @@ func GenerateInsertSQL2(dbType Dialect, mapper *Mapper, rType reflect.Type, fiel
 				break
 			}
 		}
-		if skip(field) {
+		if skipInsertField(field) {
 			if foundIndex >= 0 {
 				return "", errors.New("field '" + fields[foundIndex] + "' cannot present")
 			}
 			continue
 		}
 
+		if _, ok := field.Options["tenant"]; ok {
+			// 租户列的值总是来自 context
+			if !isFirst {
+				sb.WriteString(", ")
+			} else {
+				isFirst = false
+			}
+
+			sb.WriteString(quoteName(dbType, mapper, field.Name))
+			continue
+		}
+
+		if _, ok := tagOptionArg(field, "sequence"); ok {
+			// 序列列的值总是来自序列
+			if foundIndex >= 0 {
+				return "", errors.New("field '" + fields[foundIndex] + "' cannot present")
+			}
+			if !isFirst {
+				sb.WriteString(", ")
+			} else {
+				isFirst = false
+			}
+
+			sb.WriteString(quoteName(dbType, mapper, field.Name))
+			continue
+		}
+
 		if foundIndex < 0 {
 			if "created_at" == field.Name || "updated_at" == field.Name {
 
@@ -266,7 +317,7 @@ func GenerateInsertSQL2(dbType Dialect, mapper *Mapper, rType reflect.Type, fiel
 					isFirst = false
 				}
 
-				sb.WriteString(field.Name)
+				sb.WriteString(quoteName(dbType, mapper, field.Name))
 				continue
 			}
 
@@ -282,19 +333,14 @@ func GenerateInsertSQL2(dbType Dialect, mapper *Mapper, rType reflect.Type, fiel
 			isFirst = false
 		}
 
-		sb.WriteString(field.Name)
+		sb.WriteString(quoteName(dbType, mapper, field.Name))
 	}
 	sb.WriteString(")")
 
-	if dbType == DbTypeMSSql {
-		if !noReturn {
-			for _, field := range mapper.TypeMap(rType).Index {
-				if _, ok := field.Options["autoincr"]; ok {
-					sb.WriteString(" OUTPUT inserted.")
-					sb.WriteString(field.Name)
-					break
-				}
-			}
+	if !noReturn && dbType.Returning() == ReturningOutput {
+		if field := generatedField(mapper, rType); field != nil {
+			sb.WriteString(" OUTPUT inserted.")
+			sb.WriteString(quoteName(dbType, mapper, field.Name))
 		}
 	}
 
@@ -302,7 +348,29 @@ func GenerateInsertSQL2(dbType Dialect, mapper *Mapper, rType reflect.Type, fiel
 
 	isFirst = true
 	for _, field := range mapper.TypeMap(rType).Index {
-		if skip(field) {
+		if skipInsertField(field) {
+			continue
+		}
+
+		if _, ok := field.Options["tenant"]; ok {
+			if !isFirst {
+				sb.WriteString(", ")
+			} else {
+				isFirst = false
+			}
+
+			sb.WriteString(tenantPlaceholder)
+			continue
+		}
+
+		if sequence, ok := tagOptionArg(field, "sequence"); ok {
+			if !isFirst {
+				sb.WriteString(", ")
+			} else {
+				isFirst = false
+			}
+
+			sb.WriteString(dbType.NextSequenceValue(sequence))
 			continue
 		}
 
@@ -327,11 +395,7 @@ func GenerateInsertSQL2(dbType Dialect, mapper *Mapper, rType reflect.Type, fiel
 					isFirst = false
 				}
 
-				if dbType == DbTypePostgres {
-					sb.WriteString("now()")
-				} else {
-					sb.WriteString("CURRENT_TIMESTAMP")
-				}
+				sb.WriteString(dbType.CurrentTimestamp())
 				continue
 			}
 
@@ -345,11 +409,7 @@ func GenerateInsertSQL2(dbType Dialect, mapper *Mapper, rType reflect.Type, fiel
 		}
 
 		if (AutoCreatedAt && field.Name == "created_at") || (AutoUpdatedAt && field.Name == "updated_at") {
-			if dbType == DbTypePostgres {
-				sb.WriteString("now()")
-			} else {
-				sb.WriteString("CURRENT_TIMESTAMP")
-			}
+			sb.WriteString(dbType.CurrentTimestamp())
 			continue
 		}
 
@@ -367,28 +427,101 @@ func GenerateInsertSQL2(dbType Dialect, mapper *Mapper, rType reflect.Type, fiel
 
 	sb.WriteString(")")
 
-	if dbType == DbTypePostgres {
-		if !noReturn {
-			for _, field := range mapper.TypeMap(rType).Index {
-				if _, ok := field.Options["autoincr"]; ok {
-					sb.WriteString(" RETURNING ")
-					sb.WriteString(field.Name)
-					break
-				}
+	if !noReturn {
+		writeReturning(&sb, dbType, mapper, generatedField(mapper, rType))
+	}
+	return sb.String(), nil
+}
+
+// GenerateUpsertSQL 生成记录不存在时插入，存在时更新的语句，keys 是判断记录是否存在的列(它们必须有唯一索引)，
+// 记录存在时更新除 keys、created_at 和租户以外的列，有租户列时它总是会加到 keys 中，唯一索引也必须包含它，
+// 避免更新其它租户的记录
+func GenerateUpsertSQL(dbType Dialect, mapper *Mapper, rType reflect.Type, keys []string, noReturn bool) (string, error) {
+	if len(keys) == 0 {
+		return "", errors.New("upsert keys is empty")
+	}
+
+	tableName, err := ReadTableName(mapper, rType)
+	if err != nil {
+		return "", err
+	}
+	stmt := &UpsertStatement{Table: quoteName(dbType, mapper, tableName)}
+
+	if !noReturn {
+		if field := generatedField(mapper, rType); field != nil {
+			stmt.Returning = quoteName(dbType, mapper, field.Name)
+		}
+	}
+
+	structType := mapper.TypeMap(rType)
+	isKey := make([]bool, len(keys))
+	for _, field := range structType.Index {
+		if skipInsertField(field) {
+			continue
+		}
+
+		stmt.Columns = append(stmt.Columns, quoteName(dbType, mapper, field.Name))
+
+		_, isTenant := field.Options["tenant"]
+		sequence, isSequence := tagOptionArg(field, "sequence")
+		if (AutoCreatedAt && field.Name == "created_at") || (AutoUpdatedAt && field.Name == "updated_at") {
+			stmt.Values = append(stmt.Values, dbType.CurrentTimestamp())
+		} else if isSequence {
+			stmt.Values = append(stmt.Values, dbType.NextSequenceValue(sequence))
+		} else if isTenant {
+			stmt.Values = append(stmt.Values, tenantPlaceholder)
+		} else {
+			stmt.Values = append(stmt.Values, "#{"+field.Name+"}")
+		}
+
+		found := false
+		for idx, key := range keys {
+			if strings.EqualFold(key, field.Name) || strings.EqualFold(key, field.Field.Name) {
+				isKey[idx] = true
+				found = true
+				stmt.Keys = append(stmt.Keys, quoteName(dbType, mapper, field.Name))
+			}
+		}
+		if isTenant {
+			stmt.Tenant = quoteName(dbType, mapper, field.Name)
+			if !found {
+				stmt.Keys = append(stmt.Keys, stmt.Tenant)
 			}
 		}
+		if !found && !isTenant && !isSequence && field.Name != "created_at" {
+			stmt.Updates = append(stmt.Updates, quoteName(dbType, mapper, field.Name))
+		}
 	}
-	return sb.String(), nil
+
+	for idx := range keys {
+		if !isKey[idx] {
+			return "", errors.New("upsert key '" + keys[idx] + "' isnot exists in the table '" + tableName + "'")
+		}
+	}
+	return dbType.Upsert(stmt), nil
 }
 
 func GenerateUpdateSQL(dbType Dialect, mapper *Mapper, prefix string, rType reflect.Type, names []string, argTypes []reflect.Type) (string, error) {
+	return generateUpdateSQL(dbType, mapper, prefix, rType, names, argTypes, false)
+}
+
+// GenerateUpdateReturningSQL 生成返回整行的 update 语句(RETURNING * 或 OUTPUT INSERTED.*)，
+// 它用于 Connection.UpdateReturning，数据库生成的值(如 updated_at)会读回到参数中
+func GenerateUpdateReturningSQL(dbType Dialect, mapper *Mapper, prefix string, rType reflect.Type, names []string, argTypes []reflect.Type) (string, error) {
+	if err := checkReturningAll(dbType); err != nil {
+		return "", err
+	}
+	return generateUpdateSQL(dbType, mapper, prefix, rType, names, argTypes, true)
+}
+
+func generateUpdateSQL(dbType Dialect, mapper *Mapper, prefix string, rType reflect.Type, names []string, argTypes []reflect.Type, returnAll bool) (string, error) {
 	var sb strings.Builder
 	sb.WriteString("UPDATE ")
 	tableName, err := ReadTableName(mapper, rType)
 	if err != nil {
 		return "", err
 	}
-	sb.WriteString(tableName)
+	sb.WriteString(quoteName(dbType, mapper, tableName))
 	sb.WriteString(" SET ")
 
 	structType := mapper.TypeMap(rType)
@@ -421,6 +554,9 @@ func GenerateUpdateSQL(dbType Dialect, mapper *Mapper, prefix string, rType refl
 		if _, ok := field.Options["autoincr"]; ok {
 			continue
 		}
+		if _, ok := tagOptionArg(field, "sequence"); ok {
+			continue
+		}
 		if _, ok := field.Options["pk"]; ok {
 			continue
 		}
@@ -430,6 +566,9 @@ func GenerateUpdateSQL(dbType Dialect, mapper *Mapper, prefix string, rType refl
 		if _, ok := field.Options["deleted"]; ok {
 			continue
 		}
+		if _, ok := field.Options["tenant"]; ok {
+			continue
+		}
 
 		found := false
 		for _, name := range names {
@@ -453,13 +592,9 @@ func GenerateUpdateSQL(dbType Dialect, mapper *Mapper, prefix string, rType refl
 			isFirst = false
 		}
 
-		sb.WriteString(field.Name)
+		sb.WriteString(quoteName(dbType, mapper, field.Name))
 		if field.Name == "updated_at" {
-			if dbType == DbTypePostgres {
-				sb.WriteString("=now()")
-			} else {
-				sb.WriteString("=CURRENT_TIMESTAMP")
-			}
+			sb.WriteString("=" + dbType.CurrentTimestamp())
 			continue
 		}
 		sb.WriteString("=#{")
@@ -471,6 +606,10 @@ func GenerateUpdateSQL(dbType Dialect, mapper *Mapper, prefix string, rType refl
 		sb.WriteString("}")
 	}
 
+	if returnAll && dbType.Returning() == ReturningOutput {
+		sb.WriteString(" OUTPUT INSERTED.*")
+	}
+
 	if len(names) > 0 {
 		err := generateWhere(dbType, mapper, rType, names, argTypes, nil, StatementTypeUpdate, false, &sb)
 		if err != nil {
@@ -494,7 +633,7 @@ func GenerateUpdateSQL(dbType Dialect, mapper *Mapper, prefix string, rType refl
 				sb.WriteString(" AND ")
 			}
 
-			sb.WriteString(field.Name)
+			sb.WriteString(quoteName(dbType, mapper, field.Name))
 			sb.WriteString("=#{")
 			if prefix != "" {
 				sb.WriteString(prefix)
@@ -506,6 +645,15 @@ func GenerateUpdateSQL(dbType Dialect, mapper *Mapper, prefix string, rType refl
 		if isFirst {
 			return "", errors.New("primary key isnot found")
 		}
+
+		if tenantField := findTenantField(mapper, rType); tenantField != nil {
+			sb.WriteString(" AND ")
+			sb.WriteString(tenantCondition(dbType, mapper, tenantField))
+		}
+	}
+
+	if returnAll && dbType.Returning() == ReturningClause {
+		sb.WriteString(" RETURNING *")
 	}
 	return sb.String(), nil
 }
@@ -517,7 +665,7 @@ func GenerateUpdateSQL2(dbType Dialect, mapper *Mapper, rType, queryType reflect
 	if err != nil {
 		return "", err
 	}
-	sb.WriteString(tableName)
+	sb.WriteString(quoteName(dbType, mapper, tableName))
 	sb.WriteString(" SET ")
 
 	structType := mapper.TypeMap(rType)
@@ -544,6 +692,9 @@ func GenerateUpdateSQL2(dbType Dialect, mapper *Mapper, rType, queryType reflect
 		if deletedField != nil && deletedField.Name == field.Name {
 			continue
 		}
+		if _, ok := field.Options["tenant"]; ok {
+			continue
+		}
 
 		if !isFirst {
 			sb.WriteString(", ")
@@ -551,13 +702,9 @@ func GenerateUpdateSQL2(dbType Dialect, mapper *Mapper, rType, queryType reflect
 			isFirst = false
 		}
 
-		sb.WriteString(field.Name)
+		sb.WriteString(quoteName(dbType, mapper, field.Name))
 		if field.Name == "updated_at" {
-			if dbType == DbTypePostgres {
-				sb.WriteString("=now()")
-			} else {
-				sb.WriteString("=CURRENT_TIMESTAMP")
-			}
+			sb.WriteString("=" + dbType.CurrentTimestamp())
 			continue
 		}
 		sb.WriteString("=#{")
@@ -599,12 +746,8 @@ func GenerateUpdateSQL2(dbType Dialect, mapper *Mapper, rType, queryType reflect
 			isFirst = false
 		}
 
-		sb.WriteString(field.Name)
-		if dbType == DbTypePostgres {
-			sb.WriteString("=now()")
-		} else {
-			sb.WriteString("=CURRENT_TIMESTAMP")
-		}
+		sb.WriteString(quoteName(dbType, mapper, field.Name))
+		sb.WriteString("=" + dbType.CurrentTimestamp())
 	}
 
 	err = generateWhere(dbType, mapper, rType, []string{queryName}, []reflect.Type{queryType}, nil, StatementTypeUpdate, false, &sb)
@@ -684,7 +827,7 @@ func GenerateDeleteSQL(dbType Dialect, mapper *Mapper, rType reflect.Type, names
 	if err != nil {
 		return "", err
 	}
-	sb.WriteString(tableName)
+	sb.WriteString(quoteName(dbType, mapper, tableName))
 
 	exprs := toFilters(filters, dbType)
 	if len(names) > 0 && (deletedField == nil || forceIndex < 0 || len(names) > 1) {
@@ -692,15 +835,8 @@ func GenerateDeleteSQL(dbType Dialect, mapper *Mapper, rType reflect.Type, names
 		if err != nil {
 			return "", err
 		}
-	} else if len(exprs) > 0 {
-		sb.WriteString(" WHERE ")
-		for idx := range exprs {
-			s := strings.TrimSpace(exprs[idx])
-			if idx > 0 {
-				sb.WriteString(" AND ")
-			}
-			sb.WriteString(s)
-		}
+	} else {
+		writeConditions(&sb, noArgConditions(dbType, mapper, rType, exprs, false))
 	}
 
 	if deletedField == nil {
@@ -715,29 +851,18 @@ func GenerateDeleteSQL(dbType Dialect, mapper *Mapper, rType reflect.Type, names
 	}
 	full.WriteString(`UPDATE `)
 
-	full.WriteString(tableName)
+	full.WriteString(quoteName(dbType, mapper, tableName))
 	full.WriteString(" SET ")
-	full.WriteString(deletedField.Name)
-	if dbType == DbTypePostgres {
-		full.WriteString("=now() ")
-	} else {
-		full.WriteString("=CURRENT_TIMESTAMP ")
-	}
+	full.WriteString(quoteName(dbType, mapper, deletedField.Name))
+	full.WriteString("=" + dbType.CurrentTimestamp() + " ")
 
 	if len(names) > 0 && (forceIndex < 0 || len(names) > 1) {
 		err := generateWhere(dbType, mapper, rType, names, argTypes, exprs, StatementTypeDelete, false, &full)
 		if err != nil {
 			return "", err
 		}
-	} else if len(exprs) > 0 {
-		sb.WriteString(" WHERE ")
-		for idx := range exprs {
-			s := strings.TrimSpace(exprs[idx])
-			if idx > 0 {
-				sb.WriteString(" AND ")
-			}
-			sb.WriteString(s)
-		}
+	} else {
+		writeConditions(&full, noArgConditions(dbType, mapper, rType, exprs, false))
 	}
 
 	if forceIndex >= 0 {
@@ -758,7 +883,7 @@ func GenerateSelectSQL(dbType Dialect, mapper *Mapper, rType reflect.Type, names
 	if err != nil {
 		return "", err
 	}
-	sb.WriteString(tableName)
+	sb.WriteString(quoteName(dbType, mapper, tableName))
 
 	exprs := toFilters(filters, dbType)
 	if len(names) > 0 {
@@ -766,33 +891,48 @@ func GenerateSelectSQL(dbType Dialect, mapper *Mapper, rType reflect.Type, names
 		if err != nil {
 			return "", err
 		}
-	} else if deletedField := findDeletedField(mapper, rType); deletedField != nil {
-		sb.WriteString(" WHERE ")
-		sb.WriteString(deletedField.Name)
-		sb.WriteString(" IS NULL")
-
-		for idx := range exprs {
-			s := strings.TrimSpace(exprs[idx])
-			sb.WriteString(" AND ")
-			sb.WriteString(s)
-		}
-	} else if len(exprs) > 0 {
-		sb.WriteString(" WHERE ")
-		for idx := range exprs {
-			s := strings.TrimSpace(exprs[idx])
-			if idx > 0 {
-				sb.WriteString(" AND ")
-			}
-			sb.WriteString(s)
-		}
+	} else {
+		writeConditions(&sb, noArgConditions(dbType, mapper, rType, exprs, true))
 	}
 	if order != "" {
 		sb.WriteString(" ORDER BY ")
 		sb.WriteString(order)
 	}
+	writePage(&sb, mapper, rType, names, argTypes)
 	return sb.String(), nil
 }
 
+func isPageArg(name string) bool {
+	return name == "offset" || name == "limit"
+}
+
+// writePage 输出分页的 <page /> 元素，名为 offset 和 limit 且在表中没有对应的列的参数是分页参数
+func writePage(sb *strings.Builder, mapper *Mapper, rType reflect.Type, names []string, argTypes []reflect.Type) {
+	structType := mapper.TypeMap(rType)
+	var page pageExpression
+	for idx, name := range names {
+		if !isPageArg(name) {
+			continue
+		}
+		var argType reflect.Type
+		if argTypes != nil {
+			argType = argTypes[idx]
+		}
+		if _, _, err := toFieldName(structType, name, argType); err == nil {
+			continue
+		}
+		if name == "offset" {
+			page.offset = name
+		} else {
+			page.limit = name
+		}
+	}
+	if page.offset != "" || page.limit != "" {
+		sb.WriteString(" ")
+		sb.WriteString(page.String())
+	}
+}
+
 func GenerateCountSQL(dbType Dialect, mapper *Mapper, rType reflect.Type, names []string, argTypes []reflect.Type, filters []Filter) (string, error) {
 	var sb strings.Builder
 	sb.WriteString("SELECT count(*) FROM ")
@@ -800,7 +940,7 @@ func GenerateCountSQL(dbType Dialect, mapper *Mapper, rType reflect.Type, names
 	if err != nil {
 		return "", err
 	}
-	sb.WriteString(tableName)
+	sb.WriteString(quoteName(dbType, mapper, tableName))
 
 	exprs := toFilters(filters, dbType)
 	if len(names) > 0 {
@@ -808,28 +948,35 @@ func GenerateCountSQL(dbType Dialect, mapper *Mapper, rType reflect.Type, names
 		if err != nil {
 			return "", err
 		}
-	} else if deletedField := findDeletedField(mapper, rType); deletedField != nil {
-		sb.WriteString(" WHERE ")
-		sb.WriteString(deletedField.Name)
-		sb.WriteString(" IS NULL")
+	} else {
+		writeConditions(&sb, noArgConditions(dbType, mapper, rType, exprs, true))
+	}
+	return sb.String(), nil
+}
 
-		for idx := range exprs {
-			s := strings.TrimSpace(exprs[idx])
-			sb.WriteString(" AND ")
-			sb.WriteString(s)
+// noArgConditions 返回没有参数时的条件，包括软删除、过滤器和租户的条件
+func noArgConditions(dbType Dialect, mapper *Mapper, rType reflect.Type, exprs []string, withDeleted bool) []string {
+	var conditions []string
+	if withDeleted {
+		if deletedField := findDeletedField(mapper, rType); deletedField != nil {
+			conditions = append(conditions, quoteName(dbType, mapper, deletedField.Name)+" IS NULL")
 		}
-	} else if len(exprs) > 0 {
-		sb.WriteString(" WHERE ")
+	}
+	for idx := range exprs {
+		conditions = append(conditions, strings.TrimSpace(exprs[idx]))
+	}
+	if tenantField := findTenantField(mapper, rType); tenantField != nil {
+		conditions = append(conditions, tenantCondition(dbType, mapper, tenantField))
+	}
+	return conditions
+}
 
-		for idx := range exprs {
-			s := strings.TrimSpace(exprs[idx])
-			if idx > 0 {
-				sb.WriteString(" AND ")
-			}
-			sb.WriteString(s)
-		}
+func writeConditions(sb *strings.Builder, conditions []string) {
+	if len(conditions) == 0 {
+		return
 	}
-	return sb.String(), nil
+	sb.WriteString(" WHERE ")
+	sb.WriteString(strings.Join(conditions, " AND "))
 }
 
 func generateWhere(dbType Dialect, mapper *Mapper, rType reflect.Type, names []string, argTypes []reflect.Type, exprs []string, stmtType StatementType, isCount bool, sb *strings.Builder) error {
@@ -850,6 +997,14 @@ func generateWhere(dbType Dialect, mapper *Mapper, rType reflect.Type, names []s
 		}
 	}
 
+	for idx := range argTypes {
+		if isCriteriaArg(argTypes[idx]) {
+			// Criteria 可能没有任何条件
+			needWhereTag = true
+			break
+		}
+	}
+
 	if needWhereTag {
 		sb.WriteString(" <where>")
 	} else {
@@ -890,8 +1045,6 @@ func generateWhere(dbType Dialect, mapper *Mapper, rType reflect.Type, names []s
 		return false
 	}
 
-	hasOffset := false
-	hasLimit := false
 	isFirst := true
 	structType := mapper.TypeMap(rType)
 	for idx, name := range names {
@@ -928,7 +1081,7 @@ func generateWhere(dbType Dialect, mapper *Mapper, rType reflect.Type, names []s
 					sb.WriteString(`AND `)
 				}
 
-				sb.WriteString(deletedField.Name)
+				sb.WriteString(quoteName(dbType, mapper, deletedField.Name))
 				sb.WriteString(` IS NOT NULL </if>`)
 
 				sb.WriteString(`<if test="!`)
@@ -944,7 +1097,7 @@ func generateWhere(dbType Dialect, mapper *Mapper, rType reflect.Type, names []s
 					sb.WriteString(`AND `)
 				}
 
-				sb.WriteString(deletedField.Name)
+				sb.WriteString(quoteName(dbType, mapper, deletedField.Name))
 				sb.WriteString(` IS NULL `)
 				sb.WriteString(`</if>`)
 
@@ -960,18 +1113,30 @@ func generateWhere(dbType Dialect, mapper *Mapper, rType reflect.Type, names []s
 			argType = argTypes[idx]
 		}
 
+		if isCriteriaArg(argType) {
+			// <criteria /> 在它的每个条件前都加上 AND，多余的 AND 由 <where> 去掉
+			sb.WriteString(`<criteria value="`)
+			sb.WriteString(name)
+			sb.WriteString(`" />`)
+			isFirst = false
+			continue
+		}
+
 		isLike := false
 		field, isArgSlice, err := toFieldName(structType, name, argType)
 		if err != nil {
-			if stmtType == StatementTypeSelect {
-				if name == "offset" {
-					hasOffset = true
-					continue
-				}
-				if name == "limit" {
-					hasLimit = true
-					continue
+			if keys, isKeySlice := toKeyFields(mapper, structType, argType); keys != nil {
+				if isFirst {
+					isFirst = false
+				} else {
+					sb.WriteString(` AND `)
 				}
+				writeKeyCondition(sb, dbType, mapper, name, keys, isKeySlice)
+				continue
+			}
+			if stmtType == StatementTypeSelect && isPageArg(name) {
+				// 分页参数由 GenerateSelectSQL 在 ORDER BY 之后用 <page /> 元素输出
+				continue
 			}
 			if !strings.HasSuffix(strings.ToLower(name), "like") {
 				return err
@@ -996,7 +1161,7 @@ func generateWhere(dbType Dialect, mapper *Mapper, rType reflect.Type, names []s
 				sb.WriteString(` AND `)
 			}
 
-			sb.WriteString(field.Name)
+			sb.WriteString(quoteName(dbType, mapper, field.Name))
 			sb.WriteString(` in (<foreach collection="`)
 			sb.WriteString(name)
 			sb.WriteString(`" item="item" separator="," >#{item}</foreach>)`)
@@ -1011,7 +1176,7 @@ func generateWhere(dbType Dialect, mapper *Mapper, rType reflect.Type, names []s
 				sb.WriteString(`AND `)
 			}
 
-			sb.WriteString(field.Name)
+			sb.WriteString(quoteName(dbType, mapper, field.Name))
 			if isLike {
 				sb.WriteString(" like ")
 			} else {
@@ -1029,7 +1194,7 @@ func generateWhere(dbType Dialect, mapper *Mapper, rType reflect.Type, names []s
 			}
 
 			sb.WriteString(" (")
-			sb.WriteString(field.Name)
+			sb.WriteString(quoteName(dbType, mapper, field.Name))
 			sb.WriteString(" BETWEEN #{")
 			sb.WriteString(name)
 			sb.WriteString(".StartAt} AND #{")
@@ -1046,7 +1211,7 @@ func generateWhere(dbType Dialect, mapper *Mapper, rType reflect.Type, names []s
 				_, jsonExists = field.Options["jsonb"]
 			}
 			if jsonExists {
-				sb.WriteString(field.Name)
+				sb.WriteString(quoteName(dbType, mapper, field.Name))
 				sb.WriteString(" @> ")
 				sb.WriteString("#{")
 				sb.WriteString(name)
@@ -1055,7 +1220,7 @@ func generateWhere(dbType Dialect, mapper *Mapper, rType reflect.Type, names []s
 				sb.WriteString("#{")
 				sb.WriteString(name)
 				sb.WriteString("} = ANY (")
-				sb.WriteString(field.Name)
+				sb.WriteString(quoteName(dbType, mapper, field.Name))
 				sb.WriteString(")")
 			}
 		} else {
@@ -1065,7 +1230,7 @@ func generateWhere(dbType Dialect, mapper *Mapper, rType reflect.Type, names []s
 				sb.WriteString(` AND `)
 			}
 
-			sb.WriteString(field.Name)
+			sb.WriteString(quoteName(dbType, mapper, field.Name))
 			if isLike {
 				sb.WriteString(" like ")
 			} else {
@@ -1095,18 +1260,18 @@ func generateWhere(dbType Dialect, mapper *Mapper, rType reflect.Type, names []s
 			} else {
 				sb.WriteString(` AND `)
 			}
-			sb.WriteString(deletedField.Name)
+			sb.WriteString(quoteName(dbType, mapper, deletedField.Name))
 			sb.WriteString(" IS NULL")
 		}
 	}
 
-	if hasOffset {
-		// <if test="offset &gt; 0"> OFFSET #{offset} </if>
-		sb.WriteString(`<if test="offset &gt; 0"> OFFSET #{offset} </if>`)
-	}
-	if hasLimit {
-		// <if test="limit &gt; 0"> LIMIT #{limit} </if>
-		sb.WriteString(`<if test="limit &gt; 0"> LIMIT #{limit} </if>`)
+	if tenantField := findTenantField(mapper, rType); tenantField != nil {
+		if isFirst {
+			isFirst = false
+		} else {
+			sb.WriteString(` AND `)
+		}
+		sb.WriteString(tenantCondition(dbType, mapper, tenantField))
 	}
 
 	if needWhereTag {
@@ -1115,6 +1280,129 @@ func generateWhere(dbType Dialect, mapper *Mapper, rType reflect.Type, names []s
 	return nil
 }
 
+// keyField 是主键结构中的一个字段，column 是表中的主键列，name 是它在主键结构中的名称
+type keyField struct {
+	column string
+	name   string
+}
+
+// toKeyFields 判断参数是否是主键结构(或它的切片)，主键结构中的字段与表中的主键列一一对应，
+// 如 user_roles 表的主键是 (user_id, role_id) 时 struct{ UserID, RoleID int64 } 是它的主键结构
+func toKeyFields(mapper *Mapper, structType *StructMap, argType reflect.Type) ([]keyField, bool) {
+	if argType == nil {
+		return nil, false
+	}
+	isSlice := false
+	if argType.Kind() == reflect.Slice {
+		isSlice = true
+		argType = argType.Elem()
+	}
+	for argType.Kind() == reflect.Ptr {
+		argType = argType.Elem()
+	}
+	if argType.Kind() != reflect.Struct || IsTimeRange(argType) {
+		return nil, false
+	}
+	if ok, _, _ := isValidable(argType); ok {
+		return nil, false
+	}
+
+	var pk []*FieldInfo
+	for _, field := range structType.Index {
+		if _, ok := field.Options["pk"]; ok {
+			pk = append(pk, field)
+		}
+	}
+	if len(pk) == 0 {
+		return nil, false
+	}
+
+	var fields []*FieldInfo
+	for _, field := range mapper.TypeMap(argType).Index {
+		if field.Field.Anonymous {
+			continue
+		}
+		if field.Parent != nil && len(field.Parent.Index) != 0 && !field.Parent.Field.Anonymous {
+			continue
+		}
+		fields = append(fields, field)
+	}
+	if len(fields) != len(pk) {
+		return nil, false
+	}
+
+	keys := make([]keyField, 0, len(pk))
+	for _, pkField := range pk {
+		var found *FieldInfo
+		for _, field := range fields {
+			if strings.EqualFold(field.Name, pkField.Name) || strings.EqualFold(field.Field.Name, pkField.Field.Name) {
+				found = field
+				break
+			}
+		}
+		if found == nil {
+			return nil, false
+		}
+		keys = append(keys, keyField{column: pkField.Name, name: found.Name})
+	}
+	return keys, isSlice
+}
+
+// writeKeyCondition 输出主键结构的条件，参数是切片时用行值的 IN，数据库不支持行值时展开为 OR
+func writeKeyCondition(sb *strings.Builder, dbType Dialect, mapper *Mapper, name string, keys []keyField, isSlice bool) {
+	if !isSlice {
+		for idx, key := range keys {
+			if idx > 0 {
+				sb.WriteString(" AND ")
+			}
+			sb.WriteString(quoteName(dbType, mapper, key.column))
+			sb.WriteString("=#{")
+			sb.WriteString(name)
+			sb.WriteString(".")
+			sb.WriteString(key.name)
+			sb.WriteString("}")
+		}
+		return
+	}
+
+	if dbType.RowValueSupported() {
+		sb.WriteString("(")
+		for idx, key := range keys {
+			if idx > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString(quoteName(dbType, mapper, key.column))
+		}
+		sb.WriteString(`) in (<foreach collection="`)
+		sb.WriteString(name)
+		sb.WriteString(`" item="item" separator="," >(`)
+		for idx, key := range keys {
+			if idx > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString("#{item.")
+			sb.WriteString(key.name)
+			sb.WriteString("}")
+		}
+		sb.WriteString(`)</foreach>)`)
+		return
+	}
+
+	sb.WriteString(`(<foreach collection="`)
+	sb.WriteString(name)
+	sb.WriteString(`" item="item" separator=" OR " >(`)
+	for idx, key := range keys {
+		if idx > 0 {
+			sb.WriteString(" AND ")
+		}
+		sb.WriteString(quoteName(dbType, mapper, key.column))
+		sb.WriteString("=#{item.")
+		sb.WriteString(key.name)
+		sb.WriteString("}")
+	}
+	sb.WriteString(`)</foreach>)`)
+}
+
 func ToFieldName(mapper *Mapper, rType reflect.Type, name string, argType reflect.Type) (*FieldInfo, bool, error) {
 	structType := mapper.TypeMap(rType)
 	return toFieldName(structType, name, argType)
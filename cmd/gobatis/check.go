@@ -0,0 +1,158 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// driverPackages 是常用的驱动名对应的包，其它的驱动请把它的包加到 packages 中
+var driverPackages = map[string]string{
+	"postgres":  "github.com/lib/pq",
+	"mysql":     "github.com/go-sql-driver/mysql",
+	"mssql":     "github.com/denisenkom/go-mssqldb",
+	"sqlserver": "github.com/denisenkom/go-mssqldb",
+}
+
+// runCheck 执行 gobatis check [flags] packages
+//
+// 语句和结果类型是在 packages 的 init 中登记的，所以它在当前目录中生成一个导入 packages 的临时程序，
+// 并用 go run 执行它，由它调用 CheckResultColumns，有问题的语句输出到 stdout 并返回错误
+func runCheck(args []string) error {
+	flags := flag.NewFlagSet("check", flag.ExitOnError)
+	var driverName, dsn, xmlPaths string
+	flags.StringVar(&driverName, "driver", "postgres", "数据库驱动名")
+	flags.StringVar(&dsn, "dsn", "", "数据库连接字符串")
+	flags.StringVar(&xmlPaths, "xml", "", "xml 文件或目录，多个时用逗号分隔")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: gobatis check [flags] packages")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return errors.New("packages is missing")
+	}
+	if dsn == "" {
+		return errors.New("dsn is missing")
+	}
+
+	var paths []string
+	for _, path := range strings.Split(xmlPaths, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			abs, err := filepath.Abs(path)
+			if err != nil {
+				return err
+			}
+			paths = append(paths, abs)
+		}
+	}
+
+	packages, err := importPaths(flags.Args())
+	if err != nil {
+		return err
+	}
+	if pkg, ok := driverPackages[driverName]; ok {
+		exists := false
+		for _, name := range packages {
+			exists = exists || name == pkg
+		}
+		if !exists {
+			packages = append(packages, pkg)
+		}
+	}
+
+	dir, err := ioutil.TempDir(".", "gobatis_check")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "main.go")
+	out, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	err = checkMain.Execute(out, map[string]interface{}{
+		"packages": packages,
+		"driver":   driverName,
+		"dsn":      dsn,
+		"xmlPaths": paths,
+	})
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return errors.New("generate '" + filename + "' fail, " + err.Error())
+	}
+
+	cmd := exec.Command("go", "run", filename)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return errors.New("check fail, " + err.Error())
+	}
+	return nil
+}
+
+// importPaths 将 ./dao 这样的相对路径转换为导入路径
+func importPaths(packages []string) ([]string, error) {
+	paths := make([]string, 0, len(packages))
+	for _, pkg := range packages {
+		if !strings.HasPrefix(pkg, ".") {
+			paths = append(paths, pkg)
+			continue
+		}
+		out, err := exec.Command("go", "list", pkg).Output()
+		if err != nil {
+			return nil, errors.New("read import path of '" + pkg + "' fail, " + err.Error())
+		}
+		paths = append(paths, strings.Fields(string(out))...)
+	}
+	return paths, nil
+}
+
+var checkMain = template.Must(template.New("check").Parse(`// Code generated by gobatis check. DO NOT EDIT.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	gobatis "github.com/runner-mei/GoBatis"
+{{- range .packages}}
+	_ {{printf "%q" .}}
+{{- end}}
+)
+
+func main() {
+	factory, err := gobatis.New(&gobatis.Config{DriverName: {{printf "%q" .driver}},
+		DataSource: {{printf "%q" .dsn}},
+		XMLPaths:   []string{ {{- range $idx, $path := .xmlPaths}}{{if $idx}}, {{end}}{{printf "%q" $path}}{{end -}} }})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer factory.Close()
+
+	results := factory.CheckResultColumns(context.Background())
+	for _, result := range results {
+		fmt.Println(result.String())
+	}
+	if len(results) > 0 {
+		factory.Close()
+		os.Exit(1)
+	}
+}
+`))
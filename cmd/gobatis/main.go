@@ -3,16 +3,33 @@ package main
 import (
 	"flag"
 	"log"
+	"os"
 
 	"github.com/runner-mei/GoBatis/generator"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		var run func(args []string) error
+		switch os.Args[1] {
+		case "migrate":
+			run = runMigrate
+		case "check":
+			run = runCheck
+		}
+		if run != nil {
+			if err := run(os.Args[2:]); err != nil {
+				log.Fatalln(err)
+			}
+			return
+		}
+	}
+
 	var gen = generator.Generator{}
 	gen.Flags(flag.CommandLine)
 	flag.Parse()
 
 	if err := gen.Run(flag.Args()); err != nil {
-		log.Println(err)
+		log.Fatalln(err)
 	}
 }
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	_ "github.com/denisenkom/go-mssqldb"
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	gobatis "github.com/runner-mei/GoBatis"
+)
+
+// runMigrate 执行 gobatis migrate [flags] up|down|redo|status|version
+func runMigrate(args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	var driverName, dsn, dir, table string
+	flags.StringVar(&driverName, "driver", "postgres", "数据库驱动名")
+	flags.StringVar(&dsn, "dsn", "", "数据库连接字符串")
+	flags.StringVar(&dir, "dir", "migrations", "迁移脚本所在的目录")
+	flags.StringVar(&table, "table", "", "保存版本的表名，缺省为 gobatis_migrations")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: gobatis migrate [flags] up|down|redo|status|version")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("command is missing")
+	}
+	if dsn == "" {
+		return errors.New("dsn is missing")
+	}
+	dialect, ok := gobatis.LookupDialect(driverName)
+	if !ok {
+		return errors.New("dialect of the driver '" + driverName + "' isnot registered")
+	}
+
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator := gobatis.NewMigrator(db, dialect, nil, dir)
+	if table != "" {
+		migrator.Table = table
+	}
+
+	ctx := context.Background()
+	switch cmd := flags.Arg(0); cmd {
+	case "up":
+		return migrator.Up(ctx)
+	case "down":
+		return migrator.Down(ctx)
+	case "redo":
+		return migrator.Redo(ctx)
+	case "status":
+		status, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range status {
+			appliedAt := "pending"
+			if s.Applied {
+				appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(os.Stdout, "%-20s %d_%s\n", appliedAt, s.Version, s.Name)
+		}
+		return nil
+	case "version":
+		version, err := migrator.Version(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stdout, version)
+		return nil
+	default:
+		return errors.New("command '" + cmd + "' is unknown")
+	}
+}
@@ -4,13 +4,17 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"io/ioutil"
+	"io"
+	"io/fs"
 	"log"
 	"os"
+	"path"
 	"path/filepath"
+	"reflect"
 	"sort"
 	"strings"
 	"text/template"
+	"time"
 )
 
 type Config struct {
@@ -24,12 +28,38 @@ type Config struct {
 	DataSource   string
 	MaxIdleConns int
 	MaxOpenConns int
+	// Dialect 为 nil 时按 DriverName 查找用 RegisterDialect 注册的 Dialect
+	Dialect Dialect
+	// QuoteIdentifiers 是生成的语句中给表名和列名加引号的方式，默认只在名称是保留字、
+	// 有大写字母或特殊字符时才加引号
+	QuoteIdentifiers QuoteMode
+
+	XMLPaths []string
+	// XMLFS 不为 nil 时从它中读取 XMLPaths 指定的文件或目录(如 embed.FS),
+	// 这时 XMLPaths 为空表示它的根目录
+	XMLFS fs.FS
+	// XMLReloadInterval 大于 0 时会按这个间隔检查 XMLPaths 中的文件，
+	// 文件有改动时重新加载它，仅建议在开发时使用
+	XMLReloadInterval time.Duration
+	// StatementOverride 同一个 id 的语句被多次定义时的处理策略
+	StatementOverride OverridePolicy
 
-	XMLPaths      []string
 	IsUnsafe      bool
 	TagPrefix     string
 	TagMapper     func(s string, fieldName string) []string
 	TemplateFuncs template.FuncMap
+	// ExpressionFuncs 是 <if test> 和 <when test> 中可以使用的自定义函数，同名时覆盖内置的函数
+	ExpressionFuncs map[string]func(args ...interface{}) (interface{}, error)
+	// TypeHandlers 是自定义类型的转换器，用于参数和结果中的第三方类型
+	TypeHandlers *TypeHandlers
+
+	// Replicas 是只读的从库，select 语句会在从库上执行，事务中的语句和
+	// WithPrimary 的 context 中的语句除外，其它语句总是在主库(DB)上执行
+	Replicas []DBRunner
+	// ReplicaPolicy 是选择从库的策略
+	ReplicaPolicy ReplicaPolicy
+	// Sharding 不为 nil 时按分片键将语句路由到各个分片上，详见 Sharding
+	Sharding *Sharding
 }
 
 type DBRunner interface {
@@ -48,8 +78,13 @@ type Connection struct {
 	dialect       Dialect
 	mapper        *Mapper
 	db            DBRunner
-	sqlStatements map[string]*MappedStatement
+	sqlStatements *mappedStatements
 	isUnsafe      bool
+
+	watcher     *xmlWatcher
+	resultTypes map[string]reflect.Type
+	replicas    *replicaSet
+	shards      *shardSet
 }
 
 func (conn *Connection) DB() DBRunner {
@@ -75,18 +110,32 @@ func (conn *Connection) Mapper() *Mapper {
 	return conn.mapper
 }
 
+// StatementOverrides 返回被覆盖的语句，重新加载 xml 后返回重新加载时被覆盖的语句
+func (conn *Connection) StatementOverrides() []StatementOverride {
+	return conn.sqlStatements.overrides()
+}
+
 func (conn *Connection) Insert(ctx context.Context, id string, paramNames []string, paramValues []interface{}, notReturn ...bool) (int64, error) {
-	sqlAndParams, _, err := conn.readSQLParams(id, StatementTypeInsert, paramNames, paramValues)
+	sqlAndParams, _, err := conn.readSQLParams(ctx, id, StatementTypeInsert, paramNames, paramValues)
 	if err != nil {
 		return 0, err
 	}
 
+	db := conn.db
+	shards, err := conn.route(paramNames, paramValues, sqlAndParams, false)
+	if err != nil {
+		return 0, err
+	}
+	if len(shards) > 0 {
+		db, sqlAndParams = shards[0].db, shards[0].sqlAndParams
+	}
+
 	for idx := 0; idx < len(sqlAndParams)-1; idx++ {
 		if conn.showSQL {
 			conn.logger.Printf(`id:"%s", sql:"%s", params:"%+v"`, id, sqlAndParams[idx].SQL, sqlAndParams[idx].Params)
 		}
 
-		_, err := conn.db.ExecContext(ctx, sqlAndParams[idx].SQL, sqlAndParams[idx].Params...)
+		_, err := db.ExecContext(ctx, sqlAndParams[idx].SQL, sqlAndParams[idx].Params...)
 		if err != nil {
 			return 0, conn.dialect.HandleError(err)
 		}
@@ -99,13 +148,22 @@ func (conn *Connection) Insert(ctx context.Context, id string, paramNames []stri
 		conn.logger.Printf(`id:"%s", sql:"%s", params:"%+v"`, id, sqlStr, sqlParams)
 	}
 
+	if conn.dialect.Returning() == ReturningInto && strings.Contains(sqlStr, ReturningIntoParam) {
+		var insertID int64
+		_, err := db.ExecContext(ctx, sqlStr, append(sqlParams, sql.Named(ReturningIntoParam[1:], sql.Out{Dest: &insertID}))...)
+		if err != nil {
+			return 0, conn.dialect.HandleError(err)
+		}
+		return insertID, nil
+	}
+
 	if len(notReturn) > 0 && notReturn[0] {
-		_, err := conn.db.ExecContext(ctx, sqlStr, sqlParams...)
+		_, err := db.ExecContext(ctx, sqlStr, sqlParams...)
 		return 0, conn.dialect.HandleError(err)
 	}
 
 	if conn.dialect.InsertIDSupported() {
-		result, err := conn.db.ExecContext(ctx, sqlStr, sqlParams...)
+		result, err := db.ExecContext(ctx, sqlStr, sqlParams...)
 		if err != nil {
 			return 0, conn.dialect.HandleError(err)
 		}
@@ -117,37 +175,100 @@ func (conn *Connection) Insert(ctx context.Context, id string, paramNames []stri
 	}
 
 	var insertID int64
-	err = conn.db.QueryRowContext(ctx, sqlStr, sqlParams...).Scan(&insertID)
+	err = db.QueryRowContext(ctx, sqlStr, sqlParams...).Scan(&insertID)
 	if err != nil {
 		return 0, conn.dialect.HandleError(err)
 	}
 	return insertID, nil
 }
 
+// InsertReturning 执行返回整行的 insert 语句(RETURNING * 或 OUTPUT INSERTED.*)，并将返回的行读到 result 中，
+// 数据库生成的值(如默认值、自增列和计算列)会读回到 result 中，result 一般是插入的结构的指针
+func (conn *Connection) InsertReturning(ctx context.Context, id string, paramNames []string, paramValues []interface{}, result interface{}) error {
+	return conn.execReturning(ctx, id, StatementTypeInsert, paramNames, paramValues, result)
+}
+
+// UpdateReturning 执行返回整行的 update 语句，并将返回的行读到 result 中，详见 InsertReturning
+func (conn *Connection) UpdateReturning(ctx context.Context, id string, paramNames []string, paramValues []interface{}, result interface{}) error {
+	return conn.execReturning(ctx, id, StatementTypeUpdate, paramNames, paramValues, result)
+}
+
+func (conn *Connection) execReturning(ctx context.Context, id string, sqlType StatementType, paramNames []string, paramValues []interface{}, result interface{}) error {
+	sqlAndParams, _, err := conn.readSQLParams(ctx, id, sqlType, paramNames, paramValues)
+	if err != nil {
+		return err
+	}
+
+	db := conn.db
+	shards, err := conn.route(paramNames, paramValues, sqlAndParams, false)
+	if err != nil {
+		return err
+	}
+	if len(shards) > 0 {
+		db, sqlAndParams = shards[0].db, shards[0].sqlAndParams
+	}
+
+	for idx := 0; idx < len(sqlAndParams)-1; idx++ {
+		if conn.showSQL {
+			conn.logger.Printf(`id:"%s", sql:"%s", params:"%+v"`, id, sqlAndParams[idx].SQL, sqlAndParams[idx].Params)
+		}
+
+		_, err := db.ExecContext(ctx, sqlAndParams[idx].SQL, sqlAndParams[idx].Params...)
+		if err != nil {
+			return conn.dialect.HandleError(err)
+		}
+	}
+
+	last := sqlAndParams[len(sqlAndParams)-1]
+	return Result{o: conn,
+		ctx:       ctx,
+		db:        db,
+		id:        id,
+		sql:       last.SQL,
+		sqlParams: last.Params,
+	}.Scan(result)
+}
+
 func (conn *Connection) Update(ctx context.Context, id string, paramNames []string, paramValues []interface{}) (int64, error) {
-	sqlAndParams, _, err := conn.readSQLParams(id, StatementTypeUpdate, paramNames, paramValues)
+	sqlAndParams, _, err := conn.readSQLParams(ctx, id, StatementTypeUpdate, paramNames, paramValues)
 	if err != nil {
 		return 0, err
 	}
-	return conn.execute(ctx, id, sqlAndParams)
+
+	shards, err := conn.route(paramNames, paramValues, sqlAndParams, false)
+	if err != nil {
+		return 0, err
+	}
+	if len(shards) > 0 {
+		return conn.execute(ctx, shards[0].db, id, shards[0].sqlAndParams)
+	}
+	return conn.execute(ctx, conn.db, id, sqlAndParams)
 }
 
 func (conn *Connection) Delete(ctx context.Context, id string, paramNames []string, paramValues []interface{}) (int64, error) {
-	sqlAndParams, _, err := conn.readSQLParams(id, StatementTypeDelete, paramNames, paramValues)
+	sqlAndParams, _, err := conn.readSQLParams(ctx, id, StatementTypeDelete, paramNames, paramValues)
 	if err != nil {
 		return 0, err
 	}
-	return conn.execute(ctx, id, sqlAndParams)
+
+	shards, err := conn.route(paramNames, paramValues, sqlAndParams, false)
+	if err != nil {
+		return 0, err
+	}
+	if len(shards) > 0 {
+		return conn.execute(ctx, shards[0].db, id, shards[0].sqlAndParams)
+	}
+	return conn.execute(ctx, conn.db, id, sqlAndParams)
 }
 
-func (conn *Connection) execute(ctx context.Context, id string, sqlAndParams []sqlAndParam) (int64, error) {
+func (conn *Connection) execute(ctx context.Context, db DBRunner, id string, sqlAndParams []sqlAndParam) (int64, error) {
 	rowsAffected := int64(0)
 	for idx := range sqlAndParams {
 		if conn.showSQL {
 			conn.logger.Printf(`id:"%s", sql:"%s", params:"%+v"`, id, sqlAndParams[idx].SQL, sqlAndParams[idx].Params)
 		}
 
-		result, err := conn.db.ExecContext(ctx, sqlAndParams[idx].SQL, sqlAndParams[idx].Params...)
+		result, err := db.ExecContext(ctx, sqlAndParams[idx].SQL, sqlAndParams[idx].Params...)
 		if err != nil {
 			return 0, conn.dialect.HandleError(err)
 		}
@@ -162,7 +283,7 @@ func (conn *Connection) execute(ctx context.Context, id string, sqlAndParams []s
 }
 
 func (conn *Connection) SelectOne(ctx context.Context, id string, paramNames []string, paramValues []interface{}) Result {
-	sqlAndParams, _, err := conn.readSQLParams(id, StatementTypeSelect, paramNames, paramValues)
+	sqlAndParams, _, err := conn.readSQLParams(ctx, id, StatementTypeSelect, paramNames, paramValues)
 	if err != nil {
 		return Result{o: conn,
 			ctx: ctx,
@@ -178,8 +299,28 @@ func (conn *Connection) SelectOne(ctx context.Context, id string, paramNames []s
 			err: ErrMultSQL,
 		}
 	}
+
+	shards, err := conn.route(paramNames, paramValues, sqlAndParams, true)
+	if err != nil {
+		return Result{o: conn,
+			ctx: ctx,
+			id:  id,
+			err: err,
+		}
+	}
+	if len(shards) > 0 {
+		return Result{o: conn,
+			ctx:       ctx,
+			db:        shards[0].db,
+			id:        id,
+			sql:       shards[0].sqlAndParams[0].SQL,
+			sqlParams: shards[0].sqlAndParams[0].Params,
+			shards:    shards[1:],
+		}
+	}
 	return Result{o: conn,
 		ctx:       ctx,
+		db:        conn.readDB(ctx),
 		id:        id,
 		sql:       sqlAndParams[0].SQL,
 		sqlParams: sqlAndParams[0].Params,
@@ -187,7 +328,7 @@ func (conn *Connection) SelectOne(ctx context.Context, id string, paramNames []s
 }
 
 func (conn *Connection) Select(ctx context.Context, id string, paramNames []string, paramValues []interface{}) *Results {
-	sqlAndParams, _, err := conn.readSQLParams(id, StatementTypeSelect, paramNames, paramValues)
+	sqlAndParams, _, err := conn.readSQLParams(ctx, id, StatementTypeSelect, paramNames, paramValues)
 	if err != nil {
 		return &Results{o: conn,
 			ctx: ctx,
@@ -203,16 +344,36 @@ func (conn *Connection) Select(ctx context.Context, id string, paramNames []stri
 		}
 	}
 
+	shards, err := conn.route(paramNames, paramValues, sqlAndParams, true)
+	if err != nil {
+		return &Results{o: conn,
+			ctx: ctx,
+			id:  id,
+			err: err,
+		}
+	}
+	if len(shards) > 0 {
+		return &Results{o: conn,
+			ctx:       ctx,
+			db:        shards[0].db,
+			id:        id,
+			sql:       shards[0].sqlAndParams[0].SQL,
+			sqlParams: shards[0].sqlAndParams[0].Params,
+			shards:    shards[1:],
+		}
+	}
+
 	return &Results{o: conn,
 		ctx:       ctx,
+		db:        conn.readDB(ctx),
 		id:        id,
 		sql:       sqlAndParams[0].SQL,
 		sqlParams: sqlAndParams[0].Params,
 	}
 }
 
-func (o *Connection) readSQLParams(id string, sqlType StatementType, paramNames []string, paramValues []interface{}) ([]sqlAndParam, ResultType, error) {
-	stmt, ok := o.sqlStatements[id]
+func (o *Connection) readSQLParams(ctx context.Context, id string, sqlType StatementType, paramNames []string, paramValues []interface{}) ([]sqlAndParam, ResultType, error) {
+	stmt, ok := o.sqlStatements.get(id)
 	if !ok {
 		return nil, ResultUnknown, fmt.Errorf("sql '%s' error : statement not found ", id)
 	}
@@ -222,12 +383,13 @@ func (o *Connection) readSQLParams(id string, sqlType StatementType, paramNames
 			id, sqlType.String(), stmt.sqlType.String())
 	}
 
-	ctx, err := NewContext(o.dialect, o.mapper, paramNames, paramValues)
+	sqlCtx, err := NewContext(o.dialect, o.mapper, paramNames, paramValues)
 	if err != nil {
 		return nil, ResultUnknown, fmt.Errorf("sql '%s' error : %s", id, err)
 	}
+	sqlCtx.stdCtx = ctx
 
-	sqlAndParams, err := stmt.GenerateSQLs(ctx)
+	sqlAndParams, err := stmt.GenerateSQLs(sqlCtx)
 	if err != nil {
 		return nil, ResultUnknown, fmt.Errorf("sql '%s' error : %s", id, err)
 	}
@@ -253,6 +415,15 @@ func newConnection(cfg *Config) (*Connection, error) {
 		cfg.TemplateFuncs[k] = v
 	}
 
+	dialect := cfg.Dialect
+	if dialect == nil {
+		var ok bool
+		dialect, ok = LookupDialect(cfg.DriverName)
+		if !ok {
+			return nil, fmt.Errorf("create gobatis error : dialect of the driver '%s' isnot registered, please register it with RegisterDialect", cfg.DriverName)
+		}
+	}
+
 	if cfg.DB == nil {
 		db, err := sql.Open(cfg.DriverName, cfg.DataSource)
 		if err != nil {
@@ -273,11 +444,17 @@ func newConnection(cfg *Config) (*Connection, error) {
 		cfg.DB = db
 	}
 
+	shards, err := newShardSet(cfg.Sharding)
+	if err != nil {
+		return nil, err
+	}
+
 	base := &Connection{
-		logger:        cfg.Logger,
-		showSQL:       cfg.ShowSQL,
-		db:            cfg.DB,
-		sqlStatements: make(map[string]*MappedStatement),
+		logger:   cfg.Logger,
+		showSQL:  cfg.ShowSQL,
+		db:       cfg.DB,
+		replicas: newReplicaSet(cfg.Replicas, cfg.ReplicaPolicy),
+		shards:   shards,
 	}
 	var tagPrefix string
 	var tagMapper func(string, string) []string
@@ -287,72 +464,57 @@ func newConnection(cfg *Config) (*Connection, error) {
 		tagMapper = cfg.TagMapper
 	}
 	base.mapper = CreateMapper(tagPrefix, nil, tagMapper)
-	base.dialect = ToDbType(cfg.DriverName)
-	if base.dialect == DbTypeNone {
-		base.dialect = DbTypePostgres
-	}
+	base.mapper.typeHandlers = cfg.TypeHandlers
+	base.mapper.quoteMode = cfg.QuoteIdentifiers
+	base.dialect = dialect
 
 	dbName := strings.ToLower(base.Dialect().Name())
-	xmlPaths := []string{}
-	for _, xmlPath := range cfg.XMLPaths {
-		pathInfo, err := os.Stat(xmlPath)
-		if err != nil {
-			if os.IsNotExist(err) {
-				continue
-			}
-			return nil, err
-		}
-
-		if !pathInfo.IsDir() {
-			xmlPaths = append(xmlPaths, xmlPath)
-			continue
-		}
-
-		fs, err := ioutil.ReadDir(xmlPath)
-		if err != nil {
-			return nil, err
-		}
-
-		for _, fileInfo := range fs {
-			if !fileInfo.IsDir() {
-				if fileName := fileInfo.Name(); strings.ToLower(filepath.Ext(fileName)) == ".xml" {
-					xmlPaths = append(xmlPaths, filepath.Join(xmlPath, fileName))
-				}
-				continue
-			}
-
-			if dbName != strings.ToLower(fileInfo.Name()) {
-				continue
-			}
-
-			dialectDirs, err := ioutil.ReadDir(filepath.Join(xmlPath, fileInfo.Name()))
-			if err != nil {
-				return nil, err
-			}
-
-			for _, dialectInfo := range dialectDirs {
-				if fileName := dialectInfo.Name(); strings.ToLower(filepath.Ext(fileName)) == ".xml" {
-					xmlPaths = append(xmlPaths, filepath.Join(xmlPath, fileInfo.Name(), fileName))
-				}
-			}
-		}
+	xmlPathList := cfg.XMLPaths
+	if cfg.XMLFS != nil && len(xmlPathList) == 0 {
+		xmlPathList = []string{"."}
+	}
+	xmlPaths, err := findXMLFiles(cfg.XMLFS, xmlPathList, dbName)
+	if err != nil {
+		return nil, err
 	}
 
+	statements := make(map[string]*MappedStatement)
 	ctx := &InitContext{Config: cfg,
 		Logger:     cfg.Logger,
 		Dialect:    base.dialect,
 		Mapper:     base.mapper,
-		Statements: base.sqlStatements}
+		Statements: statements}
+
+	var watcher *xmlWatcher
+	if cfg.XMLReloadInterval > 0 {
+		watcher = newXMLWatcher(ctx, cfg.XMLFS, xmlPathList, dbName, cfg.XMLReloadInterval)
+		ctx.keepGenerated = true
+	}
 
 	for _, xmlPath := range xmlPaths {
 		log.Println("load xml -", xmlPath)
-		statements, err := readMappedStatements(ctx, xmlPath)
+		var fileInfo fs.FileInfo
+		if watcher != nil {
+			// 先取文件信息再读文件，读取过程中的修改会在下次检查时被发现
+			fileInfo, err = statXMLFile(cfg.XMLFS, xmlPath)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		xmlStatements, err := readMappedStatements(ctx, cfg.XMLFS, xmlPath)
 		if err != nil {
 			return nil, err
 		}
 
-		for _, sm := range statements {
-			base.sqlStatements[sm.id] = sm
+		for _, sm := range xmlStatements {
+			if err := ctx.registerStatement(sm); err != nil {
+				return nil, err
+			}
+		}
+
+		if watcher != nil {
+			watcher.loaded(xmlPath, fileInfo, xmlStatements)
 		}
 	}
 
@@ -360,10 +522,17 @@ func newConnection(cfg *Config) (*Connection, error) {
 		return nil, err
 	}
 
+	base.resultTypes = ctx.resultTypes
+	base.sqlStatements = newMappedStatements(statements, ctx.overrides)
+	if watcher != nil {
+		watcher.start(base.sqlStatements, ctx.generated)
+		base.watcher = watcher
+	}
+
 	if cfg.DumpSQLStatements || os.Getenv("gobatis_dump_statements") == "true" {
-		var sqlStatements = make([][2]string, 0, len(base.sqlStatements))
+		var sqlStatements = make([][2]string, 0, len(statements))
 		keyLen := 0
-		for id, stmt := range base.sqlStatements {
+		for id, stmt := range statements {
 			if len(id) > keyLen {
 				keyLen = len(id)
 			}
@@ -385,3 +554,80 @@ func newConnection(cfg *Config) (*Connection, error) {
 	}
 	return base, nil
 }
+
+// findXMLFiles 查找 xml 文件, fsys 为 nil 时表示操作系统的文件系统
+func findXMLFiles(fsys fs.FS, xmlPathList []string, dbName string) ([]string, error) {
+	xmlPaths := []string{}
+	for _, xmlPath := range xmlPathList {
+		pathInfo, err := statXMLFile(fsys, xmlPath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		if !pathInfo.IsDir() {
+			xmlPaths = append(xmlPaths, xmlPath)
+			continue
+		}
+
+		entries, err := readXMLDir(fsys, xmlPath)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				if fileName := entry.Name(); strings.ToLower(path.Ext(fileName)) == ".xml" {
+					xmlPaths = append(xmlPaths, joinXMLPath(fsys, xmlPath, fileName))
+				}
+				continue
+			}
+
+			if dbName != strings.ToLower(entry.Name()) {
+				continue
+			}
+
+			dialectDirs, err := readXMLDir(fsys, joinXMLPath(fsys, xmlPath, entry.Name()))
+			if err != nil {
+				return nil, err
+			}
+
+			for _, dialectInfo := range dialectDirs {
+				if fileName := dialectInfo.Name(); strings.ToLower(path.Ext(fileName)) == ".xml" {
+					xmlPaths = append(xmlPaths, joinXMLPath(fsys, xmlPath, entry.Name(), fileName))
+				}
+			}
+		}
+	}
+	return xmlPaths, nil
+}
+
+func statXMLFile(fsys fs.FS, name string) (fs.FileInfo, error) {
+	if fsys == nil {
+		return os.Stat(name)
+	}
+	return fs.Stat(fsys, name)
+}
+
+func readXMLDir(fsys fs.FS, name string) ([]fs.DirEntry, error) {
+	if fsys == nil {
+		return os.ReadDir(name)
+	}
+	return fs.ReadDir(fsys, name)
+}
+
+func openXMLFile(fsys fs.FS, name string) (io.ReadCloser, error) {
+	if fsys == nil {
+		return os.Open(name)
+	}
+	return fsys.Open(name)
+}
+
+func joinXMLPath(fsys fs.FS, elem ...string) string {
+	if fsys == nil {
+		return filepath.Join(elem...)
+	}
+	return path.Join(elem...)
+}
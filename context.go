@@ -2,7 +2,11 @@ package gobatis
 
 import (
 	"context"
+	"errors"
 	"log"
+	"reflect"
+	"runtime"
+	"strconv"
 	"sync"
 )
 
@@ -13,6 +17,8 @@ type SqlSession interface {
 	Insert(ctx context.Context, id string, paramNames []string, paramValues []interface{}, notReturn ...bool) (int64, error)
 	Update(ctx context.Context, id string, paramNames []string, paramValues []interface{}) (int64, error)
 	Delete(ctx context.Context, id string, paramNames []string, paramValues []interface{}) (int64, error)
+	InsertReturning(ctx context.Context, id string, paramNames []string, paramValues []interface{}, result interface{}) error
+	UpdateReturning(ctx context.Context, id string, paramNames []string, paramValues []interface{}, result interface{}) error
 	SelectOne(ctx context.Context, id string, paramNames []string, paramValues []interface{}) Result
 	Select(ctx context.Context, id string, paramNames []string, paramValues []interface{}) *Results
 }
@@ -35,6 +41,149 @@ type InitContext struct {
 	Dialect    Dialect
 	Mapper     *Mapper
 	Statements map[string]*MappedStatement
+
+	overrides   []StatementOverride
+	resultTypes map[string]reflect.Type
+	// keepGenerated 为 true 时代码中定义的语句总是会生成，并保存在 generated 中(包括被 xml 覆盖的)，
+	// 重新加载 xml 时用它们重新按 Config.StatementOverride 合并
+	keepGenerated bool
+	generated     []*MappedStatement
+	// lenient 为 true 时 test 表达式中未知的函数不是错误，go 模板也不会被解析，仅用于检查语句中的引用
+	lenient bool
+}
+
+// OverridePolicy 同一个 id 的 sql 语句被多次定义时的处理策略
+type OverridePolicy int
+
+const (
+	// OverrideXMLOverridesGenerated xml 中的语句优先于代码生成的语句,
+	// 多个 xml 文件中有相同 id 时后加载的优先，这是缺省的策略
+	OverrideXMLOverridesGenerated OverridePolicy = iota
+	// OverrideError 有重复的 id 时返回错误
+	OverrideError
+	// OverrideFirstWins 先定义的语句优先
+	OverrideFirstWins
+	// OverrideLastWins 后定义的语句优先，代码生成的语句在 xml 之后注册
+	OverrideLastWins
+)
+
+// StatementSource 语句定义的位置
+type StatementSource struct {
+	File string
+	Line int
+	// Generated 为 true 时表示语句不是从 xml 文件中读取的
+	Generated bool
+}
+
+func (s StatementSource) String() string {
+	if s.File == "" {
+		return "unknown"
+	}
+	txt := s.File + ":" + strconv.Itoa(s.Line)
+	if s.Generated {
+		return txt + "(generated)"
+	}
+	return txt
+}
+
+// StatementOverride 记录一个被覆盖的语句
+type StatementOverride struct {
+	ID         string
+	Active     StatementSource
+	Overridden StatementSource
+}
+
+func (ctx *InitContext) overridePolicy() OverridePolicy {
+	if ctx.Config == nil {
+		return OverrideXMLOverridesGenerated
+	}
+	return ctx.Config.StatementOverride
+}
+
+func (ctx *InitContext) addOverride(id string, active, overridden StatementSource) {
+	if ctx.Logger != nil {
+		ctx.Logger.Println("statement '"+id+"' of", overridden.String(), "is overridden by", active.String())
+	}
+	ctx.overrides = append(ctx.overrides, StatementOverride{
+		ID:         id,
+		Active:     active,
+		Overridden: overridden,
+	})
+}
+
+func callerSource(skip int) StatementSource {
+	_, file, line, ok := runtime.Caller(skip + 1)
+	if !ok {
+		return StatementSource{Generated: true}
+	}
+	return StatementSource{File: file, Line: line, Generated: true}
+}
+
+// ShouldGenerate 判断代码中定义的语句是否需要生成，已经有同一个 id 的语句时按 Config.StatementOverride 判断
+func (ctx *InitContext) ShouldGenerate(id string) bool {
+	old, exists := ctx.Statements[id]
+	if !exists || ctx.keepGenerated {
+		return true
+	}
+
+	switch ctx.overridePolicy() {
+	case OverrideError, OverrideLastWins:
+		// 由 RegisterStatement 来处理
+		return true
+	default:
+		ctx.addOverride(id, old.source, callerSource(1))
+		return false
+	}
+}
+
+// RegisterStatement 注册一个代码中定义的语句，已经有同一个 id 的语句时按 Config.StatementOverride 处理
+func (ctx *InitContext) RegisterStatement(stmt *MappedStatement) error {
+	if stmt.source.File == "" {
+		stmt.source = callerSource(1)
+	}
+	return ctx.registerStatement(stmt)
+}
+
+func (ctx *InitContext) registerStatement(stmt *MappedStatement) error {
+	if ctx.keepGenerated && stmt.source.Generated {
+		ctx.generated = append(ctx.generated, stmt)
+	}
+
+	old, exists := ctx.Statements[stmt.id]
+	if !exists {
+		ctx.Statements[stmt.id] = stmt
+		return nil
+	}
+
+	var replace bool
+	switch ctx.overridePolicy() {
+	case OverrideError:
+		return errors.New("statement '" + stmt.id + "' is duplicated, first is at " + old.source.String() +
+			", second is at " + stmt.source.String())
+	case OverrideFirstWins:
+		replace = false
+	case OverrideLastWins:
+		replace = true
+	default:
+		replace = old.source.Generated || !stmt.source.Generated
+	}
+
+	if replace {
+		ctx.Statements[stmt.id] = stmt
+		ctx.addOverride(stmt.id, stmt.source, old.source)
+	} else {
+		ctx.addOverride(stmt.id, old.source, stmt.source)
+	}
+	return nil
+}
+
+// RegisterResultType 登记 select 语句声明的结果类型(数组、map 和指针的元素类型)，
+// 它只用于 CheckResultColumns 检查结果中的列和结构的字段是否一致
+func (ctx *InitContext) RegisterResultType(id string, typ reflect.Type) {
+	if ctx.resultTypes == nil {
+		ctx.resultTypes = map[string]reflect.Type{}
+	}
+	ctx.resultTypes[id] = typ
 }
 
 var (
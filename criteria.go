@@ -0,0 +1,212 @@
+package gobatis
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// Criteria 是可以作为参数的查询条件，条件之间是 AND 的关系，生成工具会为方法参数中的
+// *XxxCriteria 生成一个内嵌了 Criteria 的类型和按 Xxx 的字段生成的条件方法，如
+//
+//	c := new(UserCriteria).StatusIn(1, 2).Or(
+//		new(UserCriteria).UsernameLike("a%"),
+//		new(UserCriteria).CreatedAtBetween(start, end))
+//
+// 它在语句中用 <criteria value="c" /> 元素输出，一般放在 <where> 中，
+// 参数有 RecordType() 方法时名称是这个结构中的字段名或列名，否则直接作为列名(只能是字母、数字和下划线)
+type Criteria struct {
+	items []criterion
+}
+
+// criterion 是一个条件或一组条件，groups 不为 nil 时是一组条件
+type criterion struct {
+	name   string
+	op     Operator
+	value  interface{}
+	or     bool
+	groups []*Criteria
+}
+
+// criteriaHolder 是 *Criteria 或内嵌了 Criteria 的类型
+type criteriaHolder interface {
+	criteria() *Criteria
+}
+
+// recordTyper 返回条件对应的结构，用于将字段名转换为列名
+type recordTyper interface {
+	RecordType() reflect.Type
+}
+
+func (c *Criteria) criteria() *Criteria {
+	return c
+}
+
+// Add 加上一个条件，value 的规则和 Query.Where 一样
+func (c *Criteria) Add(name string, op Operator, value interface{}) *Criteria {
+	c.items = append(c.items, criterion{name: name, op: op, value: value})
+	return c
+}
+
+// And 加上一组用 AND 连接的条件
+func (c *Criteria) And(groups ...*Criteria) *Criteria {
+	c.items = append(c.items, criterion{groups: groups})
+	return c
+}
+
+// Or 加上一组用 OR 连接的条件，每个 group 中的条件之间仍是 AND 的关系
+func (c *Criteria) Or(groups ...*Criteria) *Criteria {
+	c.items = append(c.items, criterion{or: true, groups: groups})
+	return c
+}
+
+// IsEmpty 判断是否没有任何条件
+func (c *Criteria) IsEmpty() bool {
+	return c == nil || len(c.items) == 0
+}
+
+// build 返回所有的条件和它们的参数，参数用 ? 表示，column 将名称转换为列名
+func (c *Criteria) build(column func(name string) (string, error)) ([]string, []interface{}, error) {
+	if c == nil {
+		return nil, nil, nil
+	}
+
+	var conditions []string
+	var params []interface{}
+	for _, item := range c.items {
+		if item.groups == nil {
+			col, err := column(item.name)
+			if err != nil {
+				return nil, nil, err
+			}
+			cond, values, err := condition(col, item.name, item.op, item.value)
+			if err != nil {
+				return nil, nil, err
+			}
+			if cond != "" {
+				conditions = append(conditions, cond)
+				params = append(params, values...)
+			}
+			continue
+		}
+
+		var parts []string
+		var groupParams []interface{}
+		alwaysTrue := false
+		for _, group := range item.groups {
+			conds, values, err := group.build(column)
+			if err != nil {
+				return nil, nil, err
+			}
+			if len(conds) == 0 {
+				if item.or {
+					// 其中一组没有条件时整个 OR 总是成立
+					alwaysTrue = true
+				}
+				continue
+			}
+			part := strings.Join(conds, " AND ")
+			if item.or && len(conds) > 1 {
+				part = "(" + part + ")"
+			}
+			parts = append(parts, part)
+			groupParams = append(groupParams, values...)
+		}
+		if alwaysTrue || len(parts) == 0 {
+			continue
+		}
+		if len(parts) == 1 {
+			conditions = append(conditions, parts[0])
+		} else if item.or {
+			conditions = append(conditions, "("+strings.Join(parts, " OR ")+")")
+		} else {
+			conditions = append(conditions, strings.Join(parts, " AND "))
+		}
+		params = append(params, groupParams...)
+	}
+	return conditions, params, nil
+}
+
+// criteriaExpression 是 <criteria value="c" /> 元素，它输出参数 c 中的条件，每个条件前都加上 AND，
+// 参数为 nil 时不输出任何内容
+type criteriaExpression struct {
+	value string
+}
+
+func (expr *criteriaExpression) String() string {
+	return `<criteria value="` + expr.value + `" />`
+}
+
+func (expr *criteriaExpression) writeTo(printer *sqlPrinter) {
+	value, err := printer.ctx.Get(expr.value)
+	if err != nil {
+		printer.err = errors.New("search '" + expr.value + "' fail, " + err.Error())
+		return
+	}
+	if value == nil {
+		return
+	}
+	holder, ok := value.(criteriaHolder)
+	if !ok {
+		printer.err = fmt.Errorf("'%s' isnot a gobatis.Criteria, got %T", expr.value, value)
+		return
+	}
+	if rValue := reflect.ValueOf(value); rValue.Kind() == reflect.Ptr && rValue.IsNil() {
+		return
+	}
+
+	dialect, mapper := printer.ctx.Dialect, printer.ctx.Mapper
+	column := func(name string) (string, error) {
+		if !isColumnName(name) {
+			return "", errors.New("criteria name '" + name + "' isnot a column name")
+		}
+		return quoteName(dialect, mapper, name), nil
+	}
+	if typer, ok := value.(recordTyper); ok {
+		structType := mapper.TypeMap(typer.RecordType())
+		column = func(name string) (string, error) {
+			field, _, err := toFieldName(structType, name, nil)
+			if err != nil {
+				return "", err
+			}
+			return quoteName(dialect, mapper, field.Name), nil
+		}
+	}
+
+	conditions, params, err := holder.criteria().build(column)
+	if err != nil {
+		printer.err = err
+		return
+	}
+	if len(conditions) == 0 {
+		return
+	}
+	fragments := strings.Split(" AND "+strings.Join(conditions, " AND "), "?")
+	printer.sb.WriteString(dialect.Placeholder().Concat(fragments, nil, len(printer.params)))
+	printer.params = append(printer.params, params...)
+}
+
+// isColumnName 判断 name 是否是一个列名(可以带有表的别名，如 u.name)，只能有字母、数字和下划线，
+// 没有 RecordType() 的条件中的名称直接作为列名，不能让它带入任意的 sql
+func isColumnName(name string) bool {
+	for _, part := range strings.Split(name, ".") {
+		if part == "" || unicode.IsDigit(rune(part[0])) {
+			return false
+		}
+		for _, c := range part {
+			if c != '_' && !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// isCriteriaArg 判断参数是否是 *Criteria 或内嵌了 Criteria 的类型
+func isCriteriaArg(argType reflect.Type) bool {
+	return argType != nil && argType.Implements(criteriaHolderType)
+}
+
+var criteriaHolderType = reflect.TypeOf((*criteriaHolder)(nil)).Elem()
@@ -0,0 +1,202 @@
+package gobatis
+
+import (
+	"log"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// queryUserCriteria 和生成工具生成的 XxxCriteria 一样
+type queryUserCriteria struct {
+	Criteria
+}
+
+func (c *queryUserCriteria) RecordType() reflect.Type {
+	return reflect.TypeOf(&queryUser{})
+}
+
+func newQueryUserCriteria(name string, op Operator, value interface{}) *queryUserCriteria {
+	c := &queryUserCriteria{}
+	c.Add(name, op, value)
+	return c
+}
+
+func TestCriteriaGenerateSQL(t *testing.T) {
+	mapper := CreateMapper("", nil, nil)
+	rType := reflect.TypeOf(&queryUser{})
+	names := []string{"c", "offset", "limit"}
+	argTypes := []reflect.Type{reflect.TypeOf(&queryUserCriteria{}), reflect.TypeOf(0), reflect.TypeOf(0)}
+
+	sqlStr, err := GenerateSelectSQL(DbTypePostgres, mapper, rType, names, argTypes, nil, "id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	excepted := `SELECT * FROM query_users <where><criteria value="c" /> AND deleted_at IS NULL</where> ORDER BY id <page offset="offset" limit="limit" />`
+	if sqlStr != excepted {
+		t.Error("excepted is", excepted)
+		t.Error("actual   is", sqlStr)
+	}
+
+	sqlStr, err = GenerateCountSQL(DbTypePostgres, mapper, rType, names[:1], argTypes[:1], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	excepted = `SELECT count(*) FROM query_users <where><criteria value="c" /> AND deleted_at IS NULL</where>`
+	if sqlStr != excepted {
+		t.Error("excepted is", excepted)
+		t.Error("actual   is", sqlStr)
+	}
+}
+
+func TestCriteriaElement(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	selectSQL := `SELECT * FROM query_users <where><criteria value="c" /> AND deleted_at IS NULL</where> ORDER BY id <page offset="offset" limit="limit" />`
+
+	for idx, test := range []struct {
+		dialect     Dialect
+		sql         string
+		criteria    interface{}
+		exceptedSQL string
+		params      []interface{}
+		err         string
+	}{
+		{
+			dialect: DbTypePostgres,
+			sql:     selectSQL,
+			criteria: func() interface{} {
+				c := &queryUserCriteria{}
+				c.Add("Status", In, []int{1, 2})
+				c.Or((&Criteria{}).Add("Name", Like, "a%").Add("id", Greater, 3),
+					(&Criteria{}).Add("CreatedAt", Between, []time.Time{start, end}))
+				return c
+			}(),
+			exceptedSQL: "SELECT * FROM query_users  WHERE  status IN ($1,$2) AND ((name LIKE $3 AND id > $4) OR created_at BETWEEN $5 AND $6) AND deleted_at IS NULL ORDER BY id LIMIT 10 OFFSET 20",
+			params:      []interface{}{1, 2, "a%", 3, start, end},
+		},
+		{
+			dialect: DbTypeMysql,
+			sql:     selectSQL,
+			criteria: func() interface{} {
+				c := &queryUserCriteria{}
+				c.Add("deleted_at", IsNotNull, nil)
+				c.And((&Criteria{}).Add("status", NotIn, []int{}), (&Criteria{}).Add("status", NotEqual, 0))
+				// 其中一组没有条件，这个 OR 总是成立
+				c.Or((&Criteria{}).Add("name", Equal, "abc"), &Criteria{})
+				return c
+			}(),
+			exceptedSQL: "SELECT * FROM query_users  WHERE  deleted_at IS NOT NULL AND status <> ? AND deleted_at IS NULL ORDER BY id LIMIT 10 OFFSET 20",
+			params:      []interface{}{0},
+		},
+		{
+			dialect:     DbTypeMSSql,
+			sql:         selectSQL,
+			criteria:    &queryUserCriteria{},
+			exceptedSQL: "SELECT * FROM query_users  WHERE  deleted_at IS NULL ORDER BY id OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY",
+		},
+		{
+			dialect:     DbTypePostgres,
+			sql:         selectSQL,
+			criteria:    (*queryUserCriteria)(nil),
+			exceptedSQL: "SELECT * FROM query_users  WHERE  deleted_at IS NULL ORDER BY id LIMIT 10 OFFSET 20",
+		},
+		{
+			dialect:     DbTypePostgres,
+			sql:         `SELECT * FROM users WHERE name = #{name} <criteria value="c" />`,
+			criteria:    (&Criteria{}).Add("nick_name", Like, "a%"),
+			exceptedSQL: "SELECT * FROM users WHERE name = $1  AND nick_name LIKE $2",
+			params:      []interface{}{"abc", "a%"},
+		},
+		{
+			dialect:  DbTypePostgres,
+			sql:      selectSQL,
+			criteria: newQueryUserCriteria("age", Equal, 1),
+			err:      "field 'age' is missing",
+		},
+		{
+			dialect:  DbTypePostgres,
+			sql:      selectSQL,
+			criteria: newQueryUserCriteria("CreatedAt", Between, []time.Time{start}),
+			err:      "value of the 'CreatedAt' isnot a slice with 2 elements",
+		},
+		{
+			dialect:  DbTypePostgres,
+			sql:      selectSQL,
+			criteria: (&Criteria{}).Add("1=1) OR (1", Equal, 1),
+			err:      "criteria name '1=1) OR (1' isnot a column name",
+		},
+		{
+			dialect:  DbTypePostgres,
+			sql:      selectSQL,
+			criteria: (&Criteria{}).Add("u.name; DROP TABLE users", Equal, 1),
+			err:      "criteria name 'u.name; DROP TABLE users' isnot a column name",
+		},
+		{
+			dialect:     DbTypePostgres,
+			sql:         `SELECT * FROM users u WHERE u.id > 0 <criteria value="c" />`,
+			criteria:    (&Criteria{}).Add("u.name", Equal, "a"),
+			exceptedSQL: "SELECT * FROM users u WHERE u.id > 0  AND u.name = $1",
+			params:      []interface{}{"a"},
+		},
+		{
+			dialect:  DbTypePostgres,
+			sql:      selectSQL,
+			criteria: "abc",
+			err:      "'c' isnot a gobatis.Criteria, got string",
+		},
+	} {
+		initCtx := &InitContext{Config: &Config{},
+			Logger:     log.New(os.Stdout, "[gobatis] ", log.Flags()),
+			Dialect:    test.dialect,
+			Mapper:     CreateMapper("", nil, nil),
+			Statements: make(map[string]*MappedStatement)}
+
+		stmt, err := NewMapppedStatement(initCtx, "criteria", StatementTypeSelect, ResultStruct, test.sql)
+		if err != nil {
+			t.Error(idx, err)
+			continue
+		}
+
+		ctx, err := NewContext(initCtx.Dialect, initCtx.Mapper,
+			[]string{"c", "name", "offset", "limit"}, []interface{}{test.criteria, "abc", 20, 10})
+		if err != nil {
+			t.Error(idx, err)
+			continue
+		}
+
+		sqlAndParams, err := stmt.GenerateSQLs(ctx)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Error(idx, "excepted error is", test.err)
+				t.Error(idx, "actual   error is", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Error(idx, err)
+			continue
+		}
+		if sqlAndParams[0].SQL != test.exceptedSQL {
+			t.Error(idx, "excepted is", test.exceptedSQL)
+			t.Error(idx, "actual   is", sqlAndParams[0].SQL)
+		}
+		if len(sqlAndParams[0].Params) != 0 || len(test.params) != 0 {
+			if !reflect.DeepEqual(sqlAndParams[0].Params, test.params) {
+				t.Error(idx, "excepted is", test.params)
+				t.Error(idx, "actual   is", sqlAndParams[0].Params)
+			}
+		}
+	}
+
+	initCtx := &InitContext{Config: &Config{},
+		Logger:     log.New(os.Stdout, "[gobatis] ", log.Flags()),
+		Dialect:    DbTypePostgres,
+		Mapper:     CreateMapper("", nil, nil),
+		Statements: make(map[string]*MappedStatement)}
+	_, err := NewMapppedStatement(initCtx, "criteria", StatementTypeSelect, ResultStruct, `SELECT * FROM users <where><criteria /></where>`)
+	if err == nil {
+		t.Error("excepted error got ok")
+	}
+}
@@ -0,0 +1,446 @@
+package gobatis
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+var ddlColumnTypes = map[string]struct{}{
+	"bit":         struct{}{},
+	"tinyint":     struct{}{},
+	"smallint":    struct{}{},
+	"mediumint":   struct{}{},
+	"int":         struct{}{},
+	"integer":     struct{}{},
+	"bigint":      struct{}{},
+	"char":        struct{}{},
+	"varchar":     struct{}{},
+	"nvarchar":    struct{}{},
+	"tinytext":    struct{}{},
+	"text":        struct{}{},
+	"mediumtext":  struct{}{},
+	"longtext":    struct{}{},
+	"binary":      struct{}{},
+	"varbinary":   struct{}{},
+	"date":        struct{}{},
+	"datetime":    struct{}{},
+	"time":        struct{}{},
+	"timestamp":   struct{}{},
+	"timestamptz": struct{}{},
+	"real":        struct{}{},
+	"float":       struct{}{},
+	"double":      struct{}{},
+	"decimal":     struct{}{},
+	"numeric":     struct{}{},
+	"tinyblob":    struct{}{},
+	"blob":        struct{}{},
+	"mediumblob":  struct{}{},
+	"longblob":    struct{}{},
+	"bytea":       struct{}{},
+	"bool":        struct{}{},
+	"boolean":     struct{}{},
+	"uuid":        struct{}{},
+	"serial":      struct{}{},
+	"bigserial":   struct{}{},
+}
+
+// DDLTypes 是各个数据库中的列类型，生成建表语句时按字段的类型选择
+type DDLTypes struct {
+	Boolean, Smallint, Integer, Bigint string
+	Real, Double                       string
+	Varchar, Bytes, Timestamp          string
+	IP, MAC, JSON, JSONB               string
+	// Array 为空时数组保存为 json
+	Array func(elem string) string
+}
+
+var (
+	postgresDDLTypes = &DDLTypes{
+		Boolean: "boolean", Smallint: "smallint", Integer: "integer", Bigint: "bigint",
+		Real: "real", Double: "double precision",
+		Varchar: "varchar(255)", Bytes: "bytea", Timestamp: "timestamp with time zone",
+		IP: "inet", MAC: "macaddr", JSON: "json", JSONB: "jsonb",
+		Array: func(elem string) string { return elem + "[]" },
+	}
+	mysqlDDLTypes = &DDLTypes{
+		Boolean: "boolean", Smallint: "smallint", Integer: "int", Bigint: "bigint",
+		Real: "float", Double: "double",
+		Varchar: "varchar(255)", Bytes: "blob", Timestamp: "datetime",
+		IP: "varchar(50)", MAC: "varchar(30)", JSON: "json", JSONB: "json",
+	}
+	mssqlDDLTypes = &DDLTypes{
+		Boolean: "bit", Smallint: "smallint", Integer: "int", Bigint: "bigint",
+		Real: "real", Double: "float",
+		Varchar: "nvarchar(255)", Bytes: "varbinary(max)", Timestamp: "datetimeoffset",
+		IP: "varchar(50)", MAC: "varchar(30)", JSON: "nvarchar(max)", JSONB: "nvarchar(max)",
+	}
+)
+
+// SchemaDialect 是生成建表语句、读取和比较表结构以及执行迁移时与数据库有关的部分，由 Dialect.Schema() 返回，
+// 嵌入了已有 Dialect 的 Dialect 也会使用被嵌入的 Dialect 的 SchemaDialect，
+// 其它数据库的 Dialect 可以自已创建一个 SchemaDialect，没有设置的部分按标准的写法生成
+type SchemaDialect struct {
+	// Types 为 nil 时不能生成建表语句
+	Types *DDLTypes
+	// Queries 为 nil 时不能从数据库中读取表结构
+	Queries *SchemaQueries
+	// TypeAliases 是列类型的别名，比较列类型前先转成统一的写法
+	TypeAliases map[string]string
+	// IgnoreIntWidth 为 true 时比较列类型时忽略整数类型的显示宽度，如 int(11)
+	IgnoreIntWidth bool
+	// Unsigned 为 true 时无符号整数的列类型加上 unsigned
+	Unsigned bool
+	// Serial 为 true 时自增列用 serial 或 bigserial 类型，否则在列类型后加上 Autoincr
+	Serial   bool
+	Autoincr string
+	// AddColumn 是添加列的语句中的关键字，为空时是 ADD COLUMN
+	AddColumn string
+	// AlterColumn 为 nil 时分别用 ALTER COLUMN TYPE 和 SET/DROP NOT NULL 修改列，definition 是新的列定义
+	AlterColumn func(table, column string, from, to ColumnSchema, definition string) []string
+	// DropIndex 为 nil 时约束用 DROP CONSTRAINT 删除，索引用 DROP INDEX name 删除
+	DropIndex func(table, name string, constraint bool) string
+	// CreateTableIfNotExists 为 nil 时用 CREATE TABLE IF NOT EXISTS
+	CreateTableIfNotExists func(table, definition string) string
+	// TransactionalDDL 为 true 时表示可以在事务中执行 DDL 语句
+	TransactionalDDL bool
+}
+
+// genericSchema 用于不支持的数据库，语句按标准的写法生成
+var genericSchema = &SchemaDialect{}
+
+func schemaOf(dbType Dialect) *SchemaDialect {
+	if schema := dbType.Schema(); schema != nil {
+		return schema
+	}
+	return genericSchema
+}
+
+func (schema *SchemaDialect) alterColumnSQL(table, column string, from, to ColumnSchema, definition string) []string {
+	if schema.AlterColumn != nil {
+		return schema.AlterColumn(table, column, from, to, definition)
+	}
+
+	var sqlList []string
+	if schema.normalizeColumnType(from.Type) != schema.normalizeColumnType(to.Type) {
+		sqlList = append(sqlList, "ALTER TABLE "+table+" ALTER COLUMN "+column+" TYPE "+to.Type)
+	}
+	if from.NotNull != to.NotNull {
+		if to.NotNull {
+			sqlList = append(sqlList, "ALTER TABLE "+table+" ALTER COLUMN "+column+" SET NOT NULL")
+		} else {
+			sqlList = append(sqlList, "ALTER TABLE "+table+" ALTER COLUMN "+column+" DROP NOT NULL")
+		}
+	}
+	return sqlList
+}
+
+func (schema *SchemaDialect) dropIndexSQL(table, name string, constraint bool) string {
+	if schema.DropIndex != nil {
+		return schema.DropIndex(table, name, constraint)
+	}
+	if constraint {
+		return "ALTER TABLE " + table + " DROP CONSTRAINT " + name
+	}
+	return "DROP INDEX " + name
+}
+
+func (schema *SchemaDialect) createTableIfNotExistsSQL(table, definition string) string {
+	if schema.CreateTableIfNotExists != nil {
+		return schema.CreateTableIfNotExists(table, definition)
+	}
+	return "CREATE TABLE IF NOT EXISTS " + table + " " + definition
+}
+
+// tagColumnType 返回 tag 中指定的列类型，如 `db:"name,varchar(64)"`
+func tagColumnType(field *FieldInfo) string {
+	var columnType string
+	for opt := range field.Options {
+		name := opt
+		if idx := strings.IndexByte(name, '('); idx >= 0 {
+			name = name[:idx]
+		}
+		if _, ok := ddlColumnTypes[strings.ToLower(name)]; ok {
+			if columnType == "" || opt < columnType {
+				columnType = opt
+			}
+		}
+	}
+	return columnType
+}
+
+// tagOptionArg 返回 tag 中的 name=value 或 name(value) 形式的选项的值
+func tagOptionArg(field *FieldInfo, name string) (string, bool) {
+	if value, ok := field.Options[name]; ok {
+		return value, true
+	}
+	for opt := range field.Options {
+		if strings.HasPrefix(opt, name+"(") && strings.HasSuffix(opt, ")") {
+			return opt[len(name)+1 : len(opt)-1], true
+		}
+	}
+	return "", false
+}
+
+func ddlColumnType(schema *SchemaDialect, mapper *Mapper, field *FieldInfo) (string, error) {
+	if columnType := tagColumnType(field); columnType != "" {
+		return columnType, nil
+	}
+
+	types := schema.Types
+	// 不支持 jsonb 的数据库中 jsonb 和 json 是一样的
+	if _, ok := field.Options["jsonb"]; ok {
+		return types.JSONB, nil
+	}
+	if _, ok := field.Options["json"]; ok {
+		return types.JSON, nil
+	}
+
+	typ := field.Field.Type
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	switch typ {
+	case _timeType:
+		return types.Timestamp, nil
+	case _ipType:
+		return types.IP, nil
+	case _macType:
+		return types.MAC, nil
+	case _bytesType:
+		return types.Bytes, nil
+	}
+
+	switch typ.PkgPath() + "." + typ.Name() {
+	case "database/sql.NullString":
+		return types.Varchar, nil
+	case "database/sql.NullInt64":
+		return types.Bigint, nil
+	case "database/sql.NullInt32":
+		return types.Integer, nil
+	case "database/sql.NullFloat64":
+		return types.Double, nil
+	case "database/sql.NullBool":
+		return types.Boolean, nil
+	case "database/sql.NullTime", "github.com/lib/pq.NullTime":
+		return types.Timestamp, nil
+	}
+
+	if reflect.PtrTo(typ).Implements(_valuerInterface) ||
+		reflect.PtrTo(typ).Implements(_scannerInterface) ||
+		mapper.hasTypeHandler(typ) {
+		return "", errors.New("column type of field '" + field.Field.Name + "' is unknown, please set it in the tag")
+	}
+
+	if columnType := ddlBasicType(types, typ); columnType != "" {
+		if schema.Unsigned && typ.Kind() >= reflect.Uint && typ.Kind() <= reflect.Uint64 {
+			return columnType + " unsigned", nil
+		}
+		return columnType, nil
+	}
+
+	switch typ.Kind() {
+	case reflect.Slice, reflect.Array:
+		if types.Array != nil {
+			if elemType := ddlBasicType(types, typ.Elem()); elemType != "" {
+				return types.Array(elemType), nil
+			}
+		}
+		return types.JSONB, nil
+	case reflect.Struct, reflect.Map, reflect.Interface:
+		return types.JSONB, nil
+	}
+	return "", errors.New("column type of field '" + field.Field.Name + "' is unknown, please set it in the tag")
+}
+
+func ddlBasicType(types *DDLTypes, typ reflect.Type) string {
+	switch typ.Kind() {
+	case reflect.Bool:
+		return types.Boolean
+	case reflect.Int8, reflect.Int16, reflect.Uint8:
+		return types.Smallint
+	case reflect.Int32, reflect.Uint16:
+		return types.Integer
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return types.Bigint
+	case reflect.Float32:
+		return types.Real
+	case reflect.Float64:
+		return types.Double
+	case reflect.String:
+		return types.Varchar
+	}
+	return ""
+}
+
+// GenerateCreateTableSQL 根据结构的字段和 tag 生成建表语句和建索引的语句，目前支持 postgres, mysql 和 mssql。
+//
+// tag 中可以用 pk、autoincr、notnull、unique、unique(name)、index、index(name)、default=value 和列类型(如 varchar(64))，
+// 同名的 unique(name) 和 index(name) 会合成一个多列的索引
+func GenerateCreateTableSQL(dbType Dialect, mapper *Mapper, rType reflect.Type) ([]string, error) {
+	schema, err := ReadStructSchema(dbType, mapper, rType)
+	if err != nil {
+		return nil, err
+	}
+	return createTableSQL(dbType, schema), nil
+}
+
+// ReadStructSchema 按 GenerateCreateTableSQL 的规则读取结构对应的表结构
+func ReadStructSchema(dbType Dialect, mapper *Mapper, rType reflect.Type) (*TableSchema, error) {
+	ddl := dbType.Schema()
+	if ddl == nil || ddl.Types == nil {
+		return nil, errors.New("generate ddl for '" + dbType.Name() + "' is unsupported")
+	}
+
+	tableName, err := ReadTableName(mapper, rType)
+	if err != nil {
+		return nil, err
+	}
+
+	schema := &TableSchema{Name: tableName, quoteMode: mapper.quoteMode}
+	addIndex := func(name string, unique bool, column string) {
+		for idx := range schema.Indexes {
+			if schema.Indexes[idx].Name == name && schema.Indexes[idx].Unique == unique {
+				schema.Indexes[idx].Columns = append(schema.Indexes[idx].Columns, column)
+				return
+			}
+		}
+		schema.Indexes = append(schema.Indexes, IndexSchema{Name: name, Unique: unique, Columns: []string{column}})
+	}
+
+	// 按字段在结构中定义的顺序，匿名字段中的字段在匿名字段的位置
+	fields := append([]*FieldInfo(nil), mapper.TypeMap(rType).Index...)
+	sort.SliceStable(fields, func(i, j int) bool {
+		a, b := fields[i].Index, fields[j].Index
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+
+	for _, field := range fields {
+		if field.Field.Name == "TableName" || field.Field.Anonymous {
+			continue
+		}
+		if field.Parent != nil && len(field.Parent.Index) != 0 && !field.Parent.Field.Anonymous {
+			continue
+		}
+		if _, ok := field.Options["-"]; ok {
+			continue
+		}
+
+		columnType, err := ddlColumnType(ddl, mapper, field)
+		if err != nil {
+			return nil, err
+		}
+
+		column := ColumnSchema{Name: field.Name, Type: columnType}
+		_, isPK := field.Options["pk"]
+		_, column.Autoincr = field.Options["autoincr"]
+		_, column.NotNull = field.Options["notnull"]
+		column.NotNull = column.NotNull || isPK
+		column.Default, _ = tagOptionArg(field, "default")
+		if name, ok := field.Options["unique"]; ok && name == "" {
+			column.Unique = true
+		}
+		schema.Columns = append(schema.Columns, column)
+
+		if isPK {
+			schema.PrimaryKey = append(schema.PrimaryKey, field.Name)
+		}
+		if name, ok := tagOptionArg(field, "unique"); ok && name != "" {
+			addIndex(name, true, field.Name)
+		}
+		if name, ok := tagOptionArg(field, "index"); ok {
+			if name == "" {
+				name = "idx_" + tableName + "_" + field.Name
+			}
+			addIndex(name, false, field.Name)
+		}
+	}
+	if len(schema.Columns) == 0 {
+		return nil, errors.New("struct '" + rType.Name() + "' hasnot any column")
+	}
+	sort.SliceStable(schema.Indexes, func(i, j int) bool {
+		return schema.Indexes[i].Name < schema.Indexes[j].Name
+	})
+	return schema, nil
+}
+
+func columnDefinition(dbType Dialect, mode QuoteMode, column ColumnSchema) string {
+	schema := schemaOf(dbType)
+	columnType := column.Type
+	if column.Autoincr && schema.Serial {
+		switch columnType {
+		case "integer", "int":
+			columnType = "serial"
+		case "bigint":
+			columnType = "bigserial"
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(QuoteIdentifier(dbType, mode, column.Name))
+	sb.WriteString(" ")
+	sb.WriteString(columnType)
+	if column.Autoincr && schema.Autoincr != "" {
+		sb.WriteString(" ")
+		sb.WriteString(schema.Autoincr)
+	}
+	if column.NotNull {
+		sb.WriteString(" NOT NULL")
+	}
+	if column.Default != "" {
+		sb.WriteString(" DEFAULT ")
+		sb.WriteString(column.Default)
+	}
+	if column.Unique {
+		sb.WriteString(" UNIQUE")
+	}
+	return sb.String()
+}
+
+// quoteNames 按 mode 给一组列名加上引号后用逗号连接起来
+func quoteNames(dbType Dialect, mode QuoteMode, names []string) string {
+	quoted := make([]string, len(names))
+	for idx, name := range names {
+		quoted[idx] = QuoteIdentifier(dbType, mode, name)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func createIndexSQL(dbType Dialect, mode QuoteMode, table string, index IndexSchema) string {
+	sqlStr := "INDEX " + QuoteIdentifier(dbType, mode, index.Name) +
+		" ON " + QuoteIdentifier(dbType, mode, table) + "(" + quoteNames(dbType, mode, index.Columns) + ")"
+	if index.Unique {
+		return "CREATE UNIQUE " + sqlStr
+	}
+	return "CREATE " + sqlStr
+}
+
+func createTableSQL(dbType Dialect, schema *TableSchema) []string {
+	columns := make([]string, 0, len(schema.Columns)+1)
+	for _, column := range schema.Columns {
+		columns = append(columns, columnDefinition(dbType, schema.quoteMode, column))
+	}
+	if len(schema.PrimaryKey) > 0 {
+		columns = append(columns, "PRIMARY KEY("+quoteNames(dbType, schema.quoteMode, schema.PrimaryKey)+")")
+	}
+
+	var sb strings.Builder
+	sb.WriteString("CREATE TABLE ")
+	sb.WriteString(QuoteIdentifier(dbType, schema.quoteMode, schema.Name))
+	sb.WriteString(" (\r\n  ")
+	sb.WriteString(strings.Join(columns, ",\r\n  "))
+	sb.WriteString("\r\n)")
+
+	sqlList := []string{sb.String()}
+	for _, index := range schema.Indexes {
+		sqlList = append(sqlList, createIndexSQL(dbType, schema.quoteMode, schema.Name, index))
+	}
+	return sqlList
+}
@@ -0,0 +1,249 @@
+package gobatis_test
+
+import (
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	gobatis "github.com/runner-mei/GoBatis"
+)
+
+type DDLAddress struct {
+	City string `json:"city"`
+}
+
+type DDLBase struct {
+	ID int64 `db:"id,pk,autoincr"`
+}
+
+type DDLUser struct {
+	TableName gobatis.TableName `db:"ddl_users"`
+	DDLBase
+	Name      string                 `db:"name,notnull,unique"`
+	Nickname  string                 `db:"nickname,varchar(64)"`
+	Age       uint8                  `db:"age,default=0"`
+	Score     float64                `db:"score"`
+	Enabled   bool                   `db:"enabled"`
+	Avatar    []byte                 `db:"avatar"`
+	IP        net.IP                 `db:"ip"`
+	Tags      []string               `db:"tags"`
+	Address   *DDLAddress            `db:"address"`
+	Attrs     map[string]interface{} `db:"attrs,json"`
+	GroupID   int32                  `db:"group_id,index(group_status)"`
+	Status    int16                  `db:"status,index(group_status)"`
+	Email     string                 `db:"email,unique(email_phone)"`
+	Phone     string                 `db:"phone,unique(email_phone),index"`
+	CreatedAt time.Time              `db:"created_at"`
+	DeletedAt *time.Time             `db:"deleted_at,deleted"`
+	Ignore    string                 `db:"-"`
+}
+
+type DDLScanner struct{}
+
+func (s *DDLScanner) Scan(src interface{}) error { return nil }
+
+func TestGenerateCreateTableSQL(t *testing.T) {
+	mapper := gobatis.CreateMapper("", nil, nil)
+
+	for _, test := range []struct {
+		dialect  gobatis.Dialect
+		excepted []string
+	}{
+		{dialect: gobatis.DbTypePostgres, excepted: []string{`CREATE TABLE ddl_users (
+  id bigserial NOT NULL,
+  name varchar(255) NOT NULL UNIQUE,
+  nickname varchar(64),
+  age smallint DEFAULT 0,
+  score double precision,
+  enabled boolean,
+  avatar bytea,
+  ip inet,
+  tags varchar(255)[],
+  address jsonb,
+  attrs json,
+  group_id integer,
+  status smallint,
+  email varchar(255),
+  phone varchar(255),
+  created_at timestamp with time zone,
+  deleted_at timestamp with time zone,
+  PRIMARY KEY(id)
+)`,
+			"CREATE UNIQUE INDEX email_phone ON ddl_users(email, phone)",
+			"CREATE INDEX group_status ON ddl_users(group_id, status)",
+			"CREATE INDEX idx_ddl_users_phone ON ddl_users(phone)",
+		}},
+		{dialect: gobatis.DbTypeMysql, excepted: []string{`CREATE TABLE ddl_users (
+  id bigint AUTO_INCREMENT NOT NULL,
+  name varchar(255) NOT NULL UNIQUE,
+  nickname varchar(64),
+  age smallint unsigned DEFAULT 0,
+  score double,
+  enabled boolean,
+  avatar blob,
+  ip varchar(50),
+  tags json,
+  address json,
+  attrs json,
+  group_id int,
+  status smallint,
+  email varchar(255),
+  phone varchar(255),
+  created_at datetime,
+  deleted_at datetime,
+  PRIMARY KEY(id)
+)`,
+			"CREATE UNIQUE INDEX email_phone ON ddl_users(email, phone)",
+			"CREATE INDEX group_status ON ddl_users(group_id, status)",
+			"CREATE INDEX idx_ddl_users_phone ON ddl_users(phone)",
+		}},
+		{dialect: gobatis.DbTypeMSSql, excepted: []string{`CREATE TABLE ddl_users (
+  id bigint IDENTITY(1,1) NOT NULL,
+  name nvarchar(255) NOT NULL UNIQUE,
+  nickname varchar(64),
+  age smallint DEFAULT 0,
+  score float,
+  enabled bit,
+  avatar varbinary(max),
+  ip varchar(50),
+  tags nvarchar(max),
+  address nvarchar(max),
+  attrs nvarchar(max),
+  group_id int,
+  status smallint,
+  email nvarchar(255),
+  phone nvarchar(255),
+  created_at datetimeoffset,
+  deleted_at datetimeoffset,
+  PRIMARY KEY(id)
+)`,
+			"CREATE UNIQUE INDEX email_phone ON ddl_users(email, phone)",
+			"CREATE INDEX group_status ON ddl_users(group_id, status)",
+			"CREATE INDEX idx_ddl_users_phone ON ddl_users(phone)",
+		}},
+	} {
+		sqlList, err := gobatis.GenerateCreateTableSQL(test.dialect, mapper, reflect.TypeOf(&DDLUser{}))
+		if err != nil {
+			t.Error(test.dialect.Name(), err)
+			continue
+		}
+		for idx := range sqlList {
+			sqlList[idx] = strings.Replace(sqlList[idx], "\r\n", "\n", -1)
+		}
+		if !reflect.DeepEqual(sqlList, test.excepted) {
+			t.Error(test.dialect.Name())
+			t.Error("excepted is", strings.Join(test.excepted, ";\n"))
+			t.Error("actual   is", strings.Join(sqlList, ";\n"))
+		}
+	}
+
+	_, err := gobatis.GenerateCreateTableSQL(gobatis.DbTypeOracle, mapper, reflect.TypeOf(&DDLUser{}))
+	if err == nil || err.Error() != "generate ddl for 'oracle' is unsupported" {
+		t.Error(err)
+	}
+
+	type ddlBad struct {
+		TableName gobatis.TableName `db:"ddl_bad"`
+		Value     DDLScanner        `db:"value"`
+	}
+	_, err = gobatis.GenerateCreateTableSQL(gobatis.DbTypePostgres, mapper, reflect.TypeOf(&ddlBad{}))
+	if err == nil || err.Error() != "column type of field 'Value' is unknown, please set it in the tag" {
+		t.Error(err)
+	}
+}
+
+type DDLOrder struct {
+	TableName gobatis.TableName `db:"order"`
+	ID        int64             `db:"id,pk"`
+	User      string            `db:"user,index"`
+}
+
+func TestGenerateCreateTableSQLWithReservedWords(t *testing.T) {
+	mapper := gobatis.CreateMapper("", nil, nil)
+
+	for _, test := range []struct {
+		dialect  gobatis.Dialect
+		excepted []string
+	}{
+		{dialect: gobatis.DbTypePostgres, excepted: []string{"CREATE TABLE \"order\" (\n  id bigint NOT NULL,\n  \"user\" varchar(255),\n  PRIMARY KEY(id)\n)",
+			"CREATE INDEX idx_order_user ON \"order\"(\"user\")",
+		}},
+		{dialect: gobatis.DbTypeMysql, excepted: []string{"CREATE TABLE `order` (\n  id bigint NOT NULL,\n  `user` varchar(255),\n  PRIMARY KEY(id)\n)",
+			"CREATE INDEX idx_order_user ON `order`(`user`)",
+		}},
+	} {
+		sqlList, err := gobatis.GenerateCreateTableSQL(test.dialect, mapper, reflect.TypeOf(&DDLOrder{}))
+		if err != nil {
+			t.Error(test.dialect.Name(), err)
+			continue
+		}
+		for idx := range sqlList {
+			sqlList[idx] = strings.Replace(sqlList[idx], "\r\n", "\n", -1)
+		}
+		if !reflect.DeepEqual(sqlList, test.excepted) {
+			t.Error(test.dialect.Name())
+			t.Error("excepted is", strings.Join(test.excepted, ";\n"))
+			t.Error("actual   is", strings.Join(sqlList, ";\n"))
+		}
+	}
+}
+
+type DDLEvent struct {
+	TableName  gobatis.TableName `db:"ddl_events"`
+	ID         int64             `db:"id,pk"`
+	HappenedAt time.Time         `db:"happened_at,timestamptz"`
+}
+
+func TestGenerateCreateTableSQLWithColumnType(t *testing.T) {
+	mapper := gobatis.CreateMapper("", nil, nil)
+
+	sqlList, err := gobatis.GenerateCreateTableSQL(gobatis.DbTypePostgres, mapper, reflect.TypeOf(&DDLEvent{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	excepted := "CREATE TABLE ddl_events (\n  id bigint NOT NULL,\n  happened_at timestamptz,\n  PRIMARY KEY(id)\n)"
+	if len(sqlList) != 1 || strings.Replace(sqlList[0], "\r\n", "\n", -1) != excepted {
+		t.Error("excepted is", excepted)
+		t.Error("actual   is", sqlList)
+	}
+}
+
+// sqliteDialect 是一个自已提供 SchemaDialect 的方言
+type sqliteDialect struct{ gobatis.Dialect }
+
+func (sqliteDialect) Name() string { return "sqlite" }
+
+func (sqliteDialect) Schema() *gobatis.SchemaDialect {
+	return &gobatis.SchemaDialect{
+		Types: &gobatis.DDLTypes{
+			Boolean: "integer", Smallint: "integer", Integer: "integer", Bigint: "integer",
+			Real: "real", Double: "real",
+			Varchar: "text", Bytes: "blob", Timestamp: "datetime",
+			IP: "text", MAC: "text", JSON: "text", JSONB: "text",
+		},
+		Autoincr: "AUTOINCREMENT",
+	}
+}
+
+type DDLNote struct {
+	TableName gobatis.TableName `db:"ddl_notes"`
+	ID        int64             `db:"id,pk,autoincr"`
+	Body      string            `db:"body,notnull"`
+	CreatedAt time.Time         `db:"created_at"`
+}
+
+func TestGenerateCreateTableSQLWithCustomSchema(t *testing.T) {
+	mapper := gobatis.CreateMapper("", nil, nil)
+
+	sqlList, err := gobatis.GenerateCreateTableSQL(sqliteDialect{gobatis.DbTypeNone}, mapper, reflect.TypeOf(&DDLNote{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	excepted := "CREATE TABLE ddl_notes (\n  id integer AUTOINCREMENT NOT NULL,\n  body text NOT NULL,\n  created_at datetime,\n  PRIMARY KEY(id)\n)"
+	if len(sqlList) != 1 || strings.Replace(sqlList[0], "\r\n", "\n", -1) != excepted {
+		t.Error("excepted is", excepted)
+		t.Error("actual   is", sqlList)
+	}
+}
@@ -4,27 +4,87 @@ import (
 	"encoding/json"
 	"errors"
 	"strings"
+	"sync"
 
 	"github.com/lib/pq"
 )
 
+// ReturningStrategy 是生成的 insert 语句返回自增列的方式
+type ReturningStrategy int
+
+const (
+	ReturningLastInsertID ReturningStrategy = iota // 用 sql.Result.LastInsertId() 得到
+	ReturningClause                                // 在语句末尾加上 RETURNING id
+	ReturningOutput                                // 在 VALUES 之前加上 OUTPUT inserted.id
+	ReturningInto                                  // 在语句末尾加上 RETURNING id INTO :out，执行时用 sql.Out 得到
+)
+
+// ReturningIntoParam 是 ReturningInto 方式下接收返回值的参数名，自已写 insert 语句时也可以用它，如
+//
+//	INSERT INTO users(id, name) VALUES(users_seq.NEXTVAL, #{name}) RETURNING id INTO :out
+const ReturningIntoParam = ":out"
+
 type Dialect interface {
 	Name() string
 	Placeholder() PlaceholderFormat
 	InsertIDSupported() bool
+	// RowValueSupported 返回是否支持行值，如 (a, b) IN ((1, 2), (3, 4))
+	RowValueSupported() bool
 	HandleError(error) error
 	MakeArrayValuer(interface{}) (interface{}, error)
 	MakeArrayScanner(string, interface{}) (interface{}, error)
+	// Paginate 返回在 sqlStr 后面加上分页后的语句，offset 或 limit 小于等于 0 时表示没有这一项
+	Paginate(sqlStr string, offset, limit int64) string
+	// Quote 返回加上引号后的标识符(表名或列名)
+	Quote(name string) string
+	// CurrentTimestamp 返回当前时间的表达式，如 now() 或 CURRENT_TIMESTAMP
+	CurrentTimestamp() string
+	// Returning 返回生成的 insert 语句返回自增列的方式
+	Returning() ReturningStrategy
+	// NextSequenceValue 返回取序列下一个值的表达式，用于有 sequence 标记的列
+	NextSequenceValue(sequence string) string
+	// Exists 返回判断子查询 query 是否有记录的语句，它的结果是一个布尔值或 0/1
+	Exists(query string) string
+	// Upsert 返回记录不存在时插入，存在时更新的语句
+	Upsert(stmt *UpsertStatement) string
+	// Schema 返回生成建表语句、读取表结构和执行迁移时与数据库有关的部分，不支持时返回 nil
+	Schema() *SchemaDialect
+}
+
+// UpsertStatement 是 Dialect.Upsert 的参数
+type UpsertStatement struct {
+	Table string
+	// Columns 是插入的列，Values 是与它们对应的值，如 #{name} 或 now()
+	Columns []string
+	Values  []string
+	// Keys 是判断记录是否存在的列，它们必须有唯一索引
+	Keys []string
+	// Tenant 是租户列，它也在 Keys 中，不能按 Keys 判断记录是否存在的数据库(如 mysql)用它避免更新其它租户的记录
+	Tenant string
+	// Updates 是记录存在时更新的列
+	Updates []string
+	// Returning 是要返回的自增列，为空时不返回
+	Returning string
 }
 
 type dialect struct {
 	name            string
 	placeholder     PlaceholderFormat
 	hasLastInsertID bool
+	noRowValue      bool
 	handleError     func(e error) error
 
 	makeArrayValuer  func(interface{}) (interface{}, error)
 	makeArrayScanner func(string, interface{}) (interface{}, error)
+	paginate         func(sqlStr string, offset, limit int64) string
+
+	quote            func(name string) string
+	currentTimestamp string
+	returning        ReturningStrategy
+	nextSequence     func(sequence string) string
+	exists           func(query string) string
+	upsert           func(stmt *UpsertStatement) string
+	schema           *SchemaDialect
 }
 
 func (d *dialect) Name() string {
@@ -39,6 +99,10 @@ func (d *dialect) InsertIDSupported() bool {
 	return d.hasLastInsertID
 }
 
+func (d *dialect) RowValueSupported() bool {
+	return !d.noRowValue
+}
+
 func (d *dialect) HandleError(e error) error {
 	if d.handleError == nil {
 		return e
@@ -54,6 +118,56 @@ func (d *dialect) MakeArrayScanner(name string, v interface{}) (interface{}, err
 	return d.makeArrayScanner(name, v)
 }
 
+func (d *dialect) Paginate(sqlStr string, offset, limit int64) string {
+	if d.paginate == nil {
+		return paginateLimitOffset(sqlStr, offset, limit)
+	}
+	return d.paginate(sqlStr, offset, limit)
+}
+
+func (d *dialect) Quote(name string) string {
+	if d.quote == nil {
+		return quoteDouble(name)
+	}
+	return d.quote(name)
+}
+
+func (d *dialect) CurrentTimestamp() string {
+	if d.currentTimestamp == "" {
+		return "CURRENT_TIMESTAMP"
+	}
+	return d.currentTimestamp
+}
+
+func (d *dialect) Returning() ReturningStrategy {
+	return d.returning
+}
+
+func (d *dialect) NextSequenceValue(sequence string) string {
+	if d.nextSequence == nil {
+		return "NEXT VALUE FOR " + sequence
+	}
+	return d.nextSequence(sequence)
+}
+
+func (d *dialect) Exists(query string) string {
+	if d.exists == nil {
+		return "SELECT EXISTS(" + query + ")"
+	}
+	return d.exists(query)
+}
+
+func (d *dialect) Upsert(stmt *UpsertStatement) string {
+	if d.upsert == nil {
+		return upsertOnConflict(stmt)
+	}
+	return d.upsert(stmt)
+}
+
+func (d *dialect) Schema() *SchemaDialect {
+	return d.schema
+}
+
 var (
 	makeArrayValuer = func(v interface{}) (interface{}, error) {
 		bs, err := json.Marshal(v)
@@ -83,23 +197,94 @@ var (
 	}
 
 	DbTypeNone     Dialect = &dialect{name: "unknown", placeholder: Question, hasLastInsertID: true, makeArrayValuer: makeArrayValuer, makeArrayScanner: makeArrayScanner}
-	DbTypePostgres Dialect = &dialect{name: "postgres", placeholder: Dollar, hasLastInsertID: false, makeArrayValuer: makePQArrayValuer, makeArrayScanner: makePQArrayScanner, handleError: handlePQError}
-	DbTypeMysql    Dialect = &dialect{name: "mysql", placeholder: Question, hasLastInsertID: true, makeArrayValuer: makeArrayValuer, makeArrayScanner: makeArrayScanner}
-	DbTypeMSSql    Dialect = &dialect{name: "mssql", placeholder: Question, hasLastInsertID: false, makeArrayValuer: makeArrayValuer, makeArrayScanner: makeArrayScanner}
-	DbTypeOracle   Dialect = &dialect{name: "oracle", placeholder: Question, hasLastInsertID: true, makeArrayValuer: makeArrayValuer, makeArrayScanner: makeArrayScanner}
+	DbTypePostgres Dialect = &dialect{name: "postgres", placeholder: Dollar, hasLastInsertID: false, makeArrayValuer: makePQArrayValuer, makeArrayScanner: makePQArrayScanner, handleError: handlePQError,
+		currentTimestamp: "now()", returning: ReturningClause, nextSequence: nextvalSequence, schema: postgresSchema}
+	DbTypeMysql Dialect = &dialect{name: "mysql", placeholder: Question, hasLastInsertID: true, makeArrayValuer: makeArrayValuer, makeArrayScanner: makeArrayScanner, paginate: paginateMysql,
+		quote: quoteBacktick, upsert: upsertOnDuplicateKey, schema: mysqlSchema}
+	DbTypeMSSql Dialect = &dialect{name: "mssql", placeholder: Question, hasLastInsertID: false, makeArrayValuer: makeArrayValuer, makeArrayScanner: makeArrayScanner, paginate: paginateMSSql,
+		quote: quoteBracket, returning: ReturningOutput, upsert: upsertMerge, noRowValue: true, exists: existsCaseWhen, schema: mssqlSchema}
+	DbTypeOracle Dialect = &dialect{name: "oracle", placeholder: Colon, hasLastInsertID: false, makeArrayValuer: makeArrayValuer, makeArrayScanner: makeArrayScanner, paginate: paginateOffsetFetch,
+		handleError: handleOracleError, returning: ReturningInto, nextSequence: nextvalDot, upsert: upsertMergeDual, exists: existsCaseWhenDual}
+)
+
+var (
+	dialectLock sync.RWMutex
+	dialects    = map[string]Dialect{}
 )
 
+func init() {
+	RegisterDialect("postgres", nil, DbTypePostgres)
+	RegisterDialect("mysql", nil, DbTypeMysql)
+	RegisterDialect("mssql", []string{"sqlserver"}, DbTypeMSSql)
+	RegisterDialect("oracle", []string{"ora"}, DbTypeOracle)
+}
+
+// RegisterDialect 注册一个数据库的 Dialect，name 和 aliases 是它的名称(一般是驱动名，不区分大小写)，
+// 已经注册过的名称会被替换。
+//
+// 新的数据库与已有的数据库兼容时可以嵌入已有的 Dialect 再修改不同的地方，如
+//
+//	type cockroachDialect struct{ gobatis.Dialect }
+//	func (cockroachDialect) Name() string { return "cockroach" }
+//
+//	gobatis.RegisterDialect("cockroach", nil, cockroachDialect{gobatis.DbTypePostgres})
+func RegisterDialect(name string, aliases []string, d Dialect) {
+	if d == nil {
+		panic("gobatis: dialect '" + name + "' is nil")
+	}
+	if name == "" {
+		panic("gobatis: dialect name is empty")
+	}
+
+	dialectLock.Lock()
+	defer dialectLock.Unlock()
+	dialects[strings.ToLower(name)] = d
+	for _, alias := range aliases {
+		dialects[strings.ToLower(alias)] = d
+	}
+}
+
+// LookupDialect 返回已注册的 Dialect
+func LookupDialect(name string) (Dialect, bool) {
+	dialectLock.RLock()
+	defer dialectLock.RUnlock()
+	d, ok := dialects[strings.ToLower(name)]
+	return d, ok
+}
+
+// ToDbType 返回已注册的 Dialect，没有注册时返回 DbTypeNone，需要知道是否注册过时请用 LookupDialect
 func ToDbType(driverName string) Dialect {
-	switch strings.ToLower(driverName) {
-	case "postgres":
-		return DbTypePostgres
-	case "mysql":
-		return DbTypeMysql
-	case "mssql", "sqlserver":
-		return DbTypeMSSql
-	case "oracle", "ora":
-		return DbTypeOracle
-	default:
-		return DbTypeNone
+	if d, ok := LookupDialect(driverName); ok {
+		return d
 	}
+	return DbTypeNone
+}
+
+func quoteDouble(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+func quoteBacktick(name string) string {
+	return "`" + strings.Replace(name, "`", "``", -1) + "`"
+}
+
+func quoteBracket(name string) string {
+	return "[" + strings.Replace(name, "]", "]]", -1) + "]"
+}
+
+func nextvalSequence(sequence string) string {
+	return "nextval('" + sequence + "')"
+}
+
+func nextvalDot(sequence string) string {
+	return sequence + ".NEXTVAL"
+}
+
+// existsCaseWhen 用于不能直接 SELECT EXISTS(...) 的数据库
+func existsCaseWhen(query string) string {
+	return "SELECT CASE WHEN EXISTS(" + query + ") THEN 1 ELSE 0 END"
+}
+
+func existsCaseWhenDual(query string) string {
+	return existsCaseWhen(query) + " FROM DUAL"
 }
@@ -0,0 +1,285 @@
+package gobatis
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type kingbaseDialect struct {
+	Dialect
+}
+
+func (kingbaseDialect) Name() string {
+	return "kingbase"
+}
+
+func (kingbaseDialect) CurrentTimestamp() string {
+	return "sysdate"
+}
+
+func TestRegisterDialect(t *testing.T) {
+	for _, name := range []string{"postgres", "MySQL", "sqlserver", "ora"} {
+		if _, ok := LookupDialect(name); !ok {
+			t.Error(name, "isnot registered")
+		}
+	}
+	if ToDbType("sqlserver") != DbTypeMSSql {
+		t.Error("sqlserver isnot mssql")
+	}
+	if ToDbType("kingbase") != DbTypeNone {
+		t.Error("kingbase is registered")
+	}
+
+	_, err := newConnection(&Config{DriverName: "kingbase", DB: &fakeRunner{name: "db"}})
+	if err == nil || !strings.Contains(err.Error(), "dialect of the driver 'kingbase' isnot registered") {
+		t.Error(err)
+	}
+
+	kingbase := kingbaseDialect{DbTypePostgres}
+	RegisterDialect("kingbase", []string{"kingbase8"}, kingbase)
+	defer func() {
+		dialectLock.Lock()
+		delete(dialects, "kingbase")
+		delete(dialects, "kingbase8")
+		dialectLock.Unlock()
+	}()
+
+	if d, ok := LookupDialect("KingBase8"); !ok || d.Name() != "kingbase" {
+		t.Error(d, ok)
+	}
+
+	conn, err := newConnection(&Config{DriverName: "kingbase", DB: &fakeRunner{name: "db"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conn.Dialect() != Dialect(kingbase) {
+		t.Error(conn.Dialect().Name())
+	}
+
+	conn, err = newConnection(&Config{DriverName: "unknown", Dialect: DbTypeMysql, DB: &fakeRunner{name: "db"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conn.Dialect() != DbTypeMysql {
+		t.Error(conn.Dialect().Name())
+	}
+
+	sqlStr, err := GenerateInsertSQL(kingbase, conn.Mapper(), reflect.TypeOf(&dialectUser{}), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	excepted := "INSERT INTO dialect_users(name, email, created_at, updated_at) VALUES(#{name}, #{email}, sysdate, sysdate) RETURNING id"
+	if sqlStr != excepted {
+		t.Error("excepted is", excepted)
+		t.Error("actual   is", sqlStr)
+	}
+}
+
+func TestQuote(t *testing.T) {
+	for _, test := range []struct {
+		dialect  Dialect
+		excepted string
+	}{
+		{dialect: DbTypePostgres, excepted: `"user""s"`},
+		{dialect: DbTypeMysql, excepted: "`user\"s`"},
+		{dialect: DbTypeMSSql, excepted: `[user"s]`},
+		{dialect: DbTypeOracle, excepted: `"user""s"`},
+	} {
+		if actual := test.dialect.Quote(`user"s`); actual != test.excepted {
+			t.Error(test.dialect.Name(), "excepted is", test.excepted, "actual is", actual)
+		}
+	}
+}
+
+type dialectUser struct {
+	TableName TableName `db:"dialect_users"`
+	ID        int64     `db:"id,pk,autoincr"`
+	Name      string    `db:"name,unique"`
+	Email     string    `db:"email"`
+	CreatedAt int64     `db:"created_at"`
+	UpdatedAt int64     `db:"updated_at"`
+}
+
+func TestGenerateUpsertSQL(t *testing.T) {
+	mapper := CreateMapper("", nil, nil)
+	rType := reflect.TypeOf(&dialectUser{})
+
+	for _, test := range []struct {
+		dialect  Dialect
+		noReturn bool
+		excepted string
+	}{
+		{dialect: DbTypePostgres,
+			excepted: "INSERT INTO dialect_users(name, email, created_at, updated_at) VALUES(#{name}, #{email}, now(), now())" +
+				" ON CONFLICT (name) DO UPDATE SET email=EXCLUDED.email, updated_at=EXCLUDED.updated_at RETURNING id"},
+		{dialect: DbTypePostgres, noReturn: true,
+			excepted: "INSERT INTO dialect_users(name, email, created_at, updated_at) VALUES(#{name}, #{email}, now(), now())" +
+				" ON CONFLICT (name) DO UPDATE SET email=EXCLUDED.email, updated_at=EXCLUDED.updated_at"},
+		{dialect: DbTypeMysql,
+			excepted: "INSERT INTO dialect_users(name, email, created_at, updated_at) VALUES(#{name}, #{email}, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)" +
+				" ON DUPLICATE KEY UPDATE email=VALUES(email), updated_at=VALUES(updated_at), id=LAST_INSERT_ID(id)"},
+		{dialect: DbTypeMSSql,
+			excepted: "MERGE INTO dialect_users USING (VALUES(#{name}, #{email}, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)) AS src(name, email, created_at, updated_at)" +
+				" ON (dialect_users.name = src.name) WHEN MATCHED THEN UPDATE SET email = src.email, updated_at = src.updated_at" +
+				" WHEN NOT MATCHED THEN INSERT (name, email, created_at, updated_at) VALUES (src.name, src.email, src.created_at, src.updated_at)" +
+				" OUTPUT inserted.id;"},
+		{dialect: DbTypeOracle,
+			excepted: "MERGE INTO dialect_users USING (SELECT #{name} AS name, #{email} AS email, CURRENT_TIMESTAMP AS created_at, CURRENT_TIMESTAMP AS updated_at FROM dual) src" +
+				" ON (dialect_users.name = src.name) WHEN MATCHED THEN UPDATE SET email = src.email, updated_at = src.updated_at" +
+				" WHEN NOT MATCHED THEN INSERT (name, email, created_at, updated_at) VALUES (src.name, src.email, src.created_at, src.updated_at)"},
+	} {
+		actual, err := GenerateUpsertSQL(test.dialect, mapper, rType, []string{"Name"}, test.noReturn)
+		if err != nil {
+			t.Error(test.dialect.Name(), err)
+			continue
+		}
+		if actual != test.excepted {
+			t.Error(test.dialect.Name())
+			t.Error("excepted is", test.excepted)
+			t.Error("actual   is", actual)
+		}
+	}
+
+	if _, err := GenerateUpsertSQL(DbTypePostgres, mapper, rType, []string{"phone"}, false); err == nil ||
+		err.Error() != "upsert key 'phone' isnot exists in the table 'dialect_users'" {
+		t.Error(err)
+	}
+	if _, err := GenerateUpsertSQL(DbTypePostgres, mapper, rType, nil, false); err == nil {
+		t.Error("excepted error got ok")
+	}
+}
+
+type dialectTenantUser struct {
+	TableName TableName `db:"dialect_tenant_users"`
+	ID        int64     `db:"id,pk,autoincr"`
+	TenantID  int64     `db:"tenant_id,tenant"`
+	Name      string    `db:"name,unique"`
+	Email     string    `db:"email"`
+}
+
+func TestGenerateUpsertSQLWithTenant(t *testing.T) {
+	mapper := CreateMapper("", nil, nil)
+	rType := reflect.TypeOf(&dialectTenantUser{})
+
+	// 租户列总是在 keys 中，mysql 不按 keys 判断，所以只更新同一个租户的记录
+	for _, test := range []struct {
+		dialect  Dialect
+		excepted string
+	}{
+		{dialect: DbTypePostgres,
+			excepted: `INSERT INTO dialect_tenant_users(tenant_id, name, email) VALUES(<tenant placeholder="true"/>, #{name}, #{email})` +
+				" ON CONFLICT (tenant_id, name) DO UPDATE SET email=EXCLUDED.email RETURNING id"},
+		{dialect: DbTypeMysql,
+			excepted: `INSERT INTO dialect_tenant_users(tenant_id, name, email) VALUES(<tenant placeholder="true"/>, #{name}, #{email})` +
+				" ON DUPLICATE KEY UPDATE email=IF(tenant_id = VALUES(tenant_id), VALUES(email), email)," +
+				" id=IF(tenant_id = VALUES(tenant_id), LAST_INSERT_ID(id), id)"},
+		{dialect: DbTypeMSSql,
+			excepted: `MERGE INTO dialect_tenant_users USING (VALUES(<tenant placeholder="true"/>, #{name}, #{email})) AS src(tenant_id, name, email)` +
+				" ON (dialect_tenant_users.tenant_id = src.tenant_id AND dialect_tenant_users.name = src.name) WHEN MATCHED THEN UPDATE SET email = src.email" +
+				" WHEN NOT MATCHED THEN INSERT (tenant_id, name, email) VALUES (src.tenant_id, src.name, src.email)" +
+				" OUTPUT inserted.id;"},
+	} {
+		actual, err := GenerateUpsertSQL(test.dialect, mapper, rType, []string{"name"}, false)
+		if err != nil {
+			t.Error(test.dialect.Name(), err)
+			continue
+		}
+		if actual != test.excepted {
+			t.Error(test.dialect.Name())
+			t.Error("excepted is", test.excepted)
+			t.Error("actual   is", actual)
+		}
+	}
+
+	callbacks := SetInit([]func(ctx *InitContext) error{
+		func(ctx *InitContext) error {
+			sqlStr, err := GenerateUpsertSQL(ctx.Dialect, ctx.Mapper, rType, []string{"name"}, true)
+			if err != nil {
+				return err
+			}
+			stmt, err := NewMapppedStatement(ctx, "tenant.upsert", StatementTypeInsert, ResultStruct, sqlStr)
+			if err != nil {
+				return err
+			}
+			return ctx.RegisterStatement(stmt)
+		},
+	})
+	defer SetInit(callbacks)
+
+	runner := &fakeRunner{exec: func(query string, args []interface{}) (sql.Result, error) {
+		return driver.RowsAffected(1), nil
+	}}
+	conn, err := newConnection(&Config{DriverName: "postgres", DB: runner})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 两个租户的同名记录按各自的租户判断是否存在
+	excepted := "INSERT INTO dialect_tenant_users(tenant_id, name, email) VALUES($1, $2, $3) ON CONFLICT (tenant_id, name) DO UPDATE SET email=EXCLUDED.email"
+	for _, tenant := range []int64{1, 2} {
+		ctx := WithTenant(context.Background(), tenant)
+		_, err := conn.Insert(ctx, "tenant.upsert", []string{"u"}, []interface{}{&dialectTenantUser{Name: "abc", Email: "a@b.c"}}, true)
+		if err != nil {
+			t.Error(tenant, err)
+			continue
+		}
+		if runner.query != excepted {
+			t.Error(tenant, "excepted is", excepted)
+			t.Error(tenant, "actual   is", runner.query)
+		}
+		if !reflect.DeepEqual(runner.args, []interface{}{tenant, "abc", "a@b.c"}) {
+			t.Error(tenant, runner.args)
+		}
+	}
+
+	if _, err := conn.Insert(context.Background(), "tenant.upsert", []string{"u"}, []interface{}{&dialectTenantUser{Name: "abc"}}, true); err == nil {
+		t.Error("excepted error got ok")
+	}
+}
+
+func TestDialectSchema(t *testing.T) {
+	// 嵌入了 postgres 的 Dialect 按 postgres 的方式生成建表语句和迁移语句
+	kingbase := kingbaseDialect{DbTypePostgres}
+	mapper := CreateMapper("", nil, nil)
+
+	sqlList, err := GenerateCreateTableSQL(kingbase, mapper, reflect.TypeOf(&dialectUser{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	excepted := "CREATE TABLE dialect_users (\r\n  id bigserial NOT NULL,\r\n  name varchar(255) UNIQUE,\r\n  email varchar(255),\r\n  created_at bigint,\r\n  updated_at bigint,\r\n  PRIMARY KEY(id)\r\n)"
+	if len(sqlList) != 1 || sqlList[0] != excepted {
+		t.Error("excepted is", excepted)
+		t.Error("actual   is", sqlList)
+	}
+
+	expected, err := ReadStructSchema(kingbase, mapper, reflect.TypeOf(&dialectUser{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual := &TableSchema{Name: "dialect_users", Columns: []ColumnSchema{
+		{Name: "id", Type: "int8", NotNull: true},
+		{Name: "name", Type: "character varying(255)"},
+		{Name: "email", Type: "text"},
+		{Name: "created_at", Type: "int8"},
+		{Name: "updated_at", Type: "int8"},
+	}, PrimaryKey: []string{"id"}, Indexes: []IndexSchema{
+		{Name: "dialect_users_name_key", Unique: true, Constraint: true, Columns: []string{"name"}},
+	}}
+	diff := DiffTableSchema(kingbase, expected, actual)
+	if up := strings.Join(diff.UpSQL(), ";"); up != "ALTER TABLE dialect_users ALTER COLUMN email TYPE varchar(255)" {
+		t.Error(up)
+	}
+
+	if !supportsTransactionalDDL(kingbase) || supportsTransactionalDDL(DbTypeMysql) || supportsTransactionalDDL(DbTypeOracle) {
+		t.Error("transactional ddl is wrong")
+	}
+
+	if _, err := GenerateCreateTableSQL(DbTypeOracle, mapper, reflect.TypeOf(&dialectUser{})); err == nil ||
+		err.Error() != "generate ddl for 'oracle' is unsupported" {
+		t.Error(err)
+	}
+}
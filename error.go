@@ -2,6 +2,7 @@ package gobatis
 
 import (
 	"errors"
+	"strconv"
 	"strings"
 
 	"github.com/lib/pq"
@@ -25,6 +26,74 @@ func (err *Error) Error() string {
 	return err.e.Error()
 }
 
+// SQLError 表示 sql 语句解析时的错误，带有出错的位置
+//
+// Line 和 Column 从 1 开始，为 0 时表示位置未知; File 为空时位置是相对于语句的文本的
+type SQLError struct {
+	File    string
+	Line    int
+	Column  int
+	ID      string
+	Message string
+}
+
+func (e *SQLError) Error() string {
+	var sb strings.Builder
+	sb.WriteString(e.File)
+	if e.Line > 0 {
+		if sb.Len() > 0 {
+			sb.WriteString(":")
+		}
+		sb.WriteString(strconv.Itoa(e.Line))
+		sb.WriteString(":")
+		sb.WriteString(strconv.Itoa(e.Column))
+	}
+	if sb.Len() > 0 {
+		sb.WriteString(": ")
+	}
+	sb.WriteString(e.Message)
+	return sb.String()
+}
+
+// shift 将相对于一段文本的位置转换为外层的位置，(line, column) 为这段文本在外层的起始位置
+func (e *SQLError) shift(line, column int) {
+	if e.Line <= 0 {
+		e.Line, e.Column = line, column
+		return
+	}
+	if e.Line == 1 {
+		e.Column += column - 1
+	}
+	e.Line += line - 1
+}
+
+func toSQLError(err error) *SQLError {
+	if e, ok := err.(*SQLError); ok {
+		return e
+	}
+	return &SQLError{Message: err.Error()}
+}
+
+// wrapSQLError 给错误信息加上前后缀，并保留出错的位置
+func wrapSQLError(err error, prefix, suffix string) *SQLError {
+	e := toSQLError(err)
+	e.Message = prefix + e.Message + suffix
+	return e
+}
+
+// textPosition 返回 offset 在 txt 中的行和列
+func textPosition(txt string, offset int) (int, int) {
+	if offset > len(txt) {
+		offset = len(txt)
+	}
+	line := 1 + strings.Count(txt[:offset], "\n")
+	column := offset + 1
+	if idx := strings.LastIndex(txt[:offset], "\n"); idx >= 0 {
+		column = offset - idx
+	}
+	return line, column
+}
+
 func handlePQError(e error) error {
 	if e == nil {
 		return nil
@@ -52,6 +121,22 @@ func handlePQError(e error) error {
 	return e
 }
 
+// handleOracleError 将 oracle 的唯一约束错误(ORA-00001)转换为 ValidationError，
+// oracle 驱动没有统一的错误类型，这里只能从错误信息中判断
+func handleOracleError(e error) error {
+	if e == nil {
+		return nil
+	}
+
+	msg := e.Error()
+	if strings.Contains(msg, "ORA-00001") {
+		return &Error{Validations: []ValidationError{
+			{Code: "unique_value_already_exists", Message: msg},
+		}, e: e}
+	}
+	return e
+}
+
 func ErrForGenerateStmt(err error, msg string) error {
 	return errors.New(msg + ": " + err.Error())
 }
@@ -1,12 +1,16 @@
 package gobatis
 
 import (
+	"bytes"
+	"database/sql/driver"
 	"errors"
 	"fmt"
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/Knetic/govaluate"
 )
@@ -56,17 +60,18 @@ var expFunctions = map[string]govaluate.ExpressionFunction{
 	},
 
 	"isnull": func(args ...interface{}) (interface{}, error) {
+		// 参数的值为 nil 时 govaluate 不会传入这个参数，如 isnull(a.b) 中 a 为 nil
 		if len(args) == 0 {
-			return nil, errors.New("isnull() args is empty")
+			return true, nil
 		}
 
 		for idx, arg := range args {
-			rv := reflect.ValueOf(arg)
-			if rv.Kind() != reflect.Ptr {
-				return nil, errors.New("args(" + strconv.FormatInt(int64(idx), 10) + ") isnot ptr")
+			isNull, err := isNullValue(arg)
+			if err != nil {
+				return nil, errors.New("args(" + strconv.FormatInt(int64(idx), 10) + ") " + err.Error())
 			}
 
-			if !rv.IsNil() {
+			if !isNull {
 				return false, nil
 			}
 		}
@@ -76,22 +81,329 @@ var expFunctions = map[string]govaluate.ExpressionFunction{
 
 	"isnotnull": func(args ...interface{}) (interface{}, error) {
 		if len(args) == 0 {
-			return nil, errors.New("isnull() args is empty")
+			return false, nil
 		}
 
 		for idx, arg := range args {
-			rv := reflect.ValueOf(arg)
-			if rv.Kind() != reflect.Ptr {
-				return nil, errors.New("args(" + strconv.FormatInt(int64(idx), 10) + ") isnot ptr")
+			isNull, err := isNullValue(arg)
+			if err != nil {
+				return nil, errors.New("args(" + strconv.FormatInt(int64(idx), 10) + ") " + err.Error())
 			}
 
-			if rv.IsNil() {
+			if isNull {
 				return false, nil
 			}
 		}
 
 		return true, nil
 	},
+
+	// isBlank 值为 nil 或去掉空白字符后为空字符串
+	"isBlank": func(args ...interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, errors.New("isBlank() args isnot 1")
+		}
+		return isBlankValue(args[0])
+	},
+	"isNotBlank": func(args ...interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, errors.New("isNotBlank() args isnot 1")
+		}
+		isBlank, err := isBlankValue(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return !isBlank, nil
+	},
+
+	// isZero 值为 nil 或零值，如 0, "", false 和 time.Time{}
+	"isZero": func(args ...interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, errors.New("isZero() args isnot 1")
+		}
+		return isZeroValue(args[0]), nil
+	},
+	"isNotZero": func(args ...interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, errors.New("isNotZero() args isnot 1")
+		}
+		return !isZeroValue(args[0]), nil
+	},
+
+	// contains(collection, value) 字符串中是否有子串，slice 或 array 中是否有这个元素，map 中是否有这个 key
+	"contains": func(args ...interface{}) (interface{}, error) {
+		if len(args) != 2 {
+			return nil, errors.New("contains() args isnot 2")
+		}
+		return containsValue(args[0], args[1])
+	},
+
+	// in(value, a, b, c) 或 in(value, list) 值是否在列表中
+	"in": func(args ...interface{}) (interface{}, error) {
+		if len(args) < 2 {
+			return nil, errors.New("in() args is less 2")
+		}
+		if len(args) == 2 {
+			if list := expValue(args[1]); list != nil {
+				if kind := reflect.TypeOf(list).Kind(); kind == reflect.Slice || kind == reflect.Array {
+					return containsValue(list, args[0])
+				}
+			}
+		}
+		for _, arg := range args[1:] {
+			if equalValue(args[0], arg) {
+				return true, nil
+			}
+		}
+		return false, nil
+	},
+
+	"hasPrefix": func(args ...interface{}) (interface{}, error) {
+		if len(args) != 2 {
+			return nil, errors.New("hasPrefix() args isnot 2")
+		}
+		s, prefix, err := twoStrings(args[0], args[1])
+		if err != nil {
+			return nil, errors.New("hasPrefix() " + err.Error())
+		}
+		return strings.HasPrefix(s, prefix), nil
+	},
+	"hasSuffix": func(args ...interface{}) (interface{}, error) {
+		if len(args) != 2 {
+			return nil, errors.New("hasSuffix() args isnot 2")
+		}
+		s, suffix, err := twoStrings(args[0], args[1])
+		if err != nil {
+			return nil, errors.New("hasSuffix() " + err.Error())
+		}
+		return strings.HasSuffix(s, suffix), nil
+	},
+
+	"lower": func(args ...interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, errors.New("lower() args isnot 1")
+		}
+		s, err := stringValue(args[0])
+		if err != nil {
+			return nil, errors.New("lower() " + err.Error())
+		}
+		return strings.ToLower(s), nil
+	},
+	"upper": func(args ...interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, errors.New("upper() args isnot 1")
+		}
+		s, err := stringValue(args[0])
+		if err != nil {
+			return nil, errors.New("upper() " + err.Error())
+		}
+		return strings.ToUpper(s), nil
+	},
+	"trim": func(args ...interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, errors.New("trim() args isnot 1")
+		}
+		s, err := stringValue(args[0])
+		if err != nil {
+			return nil, errors.New("trim() " + err.Error())
+		}
+		return strings.TrimSpace(s), nil
+	},
+
+	// coalesce 返回第一个不为 null 的值
+	"coalesce": func(args ...interface{}) (interface{}, error) {
+		if len(args) == 0 {
+			return nil, errors.New("coalesce() args is empty")
+		}
+		for _, arg := range args {
+			if value := expValue(arg); value != nil {
+				if f, ok := toFloat64(value); ok {
+					return f, nil
+				}
+				return value, nil
+			}
+		}
+		return nil, nil
+	},
+}
+
+func (ctx *InitContext) expressionFunctions() map[string]govaluate.ExpressionFunction {
+	if ctx == nil || ctx.Config == nil || len(ctx.Config.ExpressionFuncs) == 0 {
+		return expFunctions
+	}
+
+	funcs := make(map[string]govaluate.ExpressionFunction, len(expFunctions)+len(ctx.Config.ExpressionFuncs))
+	for name, fn := range expFunctions {
+		funcs[name] = fn
+	}
+	for name, fn := range ctx.Config.ExpressionFuncs {
+		funcs[name] = fn
+	}
+	return funcs
+}
+
+// expValue 去掉指针，并将 sql.NullXXX 之类的值转换为它包含的值，值为 null 时返回 nil
+func expValue(value interface{}) interface{} {
+	for value != nil {
+		rv := reflect.ValueOf(value)
+		switch rv.Kind() {
+		case reflect.Ptr, reflect.Interface:
+			if rv.IsNil() {
+				return nil
+			}
+			value = rv.Elem().Interface()
+		case reflect.Struct:
+			valuer, ok := value.(driver.Valuer)
+			if !ok {
+				return value
+			}
+			v, err := valuer.Value()
+			if err != nil {
+				return value
+			}
+			return v
+		default:
+			return value
+		}
+	}
+	return nil
+}
+
+func isNullValue(value interface{}) (bool, error) {
+	if value == nil {
+		return true, nil
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+		return rv.IsNil(), nil
+	case reflect.Struct:
+		if _, ok := value.(driver.Valuer); ok {
+			return expValue(value) == nil, nil
+		}
+	}
+	return false, errors.New("isnot ptr")
+}
+
+func isBlankValue(value interface{}) (bool, error) {
+	value = expValue(value)
+	if value == nil {
+		return true, nil
+	}
+	if bs, ok := value.([]byte); ok {
+		return len(bytes.TrimSpace(bs)) == 0, nil
+	}
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.String {
+		return false, errors.New("value isnot string")
+	}
+	return strings.TrimSpace(rv.String()) == "", nil
+}
+
+func isZeroValue(value interface{}) bool {
+	value = expValue(value)
+	if value == nil {
+		return true
+	}
+	if t, ok := value.(time.Time); ok {
+		return t.IsZero()
+	}
+	return reflect.ValueOf(value).IsZero()
+}
+
+func toFloat64(value interface{}) (float64, bool) {
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+func equalValue(a, b interface{}) bool {
+	a, b = expValue(a), expValue(b)
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	if fa, ok := toFloat64(a); ok {
+		fb, ok := toFloat64(b)
+		return ok && fa == fb
+	}
+
+	ra, rb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if ra.Kind() == reflect.String && rb.Kind() == reflect.String {
+		return ra.String() == rb.String()
+	}
+	if ta, ok := a.(time.Time); ok {
+		tb, ok := b.(time.Time)
+		return ok && ta.Equal(tb)
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func containsValue(collection, value interface{}) (bool, error) {
+	collection = expValue(collection)
+	if collection == nil {
+		return false, nil
+	}
+
+	rv := reflect.ValueOf(collection)
+	switch rv.Kind() {
+	case reflect.String:
+		s, err := stringValue(value)
+		if err != nil {
+			return false, errors.New("contains() " + err.Error())
+		}
+		return strings.Contains(rv.String(), s), nil
+	case reflect.Slice, reflect.Array:
+		for idx := 0; idx < rv.Len(); idx++ {
+			if equalValue(rv.Index(idx).Interface(), value) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case reflect.Map:
+		for _, key := range rv.MapKeys() {
+			if equalValue(key.Interface(), value) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	return false, errors.New("contains() value isnot string, slice, array or map")
+}
+
+func stringValue(value interface{}) (string, error) {
+	value = expValue(value)
+	if value == nil {
+		return "", nil
+	}
+	if bs, ok := value.([]byte); ok {
+		return string(bs), nil
+	}
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.String {
+		return "", errors.New("value isnot string")
+	}
+	return rv.String(), nil
+}
+
+func twoStrings(a, b interface{}) (string, string, error) {
+	s1, err := stringValue(a)
+	if err != nil {
+		return "", "", err
+	}
+	s2, err := stringValue(b)
+	if err != nil {
+		return "", "", err
+	}
+	return s1, s2, nil
 }
 
 type sqlPrinter struct {
@@ -165,10 +477,14 @@ func (stmt *rawStringWithParams) writeTo(printer *sqlPrinter) {
 }
 
 type evalParameters struct {
-	ctx *Context
+	ctx   *Context
+	paths map[string]string
 }
 
 func (eval evalParameters) Get(name string) (interface{}, error) {
+	if path, ok := eval.paths[name]; ok {
+		name = path
+	}
 	value, err := eval.ctx.Get(name)
 	if err == nil {
 		return value, nil
@@ -180,12 +496,14 @@ func (eval evalParameters) Get(name string) (interface{}, error) {
 }
 
 type ifExpression struct {
+	raw      string
 	test     *govaluate.EvaluableExpression
+	paths    map[string]string
 	segement sqlExpression
 }
 
 func (ifExpr ifExpression) String() string {
-	return "<if test=\"" + ifExpr.test.String() + "\">" + ifExpr.segement.String() + "</if>"
+	return "<if test=\"" + ifExpr.raw + "\">" + ifExpr.segement.String() + "</if>"
 }
 
 func (ifExpr ifExpression) writeTo(printer *sqlPrinter) {
@@ -201,8 +519,12 @@ func (ifExpr ifExpression) writeTo(printer *sqlPrinter) {
 }
 
 func (ifExpr ifExpression) isOK(printer *sqlPrinter) (bool, error) {
-	result, err := ifExpr.test.Eval(evalParameters{ctx: printer.ctx})
+	result, err := ifExpr.test.Eval(evalParameters{ctx: printer.ctx, paths: ifExpr.paths})
 	if err != nil {
+		// 检查结果列时所有的参数都为 nil，isNotEmpty(name) 之类的函数会出错，这时当作条件不成立
+		if _, isNull := printer.ctx.finder.(nullFinder); isNull {
+			return false, nil
+		}
 		return false, err
 	}
 
@@ -218,18 +540,103 @@ func (ifExpr ifExpression) isOK(printer *sqlPrinter) (bool, error) {
 	return bResult, nil
 }
 
-func newIFExpression(test string, segement sqlExpression) (sqlExpression, error) {
+func newIFExpression(ctx *InitContext, test string, segement sqlExpression) (sqlExpression, error) {
 	if test == "" {
 		return nil, errors.New("if test is empty")
 	}
 	if segement == nil {
 		return nil, errors.New("if content is empty")
 	}
-	expr, err := govaluate.NewEvaluableExpressionWithFunctions(test, expFunctions)
+	rewrited, paths := rewritePathsInTest(test)
+	funcs := ctx.expressionFunctions()
+	if ctx != nil && ctx.lenient {
+		funcs = lenientFunctions(rewrited, funcs)
+	}
+	expr, err := govaluate.NewEvaluableExpressionWithFunctions(rewrited, funcs)
 	if err != nil {
 		return nil, err
 	}
-	return ifExpression{test: expr, segement: segement}, nil
+	return ifExpression{raw: test, test: expr, paths: paths, segement: segement}, nil
+}
+
+// rewritePathsInTest 将表达式中的属性路径(如 a.b.c 和 items[0].sku)替换为一个变量，
+// 因为 govaluate 不能解析它们，求值时再用这个变量找到原来的路径
+func rewritePathsInTest(test string) (string, map[string]string) {
+	var sb strings.Builder
+	var paths map[string]string
+
+	for pos := 0; pos < len(test); {
+		c := test[pos]
+		if c == '\'' || c == '"' || c == '[' {
+			closeChar := c
+			if c == '[' {
+				closeChar = ']'
+			}
+			end := strings.IndexByte(test[pos+1:], closeChar)
+			if end < 0 {
+				sb.WriteString(test[pos:])
+				break
+			}
+			end += pos + 2
+			sb.WriteString(test[pos:end])
+			pos = end
+			continue
+		}
+
+		end := readPathIdent(test, pos)
+		if end == pos {
+			if c >= '0' && c <= '9' {
+				// 数字，如 1.5 和 1e5
+				for end = pos + 1; end < len(test) && (test[end] == '.' || isPathIdent(rune(test[end]))); end++ {
+				}
+			} else {
+				_, size := utf8.DecodeRuneInString(test[pos:])
+				end = pos + size
+			}
+			sb.WriteString(test[pos:end])
+			pos = end
+			continue
+		}
+
+		for end < len(test) {
+			if test[end] == '.' {
+				next := readPathIdent(test, end+1)
+				if next == end+1 {
+					break
+				}
+				end = next
+			} else if test[end] == '[' {
+				next := end + 1
+				for next < len(test) && test[next] != ']' {
+					if test[next] == '\'' || test[next] == '"' {
+						if closeAt := strings.IndexByte(test[next+1:], test[next]); closeAt >= 0 {
+							next += closeAt + 1
+						}
+					}
+					next++
+				}
+				if next >= len(test) {
+					break
+				}
+				end = next + 1
+			} else {
+				break
+			}
+		}
+
+		name := test[pos:end]
+		if isPropertyPath(name) {
+			if paths == nil {
+				paths = map[string]string{}
+			}
+			variable := "gobatis_path_" + strconv.Itoa(len(paths))
+			paths[variable] = name
+			name = variable
+		}
+		sb.WriteString(name)
+		pos = end
+	}
+	return sb.String(), paths
 }
 
 type choseExpression struct {
@@ -262,11 +669,11 @@ func (chose *choseExpression) writeTo(printer *sqlPrinter) {
 	}
 }
 
-func newChoseExpression(el xmlChoseElement) (sqlExpression, error) {
+func newChoseExpression(ctx *InitContext, el xmlChoseElement) (sqlExpression, error) {
 	var when []ifExpression
 
 	for idx := range el.when {
-		s, err := newIFExpression(el.when[idx].test, el.when[idx].content)
+		s, err := newIFExpression(ctx, el.when[idx].test, el.when[idx].content)
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,159 @@
+package gobatis
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"sync"
+)
+
+// fakeDriver 是测试用的 database/sql 驱动，数据源名为库的名称，语句的结果由 exec 和 query 决定，
+// 它们为 nil 时返回错误，测试可以用 record 记录执行过的语句，再用 reset 取出来
+type fakeDriver struct {
+	mu      sync.Mutex
+	records []string
+
+	// recordTx 为 true 时记录 BEGIN、COMMIT 和 ROLLBACK
+	recordTx bool
+	exec     func(name, query string, args []driver.Value) (driver.Result, error)
+	query    func(name, query string, args []driver.Value) (driver.Rows, error)
+}
+
+// newFakeDriver 创建一个 fakeDriver 并用 driverName 注册它
+func newFakeDriver(driverName string) *fakeDriver {
+	d := &fakeDriver{}
+	sql.Register(driverName, d)
+	return d
+}
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error) {
+	return &fakeConn{driver: d, name: name}, nil
+}
+
+// record 记录语句，name 不为空时记为 "name: query"
+func (d *fakeDriver) record(name, query string) {
+	if name != "" {
+		query = name + ": " + query
+	}
+	d.mu.Lock()
+	d.records = append(d.records, query)
+	d.mu.Unlock()
+}
+
+// reset 返回并清空记录的语句
+func (d *fakeDriver) reset() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	records := d.records
+	d.records = nil
+	return records
+}
+
+type fakeConn struct {
+	driver *fakeDriver
+	name   string
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{conn: c, query: query}, nil
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.recordTx("BEGIN")
+	return c, nil
+}
+
+func (c *fakeConn) Commit() error {
+	c.recordTx("COMMIT")
+	return nil
+}
+
+func (c *fakeConn) Rollback() error {
+	c.recordTx("ROLLBACK")
+	return nil
+}
+
+func (c *fakeConn) recordTx(query string) {
+	if c.driver.recordTx {
+		c.driver.record("", query)
+	}
+}
+
+type fakeStmt struct {
+	conn  *fakeConn
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if s.conn.driver.exec == nil {
+		return nil, errors.New("unsupported")
+	}
+	return s.conn.driver.exec(s.conn.name, s.query, args)
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if s.conn.driver.query == nil {
+		return nil, errors.New("unsupported")
+	}
+	return s.conn.driver.query(s.conn.name, s.query, args)
+}
+
+// fakeRows 返回 values 中的记录
+type fakeRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+// fakeRunner 是测试用的 DBRunner，它记录最后执行的语句和参数，
+// exec 为 nil 时和查询一样返回一个带有自已名字的错误，以便知道语句在哪个库上执行
+type fakeRunner struct {
+	name  string
+	inUse int
+
+	query string
+	args  []interface{}
+	exec  func(query string, args []interface{}) (sql.Result, error)
+}
+
+func (r *fakeRunner) Prepare(query string) (*sql.Stmt, error) {
+	return nil, errors.New(r.name)
+}
+
+func (r *fakeRunner) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	r.query, r.args = query, args
+	if r.exec == nil {
+		return nil, errors.New(r.name)
+	}
+	return r.exec(query, args)
+}
+
+func (r *fakeRunner) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	r.query, r.args = query, args
+	return nil, errors.New(r.name)
+}
+
+func (r *fakeRunner) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return nil
+}
+
+func (r *fakeRunner) Stats() sql.DBStats {
+	return sql.DBStats{InUse: r.inUse}
+}
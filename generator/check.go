@@ -0,0 +1,96 @@
+package generator
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	gobatis "github.com/runner-mei/GoBatis"
+	"github.com/runner-mei/GoBatis/goparser"
+)
+
+func (cmd *Generator) loadXMLStatements() error {
+	if cmd.xmlPaths == "" {
+		return nil
+	}
+
+	cmd.xmlStatements = map[string][]gobatis.XMLStatement{}
+	for _, pa := range strings.Split(cmd.xmlPaths, ",") {
+		pa = strings.TrimSpace(pa)
+		if pa == "" {
+			continue
+		}
+
+		st, err := os.Stat(pa)
+		if err != nil {
+			return err
+		}
+
+		var filenames []string
+		if st.IsDir() {
+			err = filepath.Walk(pa, func(filename string, info os.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+				if !info.IsDir() && strings.HasSuffix(strings.ToLower(filename), ".xml") {
+					filenames = append(filenames, filename)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		} else {
+			filenames = append(filenames, pa)
+		}
+
+		for _, filename := range filenames {
+			statements, err := gobatis.ReadXMLStatements(filename)
+			if err != nil {
+				return err
+			}
+			for _, stmt := range statements {
+				cmd.xmlStatements[stmt.ID] = append(cmd.xmlStatements[stmt.ID], stmt)
+			}
+		}
+	}
+	return nil
+}
+
+// checkStatements 检查方法的注释中和 xml 文件中的语句所引用的参数是否存在
+func (cmd *Generator) checkStatements(file *goparser.File) error {
+	for _, itf := range file.Interfaces {
+		for _, method := range itf.Methods {
+			id := itf.Name + "." + method.Name
+
+			if method.Config != nil && method.Config.Reference == nil {
+				if method.Config.DefaultSQL != "" {
+					if err := method.CheckStatement(method.Config.DefaultSQL); err != nil {
+						return errors.New("method '" + id + "' is invalid, " + err.Error())
+					}
+				}
+
+				dialects := make([]string, 0, len(method.Config.Dialects))
+				for dialect := range method.Config.Dialects {
+					dialects = append(dialects, dialect)
+				}
+				sort.Strings(dialects)
+				for _, dialect := range dialects {
+					if err := method.CheckStatement(method.Config.Dialects[dialect]); err != nil {
+						return errors.New("method '" + id + "' is invalid in the " + dialect + ", " + err.Error())
+					}
+				}
+			}
+
+			for _, stmt := range cmd.xmlStatements[id] {
+				if err := method.CheckStatement(stmt.SQL); err != nil {
+					return errors.New(stmt.File + ":" + strconv.Itoa(stmt.Line) + ": statement '" + id + "' is invalid, " + err.Error())
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,237 @@
+package generator
+
+import (
+	"errors"
+	"go/types"
+	"io"
+	"reflect"
+	"strings"
+	"text/template"
+
+	"github.com/runner-mei/GoBatis/goparser"
+)
+
+// criteriaType 是要生成的 XxxCriteria 类型，Xxx 是记录的类型
+type criteriaType struct {
+	Name       string
+	RecordType string
+	Fields     []criteriaField
+}
+
+// criteriaField 是记录中可以作为条件的字段
+type criteriaField struct {
+	Name     string
+	Type     string // 条件值的类型，字段为指针时是它指向的类型
+	Ordered  bool   // 可以比较大小(数字、字符串和时间)
+	IsString bool
+	Nullable bool
+}
+
+// findCriteriaTypes 查找方法参数中名为 *XxxCriteria 的类型，Xxx 是方法的记录类型，
+// 它们都由 gobatis 生成，第一次生成时它们还不存在，goparser 用一个空结构代替它们
+func findCriteriaTypes(file *goparser.File) []*criteriaType {
+	var criterias []*criteriaType
+	exists := map[string]bool{}
+	ctx := &goparser.PrintContext{File: file}
+	for _, itf := range file.Interfaces {
+		ctx.Interface = itf
+		for _, m := range itf.Methods {
+			if m.Params == nil {
+				continue
+			}
+			for _, param := range m.Params.List {
+				ptr, ok := param.Type.(*types.Pointer)
+				if !ok {
+					continue
+				}
+				named, ok := ptr.Elem().(*types.Named)
+				if !ok || named.Obj().Pkg() == nil || named.Obj().Pkg().Name() != file.Package {
+					continue
+				}
+				if !strings.HasSuffix(named.Obj().Name(), "Criteria") || exists[named.Obj().Name()] {
+					continue
+				}
+
+				recordType, ok := itf.DetectRecordType(m).(*types.Named)
+				if !ok || named.Obj().Name() != recordType.Obj().Name()+"Criteria" {
+					continue
+				}
+				st, ok := recordType.Underlying().(*types.Struct)
+				if !ok {
+					continue
+				}
+
+				exists[named.Obj().Name()] = true
+				criterias = append(criterias, &criteriaType{
+					Name:       named.Obj().Name(),
+					RecordType: goparser.PrintType(ctx, recordType, false),
+					Fields:     criteriaFields(ctx, st, file.TagName, nil),
+				})
+			}
+		}
+	}
+	return criterias
+}
+
+func criteriaFields(ctx *goparser.PrintContext, st *types.Struct, tagName string, fields []criteriaField) []criteriaField {
+	for i := 0; i < st.NumFields(); i++ {
+		field := st.Field(i)
+		if !field.Exported() || field.Name() == "TableName" {
+			continue
+		}
+		if _, ok := goparser.ColumnName(reflect.StructTag(st.Tag(i)), tagName, field.Name()); !ok {
+			continue
+		}
+
+		typ := field.Type()
+		nullable := false
+		if ptr, ok := typ.(*types.Pointer); ok {
+			typ, nullable = ptr.Elem(), true
+		}
+
+		if field.Anonymous() && !goparser.IsIgnoreStructTypes(typ) {
+			if embedded, ok := typ.Underlying().(*types.Struct); ok {
+				fields = criteriaFields(ctx, embedded, tagName, fields)
+			}
+			continue
+		}
+
+		cf := criteriaField{Name: field.Name(), Nullable: nullable}
+		switch u := typ.Underlying().(type) {
+		case *types.Basic:
+			cf.IsString = u.Info()&types.IsString != 0
+			cf.Ordered = u.Info()&types.IsOrdered != 0
+		case *types.Struct:
+			if goparser.IsIgnoreStructTypes(typ) {
+				// time.Time 可以比较大小，sql.NullXXX 等只能比较是否相等
+				cf.Ordered = typ.String() == "time.Time"
+				cf.Nullable = cf.Nullable || !cf.Ordered
+			} else {
+				// json 等
+				continue
+			}
+		default:
+			continue
+		}
+		cf.Type = goparser.PrintType(ctx, typ, false)
+		fields = append(fields, cf)
+	}
+	return fields
+}
+
+func (cmd *Generator) generateCriterias(out io.Writer, file *goparser.File) error {
+	for _, criteria := range findCriteriaTypes(file) {
+		if err := criteriaFunc.Execute(out, criteria); err != nil {
+			return errors.New("generate criteria '" + criteria.Name + "' fail, " + err.Error())
+		}
+	}
+	return nil
+}
+
+var criteriaFunc = template.Must(template.New("CriteriaFunc").Parse(`
+// {{.Name}} 是 {{.RecordType}} 的查询条件
+type {{.Name}} struct {
+	gobatis.Criteria
+}
+
+// RecordType 返回条件对应的结构，gobatis 用它将字段名转换为列名
+func (c *{{.Name}}) RecordType() reflect.Type {
+	return reflect.TypeOf(&{{.RecordType}}{})
+}
+
+func (c *{{.Name}}) criterias(groups []*{{.Name}}) []*gobatis.Criteria {
+	criterias := make([]*gobatis.Criteria, len(groups))
+	for idx, group := range groups {
+		if group != nil {
+			criterias[idx] = &group.Criteria
+		}
+	}
+	return criterias
+}
+
+// And 加上一组用 AND 连接的条件
+func (c *{{.Name}}) And(groups ...*{{.Name}}) *{{.Name}} {
+	c.Criteria.And(c.criterias(groups)...)
+	return c
+}
+
+// Or 加上一组用 OR 连接的条件
+func (c *{{.Name}}) Or(groups ...*{{.Name}}) *{{.Name}} {
+	c.Criteria.Or(c.criterias(groups)...)
+	return c
+}
+{{- $name := .Name}}
+{{- range $field := .Fields}}
+
+func (c *{{$name}}) {{$field.Name}}Equal(value {{$field.Type}}) *{{$name}} {
+	c.Criteria.Add("{{$field.Name}}", gobatis.Equal, value)
+	return c
+}
+
+func (c *{{$name}}) {{$field.Name}}NotEqual(value {{$field.Type}}) *{{$name}} {
+	c.Criteria.Add("{{$field.Name}}", gobatis.NotEqual, value)
+	return c
+}
+
+func (c *{{$name}}) {{$field.Name}}In(values ...{{$field.Type}}) *{{$name}} {
+	c.Criteria.Add("{{$field.Name}}", gobatis.In, values)
+	return c
+}
+
+func (c *{{$name}}) {{$field.Name}}NotIn(values ...{{$field.Type}}) *{{$name}} {
+	c.Criteria.Add("{{$field.Name}}", gobatis.NotIn, values)
+	return c
+}
+{{- if $field.Ordered}}
+
+func (c *{{$name}}) {{$field.Name}}Less(value {{$field.Type}}) *{{$name}} {
+	c.Criteria.Add("{{$field.Name}}", gobatis.Less, value)
+	return c
+}
+
+func (c *{{$name}}) {{$field.Name}}LessEqual(value {{$field.Type}}) *{{$name}} {
+	c.Criteria.Add("{{$field.Name}}", gobatis.LessEqual, value)
+	return c
+}
+
+func (c *{{$name}}) {{$field.Name}}Greater(value {{$field.Type}}) *{{$name}} {
+	c.Criteria.Add("{{$field.Name}}", gobatis.Greater, value)
+	return c
+}
+
+func (c *{{$name}}) {{$field.Name}}GreaterEqual(value {{$field.Type}}) *{{$name}} {
+	c.Criteria.Add("{{$field.Name}}", gobatis.GreaterEqual, value)
+	return c
+}
+
+func (c *{{$name}}) {{$field.Name}}Between(start, end {{$field.Type}}) *{{$name}} {
+	c.Criteria.Add("{{$field.Name}}", gobatis.Between, []{{$field.Type}}{start, end})
+	return c
+}
+{{- end}}
+{{- if $field.IsString}}
+
+func (c *{{$name}}) {{$field.Name}}Like(value {{$field.Type}}) *{{$name}} {
+	c.Criteria.Add("{{$field.Name}}", gobatis.Like, value)
+	return c
+}
+
+func (c *{{$name}}) {{$field.Name}}NotLike(value {{$field.Type}}) *{{$name}} {
+	c.Criteria.Add("{{$field.Name}}", gobatis.NotLike, value)
+	return c
+}
+{{- end}}
+{{- if $field.Nullable}}
+
+func (c *{{$name}}) {{$field.Name}}IsNull() *{{$name}} {
+	c.Criteria.Add("{{$field.Name}}", gobatis.IsNull, nil)
+	return c
+}
+
+func (c *{{$name}}) {{$field.Name}}IsNotNull() *{{$name}} {
+	c.Criteria.Add("{{$field.Name}}", gobatis.IsNotNull, nil)
+	return c
+}
+{{- end}}
+{{- end}}
+`))
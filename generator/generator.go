@@ -19,18 +19,32 @@ import (
 )
 
 type Generator struct {
+	xmlPaths      string
+	tagName       string
+	xmlStatements map[string][]gobatis.XMLStatement
 }
 
 func (cmd *Generator) Flags(fs *flag.FlagSet) *flag.FlagSet {
+	fs.StringVar(&cmd.xmlPaths, "xml", "", "检查 xml 文件中的语句的参数，多个文件或目录用逗号分隔")
+	fs.StringVar(&cmd.tagName, "tag", "db", "结构中列名所在的 tag，与 Config.TagPrefix 一致，为 xorm 时按 xorm 的格式解析")
 	return fs
 }
 
 func (cmd *Generator) Run(args []string) error {
+	if err := cmd.loadXMLStatements(); err != nil {
+		return err
+	}
+
+	hasError := false
 	for _, file := range flag.Args() {
 		if err := cmd.runFile(file); err != nil {
 			log.Println(err)
+			hasError = true
 		}
 	}
+	if hasError {
+		return errors.New("generate fail")
+	}
 	return nil
 }
 
@@ -45,6 +59,11 @@ func (cmd *Generator) runFile(filename string) error {
 	if err != nil {
 		return err
 	}
+	file.TagName = cmd.tagName
+
+	if err = cmd.checkStatements(file); err != nil {
+		return err
+	}
 
 	targetFile := strings.TrimSuffix(pa, ".go") + ".gobatis.go"
 
@@ -75,6 +94,10 @@ func (cmd *Generator) runFile(filename string) error {
 		}
 	}
 
+	if err = cmd.generateCriterias(out, file); err != nil {
+		return err
+	}
+
 	if err = out.Close(); err != nil {
 		os.Remove(targetFile + ".tmp")
 		return err
@@ -154,9 +177,18 @@ var funcs = template.FuncMap{
 	"isType":            isExceptedType,
 	"isStructType":      goparser.IsStructType,
 	"underlyingType":    goparser.GetElemType,
+	"selectKind":        selectKind,
 	"typePrint": func(ctx *goparser.PrintContext, typ types.Type) string {
 		return goparser.PrintType(ctx, typ, false)
 	},
+	"isAggregate": func(name string) bool {
+		_, _, ok := gobatis.SplitAggregateMethod(name)
+		return ok
+	},
+	"isStats": func(name string) bool {
+		_, ok := gobatis.SplitStatsMethod(name)
+		return ok
+	},
 	"detectRecordType": func(itf *goparser.Interface, method *goparser.Method) types.Type {
 		return itf.DetectRecordType(method)
 	},
@@ -284,7 +316,7 @@ func init() {
 		sqlStr
 		{{- else}}
 		s
-		{{- end}}, err := gobatis.GenerateInsertSQL{{if eq .var_style 2}}2{{end}}(ctx.Dialect, ctx.Mapper, 
+		{{- end}}, err := gobatis.GenerateInsert{{if .method.ReturningParam}}Returning{{end}}SQL{{if eq .var_style 2}}2{{end}}(ctx.Dialect, ctx.Mapper, 
 		reflect.TypeOf(&{{.recordTypeName}}{}), 
 		{{- if eq .var_style 2}}
 		[]string{
@@ -295,7 +327,8 @@ func init() {
 			{{- end}}
 			},
 		{{- end}}
-		{{- if eq (len .method.Results.List) 2 -}}
+		{{- if .method.ReturningParam -}}
+		{{- else if eq (len .method.Results.List) 2 -}}
 	    	false
 	    	{{- else -}}
 	    	true
@@ -328,7 +361,10 @@ func init() {
 		{{- if and (isType $param.Type "context") (eq $idx 0)}}
 			{{- set $ "var_first_is_context" true}}
 		{{- else if and (isType $param.Type "struct") (isNotLast $.method.Params.List $idx)}}
+			{{- /* 第一个参数可以是主键结构 */ -}}
+			{{- if not (or (eq $idx 0) (and (eq $idx 1) $.var_first_is_context))}}
 			{{- set $ "var_contains_struct" true}}
+			{{- end}}
 		{{- end}}
 	{{- end}}
 
@@ -343,7 +379,7 @@ func init() {
 		{{- end}}, err := 
 
 		{{- if $var_style_1 -}}
-				gobatis.GenerateUpdateSQL(ctx.Dialect, ctx.Mapper, 
+				gobatis.GenerateUpdate{{if .method.ReturningParam}}Returning{{end}}SQL(ctx.Dialect, ctx.Mapper, 
 					"{{$lastParam.Name}}.", reflect.TypeOf(&{{.recordTypeName}}{}), 
 					[]string{
 					{{- range $idx, $param := .method.Params.List}}
@@ -496,6 +532,98 @@ func init() {
 	{{- end}}
 {{- end}}
 
+{{- define "aggregate"}}
+	{{-   $var_undefined := default .var_undefined false}}
+	{{-   if $var_undefined }}
+	sqlStr
+	{{- else}}
+	s
+	{{- end}}, err := gobatis.GenerateAggregateSQL(ctx.Dialect, ctx.Mapper, 
+	reflect.TypeOf(&{{.recordTypeName}}{}), 
+	"{{.method.Name}}",
+	{{- $r1 := index .method.Results.List 0}}
+	reflect.TypeOf(new({{typePrint $.printContext $r1.Type}})).Elem(),
+		[]string{
+	{{-     range $idx, $param := .method.Params.List}}
+	{{-       if isType $param.Type "context" | not }}
+		"{{$param.Name}}",
+	{{-       end}}
+	{{-     end}}
+		},
+		[]reflect.Type{
+	{{-     range $idx, $param := .method.Params.List}}
+	{{-       if isType $param.Type "context" | not }}
+	  {{- if isType $param.Type "slice"}}
+		  reflect.TypeOf({{typePrint $.printContext $param.Type}}{}),
+	  {{- else if isType $param.Type "ptr"}}
+		  reflect.TypeOf(({{typePrint $.printContext $param.Type}})(nil)),
+	  {{- else if isType $param.Type "basic"}}
+		  reflect.TypeOf(new({{typePrint $.printContext $param.Type}})).Elem(),
+		{{- else}}
+		  reflect.TypeOf(&{{typePrint $.printContext $param.Type}}{}).Elem(),
+		{{- end}}
+	{{-       end}}
+	{{-     end}}
+		},
+		[]gobatis.Filter{ 
+		{{- range $param := .method.Config.SQL.Filters}}
+		{Expression: "{{$param.Expression}}"{{if $param.Dialect}}, Dialect: "{{$param.Dialect}}"{{end}}},
+		{{- end}}
+		})
+	if err != nil {
+		return gobatis.ErrForGenerateStmt(err, "generate {{.itf.Name}}.{{.method.Name}} error")
+	}
+	{{- if not $var_undefined }}
+	sqlStr = s
+	{{- end}}
+{{- end}}
+
+{{- define "stats"}}
+	{{-   $var_undefined := default .var_undefined false}}
+	{{-   if $var_undefined }}
+	sqlStr
+	{{- else}}
+	s
+	{{- end}}, err := gobatis.GenerateStatsSQL(ctx.Dialect, ctx.Mapper, 
+	reflect.TypeOf(&{{.recordTypeName}}{}), 
+	"{{.method.Name}}",
+	{{- $r1 := index .method.Results.List 0}}
+	reflect.TypeOf(new({{typePrint $.printContext $r1.Type}})).Elem(),
+		[]string{
+	{{-     range $idx, $param := .method.Params.List}}
+	{{-       if isType $param.Type "context" | not }}
+		"{{$param.Name}}",
+	{{-       end}}
+	{{-     end}}
+		},
+		[]reflect.Type{
+	{{-     range $idx, $param := .method.Params.List}}
+	{{-       if isType $param.Type "context" | not }}
+	  {{- if isType $param.Type "slice"}}
+		  reflect.TypeOf({{typePrint $.printContext $param.Type}}{}),
+	  {{- else if isType $param.Type "ptr"}}
+		  reflect.TypeOf(({{typePrint $.printContext $param.Type}})(nil)),
+	  {{- else if isType $param.Type "basic"}}
+		  reflect.TypeOf(new({{typePrint $.printContext $param.Type}})).Elem(),
+		{{- else}}
+		  reflect.TypeOf(&{{typePrint $.printContext $param.Type}}{}).Elem(),
+		{{- end}}
+	{{-       end}}
+	{{-     end}}
+		},
+		[]gobatis.Filter{ 
+		{{- range $param := .method.Config.SQL.Filters}}
+		{Expression: "{{$param.Expression}}"{{if $param.Dialect}}, Dialect: "{{$param.Dialect}}"{{end}}},
+		{{- end}}
+		})
+	if err != nil {
+		return gobatis.ErrForGenerateStmt(err, "generate {{.itf.Name}}.{{.method.Name}} error")
+	}
+	{{- if not $var_undefined }}
+	sqlStr = s
+	{{- end}}
+{{- end}}
+
 {{- define "select"}}
 	{{-   $var_undefined := default .var_undefined false}}
 	{{-   if $var_undefined}}
@@ -550,7 +678,11 @@ func init() {
 	  {{- else if eq $statementType "delete"}}
     {{-   template "delete" . | arg "recordTypeName" .recordTypeName}}
 	  {{- else if eq $statementType "select"}}
-	  {{-   if containSubstr .method.Name "Count" }}
+	  {{-   if isAggregate .method.Name }}
+	  {{-     template "aggregate" . | arg "recordTypeName" .recordTypeName}}
+	  {{-   else if isStats .method.Name }}
+	  {{-     template "stats" . | arg "recordTypeName" .recordTypeName}}
+	  {{-   else if containSubstr .method.Name "Count" }}
 	  {{-     template "count" . | arg "recordTypeName" .recordTypeName}}
 	  {{-   else}}
 		{{-     $r1 := index .method.Results.List 0}}
@@ -558,16 +690,16 @@ func init() {
 	  {{-       template "select" . | arg "recordTypeName" .recordTypeName}}
 	  {{-     else}}
               {{- set . "genError" true}}
-	  	        return errors.New("sql '{{.itf.Name}}.{{.method.Name}}' error : statement not found - Generate SQL fail: sql is undefined")
+	  	        {{- template "stmtNotFound" . | arg "reason" " - Generate SQL fail: sql is undefined"}}
 	  {{-     end}}
 	  {{-   end}}
 	  {{- else}}
     {{- set . "genError" true}}
-	  return errors.New("sql '{{.itf.Name}}.{{.method.Name}}' error : statement not found ")
+	  {{- template "stmtNotFound" . | arg "reason" " "}}
 	  {{- end}}
   {{- else}}
         {{- set . "genError" true}}
-        return errors.New("sql '{{.itf.Name}}.{{.method.Name}}' error : statement not found - Generate SQL fail: recordType is unknown")
+        {{- template "stmtNotFound" . | arg "reason" " - Generate SQL fail: recordType is unknown"}}
   {{- end}}
 {{- end}}
 
@@ -579,7 +711,15 @@ stmt, err := gobatis.NewMapppedStatement(ctx, "{{.itf.Name}}.{{.method.Name}}",
 if err != nil {
 	return err
 }
-ctx.Statements["{{.itf.Name}}.{{.method.Name}}"] = stmt
+if err := ctx.RegisterStatement(stmt); err != nil {
+	return err
+}
+{{- end}}
+
+{{- define "stmtNotFound"}}
+if _, exists := ctx.Statements["{{.itf.Name}}.{{.method.Name}}"]; !exists {
+	return errors.New("sql '{{.itf.Name}}.{{.method.Name}}' error : statement not found{{.reason}}")
+}
 {{- end}}
 
 
@@ -589,7 +729,7 @@ func init() {
 	{{-   if and $m.Config $m.Config.Reference}}
 	{{-   else}}
 	{ //// {{$.itf.Name}}.{{$m.Name}}
-		if _, exists := ctx.Statements["{{$.itf.Name}}.{{$m.Name}}"]; !exists {
+		if ctx.ShouldGenerate("{{$.itf.Name}}.{{$m.Name}}") {
 
     {{- set $ "genError" false}}
     {{- set $ "var_undefined" false}}
@@ -628,6 +768,12 @@ func init() {
 			{{- template "registerStmt" $ | arg "method" $m}}
 		{{- end}}
 		}
+		{{- if and (eq $m.StatementTypeName "select") (eq (len $m.Results.List) 2) (eq (selectKind $m.Name) "select")}}
+		{{-   $r1 := index $m.Results.List 0}}
+		{{-   if isType $r1.Type "underlyingStruct"}}
+		ctx.RegisterResultType("{{$.itf.Name}}.{{$m.Name}}", reflect.TypeOf(&{{typePrint $.printContext (underlyingType $r1.Type)}}{}).Elem())
+		{{-   end}}
+		{{- end}}
 	}
 	{{-   end}}
 	{{- end}}
@@ -648,29 +794,38 @@ func New{{.itf.Name}}(ref gobatis.SqlSession
 
 	implFunc = template.Must(template.New("ImplFunc").Funcs(funcs).Parse(`
 {{- define "printContext"}}
+	{{- if and .method.Config (eq (index .method.Config.Options "primary") "true") -}}
+		gobatis.WithPrimary({{- template "printContextValue" . -}}),
+	{{- else -}}
+		{{- template "printContextValue" . -}},
+	{{- end -}}
+{{- end -}}
+{{- define "printContextValue"}}
 	{{- if .method.Params.List -}}
 		{{- set $ "hasContextInParams" false -}}
 	  	{{- range $param := .method.Params.List}}
 	  	  {{- if isType $param.Type "context" -}}
-	   		{{- $param.Name -}},
+	   		{{- $param.Name -}}
 			{{- set $ "hasContextInParams" true -}}
 	   	   {{- end -}}
 	   	{{- end -}}
 	   	{{- if not .hasContextInParams -}}
-  			context.Background(),
+  			context.Background()
 	   	{{- end -}}
   	{{- else -}}
-  	context.Background(),
+  	context.Background()
   	{{- end -}}
 {{- end -}}
 {{- define "insert"}}
-  {{- if eq (len .method.Results.List) 2}}
+  {{- if .method.ReturningParam}}
+  return
+  {{- else if eq (len .method.Results.List) 2}}
   return
   {{- else -}}
 	{{- $rerr := index .method.Results.List 0}}
 	{{- $errName := default $rerr.Name "err"}}
 	_, {{$errName}} {{if not $rerr.Name -}}:{{- end -}}=
-  {{- end}} impl.session.Insert(
+  {{- end}} impl.session.Insert{{if .method.ReturningParam}}Returning{{end}}(
   	{{- template "printContext" . -}}
   	"{{.itf.Name}}.{{.method.Name}}",
 		{{- if .method.Params.List}}
@@ -695,13 +850,17 @@ func New{{.itf.Name}}(ref gobatis.SqlSession
 		{{- else -}}
 		nil
 		{{- end -}}
-	  {{- if ne (len .method.Results.List) 2 -}}
+	  {{- if .method.ReturningParam -}}
+	  ,
+	  {{.method.ReturningParam.Name}}
+	  {{- else if ne (len .method.Results.List) 2 -}}
 	  ,
 	  true
 	  {{- end -}}
     )
 
-  {{- if ne (len .method.Results.List) 2}}
+  {{- if .method.ReturningParam}}
+  {{- else if ne (len .method.Results.List) 2}}
 	{{- $rerr := index .method.Results.List 0}}
 	{{- $errName := default $rerr.Name "err"}}
 	return {{$errName}}
@@ -710,13 +869,15 @@ func New{{.itf.Name}}(ref gobatis.SqlSession
 {{- end}}
 
 {{- define "update"}}
-{{- if eq (len .method.Results.List) 2}}
+{{- if .method.ReturningParam}}
+  return
+  {{- else if eq (len .method.Results.List) 2}}
   return
   {{- else -}}
 	{{- $rerr := index .method.Results.List 0}}
 	{{- $errName := default $rerr.Name "err"}}
 	_, {{$errName}} {{if not $rerr.Name -}}:{{- end -}}=
-  {{- end}} impl.session.Update(
+  {{- end}} impl.session.Update{{if .method.ReturningParam}}Returning{{end}}(
   	{{- template "printContext" . -}}
   	"{{.itf.Name}}.{{.method.Name}}",
 	{{- if .method.Params.List}}
@@ -741,10 +902,15 @@ func New{{.itf.Name}}(ref gobatis.SqlSession
 	{{- else -}}
 	nil
 	{{- end -}}
+	{{- if .method.ReturningParam -}}
+	,
+	{{.method.ReturningParam.Name}}
+	{{- end -}}
 	)
 
 
-  {{- if ne (len .method.Results.List) 2}}
+  {{- if .method.ReturningParam}}
+  {{- else if ne (len .method.Results.List) 2}}
 	{{- $rerr := index .method.Results.List 0}}
 	{{- $errName := default $rerr.Name "err"}}
 	return {{$errName}}
@@ -870,6 +1036,8 @@ func New{{.itf.Name}}(ref gobatis.SqlSession
     return 0, {{$errName}}
   	{{- else if isType $r1.Type "string"}}
     return "", {{$errName}}
+  	{{- else if isType $r1.Type "bool"}}
+    return false, {{$errName}}
   	{{- else if isType $r1.Type "struct"}}
     return instance, {{$errName}}
   	{{- else}}
@@ -902,6 +1070,8 @@ func New{{.itf.Name}}(ref gobatis.SqlSession
 		    return 0, sql.ErrNoRows
 		  	{{- else if isType $r1.Type "string"}}
 		    return "", sql.ErrNoRows
+		  	{{- else if isType $r1.Type "bool"}}
+		    return false, sql.ErrNoRows
 		  	{{- else}}
 		    return nil, sql.ErrNoRows
 		  	{{- end}}
@@ -1261,6 +1431,21 @@ func (impl *{{$.itf.Name}}Impl) {{$m.MethodSignature $.printContext}} {
 `))
 }
 
+// selectKind 按方法名返回 select 方法生成的语句的种类: aggregate、stats、count 或 select，
+// 它和生成语句时的判断一致，只有 select 的结果类型会被登记
+func selectKind(name string) string {
+	if _, _, ok := gobatis.SplitAggregateMethod(name); ok {
+		return "aggregate"
+	}
+	if _, ok := gobatis.SplitStatsMethod(name); ok {
+		return "stats"
+	}
+	if strings.Contains(name, "Count") {
+		return "count"
+	}
+	return "select"
+}
+
 func isExceptedType(typ types.Type, excepted string, or ...string) bool {
 	if ptr, ok := typ.(*types.Pointer); ok {
 		if excepted == "ptr" {
@@ -1370,6 +1555,12 @@ func isExceptedType(typ types.Type, excepted string, or ...string) bool {
 				}
 			}
 
+		case "bool":
+			if basic, ok := typ.Underlying().(*types.Basic); ok {
+				if basic.Kind() == types.Bool {
+					return true
+				}
+			}
 		case "basic":
 			if _, ok := typ.(*types.Basic); ok {
 				return true
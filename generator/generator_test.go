@@ -0,0 +1,25 @@
+package generator
+
+import "testing"
+
+func TestSelectKind(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		excepted string
+	}{
+		{name: "SumAmountByUserID", excepted: "aggregate"},
+		{name: "StatsByStatus", excepted: "stats"},
+		{name: "Count", excepted: "count"},
+		{name: "CountByUserID", excepted: "count"},
+		{name: "GetCount", excepted: "count"},
+		{name: "GetCountry", excepted: "count"},
+		{name: "ListCountryCount", excepted: "count"},
+		{name: "ListByAccountID", excepted: "select"},
+		{name: "Get", excepted: "select"},
+	} {
+		if actual := selectKind(test.name); actual != test.excepted {
+			t.Error(test.name, "excepted is", test.excepted)
+			t.Error(test.name, "actual   is", actual)
+		}
+	}
+}
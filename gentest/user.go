@@ -2,6 +2,7 @@
 package gentest
 
 import (
+	"context"
 	"time"
 
 	gobatis "github.com/runner-mei/GoBatis"
@@ -115,6 +116,10 @@ type UserDao interface {
 	// @default select username from auth_users where id = #{id}
 	GetNameByID(id int64) (string, error)
 
+	// @option primary true
+	// @default select * from auth_users where id = #{id}
+	GetFromPrimary(ctx context.Context, id int64) (*User, error)
+
 	// @type select
 	// @default select * from auth_roles where exists(
 	//            select * from auth_users_and_roles
@@ -0,0 +1,268 @@
+package goparser
+
+import (
+	"errors"
+	"go/types"
+	"reflect"
+	"strconv"
+	"strings"
+
+	gobatis "github.com/runner-mei/GoBatis"
+)
+
+// CheckStatement 检查 sql 语句中引用的参数(#{}、<print value>、<foreach collection>、<page>、<criteria> 和 test 表达式中的变量)
+// 在方法的参数中是否存在，参数是结构时按 File.TagName 指定的 tag 检查它的字段
+func (m *Method) CheckStatement(sqlStr string) error {
+	refs, err := gobatis.StatementReferences(sqlStr)
+	if err != nil {
+		return err
+	}
+
+	var params []Param
+	if m.Params != nil {
+		for _, param := range m.Params.List {
+			if isContextType(param.Type) {
+				continue
+			}
+			params = append(params, param)
+		}
+	}
+
+	// 已检查过的 foreach 中 collection 的元素类型，为 nil 时表示无法知道
+	elems := map[string]types.Type{}
+	for _, ref := range refs {
+		typ, err := m.resolveReference(params, elems, ref)
+		if err != nil {
+			return err
+		}
+
+		if ref.Kind == gobatis.ReferenceCollection {
+			elems[scopeKey(len(ref.Foreach), ref.Name)] = elemType(typ)
+		}
+	}
+	return nil
+}
+
+func scopeKey(depth int, collection string) string {
+	return strconv.Itoa(depth) + ":" + collection
+}
+
+func (m *Method) resolveReference(params []Param, elems map[string]types.Type, ref gobatis.ParamReference) (types.Type, error) {
+	root := ref.Path[0]
+
+	for i := len(ref.Foreach) - 1; i >= 0; i-- {
+		scope := ref.Foreach[i]
+		if root == scope.Index {
+			// index 是数组的下标或 map 的 key, 不再检查
+			return nil, nil
+		}
+		if root == scope.Item {
+			typ := elems[scopeKey(i, scope.Collection)]
+			if typ == nil {
+				return nil, nil
+			}
+			return m.resolvePath(ref, typ, 1)
+		}
+	}
+
+	for _, param := range params {
+		if param.Name == root {
+			return m.resolvePath(ref, param.Type, 1)
+		}
+	}
+	if len(params) == 1 {
+		return m.resolvePath(ref, params[0].Type, 0)
+	}
+
+	names := make([]string, 0, len(params))
+	for _, param := range params {
+		names = append(names, param.Name)
+	}
+	return nil, m.referenceError(ref, "'"+root+"' isnot exists in the arguments("+strings.Join(names, ", ")+")")
+}
+
+func (m *Method) resolvePath(ref gobatis.ParamReference, typ types.Type, start int) (types.Type, error) {
+	for _, segment := range ref.Path[start:] {
+		typ = derefType(typ)
+		if _, ok := typ.Underlying().(*types.Interface); ok {
+			// 运行时才知道它的类型
+			return nil, nil
+		}
+
+		if strings.HasPrefix(segment, "[") {
+			switch u := typ.Underlying().(type) {
+			case *types.Map:
+				typ = u.Elem()
+				continue
+			case *types.Slice:
+				if !strings.HasPrefix(segment, "['") {
+					typ = u.Elem()
+					continue
+				}
+			case *types.Array:
+				if !strings.HasPrefix(segment, "['") {
+					typ = u.Elem()
+					continue
+				}
+			}
+			return nil, m.referenceError(ref, "'"+typeString(typ)+"' isnot slice, array or map")
+		}
+
+		if u, ok := typ.Underlying().(*types.Map); ok {
+			typ = u.Elem()
+			continue
+		}
+		if u, ok := typ.Underlying().(*types.Struct); ok {
+			if field := findStructField(u, segment, m.tagName()); field != nil {
+				typ = field
+				continue
+			}
+		}
+		if result := findNiladicMethod(typ, segment); result != nil {
+			typ = result
+			continue
+		}
+		return nil, m.referenceError(ref, "'"+segment+"' isnot exists in the "+typeString(typ))
+	}
+	return typ, nil
+}
+
+func (m *Method) referenceError(ref gobatis.ParamReference, msg string) error {
+	var text string
+	switch ref.Kind {
+	case gobatis.ReferencePrint:
+		text = "<print value=\"" + ref.Name + "\">"
+	case gobatis.ReferenceCollection:
+		text = "<foreach collection=\"" + ref.Name + "\">"
+	case gobatis.ReferenceTest:
+		text = "test '" + ref.Name + "'"
+	case gobatis.ReferencePage:
+		text = "<page> '" + ref.Name + "'"
+	case gobatis.ReferenceCriteria:
+		text = "<criteria value=\"" + ref.Name + "\">"
+	default:
+		text = "#{" + ref.Name + "}"
+	}
+	return errors.New(text + " is invalid, " + msg)
+}
+
+func isContextType(typ types.Type) bool {
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	named, ok := typ.(*types.Named)
+	return ok && named.Obj().Name() == "Context"
+}
+
+func derefType(typ types.Type) types.Type {
+	for {
+		ptr, ok := typ.(*types.Pointer)
+		if !ok {
+			return typ
+		}
+		typ = ptr.Elem()
+	}
+}
+
+func elemType(typ types.Type) types.Type {
+	if typ == nil {
+		return nil
+	}
+	switch u := derefType(typ).Underlying().(type) {
+	case *types.Slice:
+		return u.Elem()
+	case *types.Array:
+		return u.Elem()
+	case *types.Map:
+		return u.Elem()
+	}
+	return nil
+}
+
+func typeString(typ types.Type) string {
+	return types.TypeString(typ, func(pkg *types.Package) string {
+		return pkg.Name()
+	})
+}
+
+func (m *Method) tagName() string {
+	if m.Itf == nil || m.Itf.File == nil {
+		return ""
+	}
+	return m.Itf.File.TagName
+}
+
+// ColumnName 返回字段在 tag 中的列名，tag 中没有列名时返回空字符串，字段被忽略(列名为 -)时 ok 为 false。
+// tagName 为空时是 db，为 xorm 时按 xorm 的格式(见 gobatis.TagSplitForXORM)解析，其它的和 db 的格式一样
+func ColumnName(tag reflect.StructTag, tagName, fieldName string) (name string, ok bool) {
+	if tagName == "" {
+		tagName = "db"
+	}
+	value := tag.Get(tagName)
+	if tagName != "xorm" {
+		name = strings.Split(value, ",")[0]
+		if name == "-" {
+			return "", false
+		}
+		return name, true
+	}
+
+	if strings.TrimSpace(value) == "-" {
+		return "", false
+	}
+	parts := gobatis.TagSplitForXORM(value, fieldName)
+	if len(parts) == 0 || parts[0] == fieldName {
+		return "", true
+	}
+	return strings.Trim(parts[0], "'`"), true
+}
+
+// findStructField 按字段名、tag 中的列名或小写的字段名查找字段，匿名字段中的字段也会被查找
+func findStructField(st *types.Struct, name, tagName string) types.Type {
+	for i := 0; i < st.NumFields(); i++ {
+		field := st.Field(i)
+		column, ok := ColumnName(reflect.StructTag(st.Tag(i)), tagName, field.Name())
+		if !ok {
+			continue
+		}
+		if field.Name() == name || column == name ||
+			(column == "" && strings.ToLower(field.Name()) == name) {
+			return field.Type()
+		}
+	}
+
+	for i := 0; i < st.NumFields(); i++ {
+		field := st.Field(i)
+		if !field.Anonymous() {
+			continue
+		}
+		if embedded, ok := derefType(field.Type()).Underlying().(*types.Struct); ok {
+			if typ := findStructField(embedded, name, tagName); typ != nil {
+				return typ
+			}
+		}
+	}
+	return nil
+}
+
+// findNiladicMethod 查找没有参数并且返回一个值(或一个值和 error)的方法
+func findNiladicMethod(typ types.Type, name string) types.Type {
+	obj, _, _ := types.LookupFieldOrMethod(types.NewPointer(typ), true, nil, name)
+	fn, ok := obj.(*types.Func)
+	if !ok {
+		return nil
+	}
+	sig := fn.Type().(*types.Signature)
+	if sig.Params().Len() != 0 {
+		return nil
+	}
+	switch sig.Results().Len() {
+	case 1:
+		return sig.Results().At(0).Type()
+	case 2:
+		if sig.Results().At(1).Type().String() == "error" {
+			return sig.Results().At(0).Type()
+		}
+	}
+	return nil
+}
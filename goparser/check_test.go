@@ -0,0 +1,179 @@
+package goparser
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"reflect"
+	"testing"
+)
+
+const checkText = `package check
+
+type Context interface{}
+
+type Address struct {
+	City string
+}
+
+type Base struct {
+	ID int64 ` + "`db:\"id\"`" + `
+}
+
+type User struct {
+	Base
+	Name     string ` + "`db:\"username\"`" + `
+	Nickname string
+	Address  *Address
+	Tags     []string
+	Attrs    map[string]interface{}
+	Extra    interface{}
+}
+
+func (u *User) FullName() string { return u.Name }
+
+type Dao interface {
+	Update(ctx Context, u *User) error
+	UpdateName(id int64, name string) error
+	UpdateAll(users []User) error
+}
+`
+
+func TestCheckStatement(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "check.go", checkText, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg, err := (&types.Config{}).Check("check", fset, []*ast.File{f}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dao := pkg.Scope().Lookup("Dao").Type().Underlying().(*types.Interface)
+	methods := map[string]*Method{}
+	for i := 0; i < dao.NumMethods(); i++ {
+		fn := dao.Method(i)
+		sig := fn.Type().(*types.Signature)
+		m := &Method{Name: fn.Name()}
+		m.Params = NewParams(m, sig.Params(), sig.Variadic())
+		methods[fn.Name()] = m
+	}
+
+	for _, test := range []struct {
+		method   string
+		sql      string
+		excepted string
+	}{
+		{method: "Update", sql: "update users set username=#{u.username}, nickname=#{u.nickname} where id=#{u.id}"},
+		{method: "Update", sql: "update users set username=#{username}, city=#{Address.City}, tag=#{u.Tags[0]} where id=#{ID}"},
+		{method: "Update", sql: "update users set a=#{u.Attrs['a'].b}, b=#{u.Extra.x}, c=#{u.FullName} where id=#{u.id}"},
+		{method: "Update", sql: `update users <set><if test="isNotEmpty(u.username)">username=#{u.username}</if></set> where id=#{u.id}`},
+		{method: "UpdateName", sql: "update users set username=#{name} where id=#{id}"},
+		{method: "UpdateAll", sql: `<foreach collection="users" item="user" index="idx" separator=";">update users set username=#{user.username}, sort=#{idx} where id=#{user.id}</foreach>`},
+		{method: "UpdateAll", sql: `<foreach collection="users" item="user" separator=";"><foreach collection="user.Tags" item="tag">#{tag}</foreach></foreach>`},
+
+		{method: "Update", sql: "update users set username=#{u.usernme} where id=#{u.id}",
+			excepted: "#{u.usernme} is invalid, 'usernme' isnot exists in the check.User"},
+		{method: "Update", sql: "update users set city=#{u.Address.Cty} where id=#{u.id}",
+			excepted: "#{u.Address.Cty} is invalid, 'Cty' isnot exists in the check.Address"},
+		{method: "Update", sql: "update users set tag=#{u.Tags.a} where id=#{u.id}",
+			excepted: "#{u.Tags.a} is invalid, 'a' isnot exists in the []string"},
+		{method: "Update", sql: "update users set name=#{u.Name[0]} where id=#{u.id}",
+			excepted: "#{u.Name[0]} is invalid, 'string' isnot slice, array or map"},
+		{method: "Update", sql: `update users <set><if test="isNotEmpty(u.nicknam)">nickname=#{u.nickname}</if></set> where id=#{u.id}`,
+			excepted: "test 'u.nicknam' is invalid, 'nicknam' isnot exists in the check.User"},
+		{method: "UpdateName", sql: "update users set username=#{nam} where id=#{id}",
+			excepted: "#{nam} is invalid, 'nam' isnot exists in the arguments(id, name)"},
+		{method: "UpdateName", sql: `update users set username=<print value="nam" /> where id=#{id}`,
+			excepted: `<print value="nam"> is invalid, 'nam' isnot exists in the arguments(id, name)`},
+		{method: "UpdateAll", sql: `<foreach collection="user" item="user">#{user.id}</foreach>`,
+			excepted: `<foreach collection="user"> is invalid, 'user' isnot exists in the []check.User`},
+		{method: "UpdateAll", sql: `<foreach collection="users" item="user">#{user.nam}</foreach>`,
+			excepted: "#{user.nam} is invalid, 'nam' isnot exists in the check.User"},
+	} {
+		err := methods[test.method].CheckStatement(test.sql)
+		if test.excepted == "" {
+			if err != nil {
+				t.Error(test.sql)
+				t.Error(err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Error(test.sql)
+			t.Error("want error got ok")
+		} else if err.Error() != test.excepted {
+			t.Error(test.sql)
+			t.Error("excepted is", test.excepted)
+			t.Error("actual   is", err)
+		}
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	for _, test := range []struct {
+		tag      string
+		tagName  string
+		excepted string
+		ok       bool
+	}{
+		{tag: `db:"user_name,notnull"`, excepted: "user_name", ok: true},
+		{tag: `db:"-"`, ok: false},
+		{tag: `json:"name"`, ok: true},
+		{tag: `xorm:"'user_name' notnull"`, tagName: "xorm", excepted: "user_name", ok: true},
+		{tag: `xorm:"notnull user_name"`, tagName: "xorm", excepted: "user_name", ok: true},
+		{tag: `xorm:"pk autoincr"`, tagName: "xorm", ok: true},
+		{tag: `xorm:"-"`, tagName: "xorm", ok: false},
+		{tag: `json:"user_name"`, tagName: "json", excepted: "user_name", ok: true},
+	} {
+		name, ok := ColumnName(reflect.StructTag(test.tag), test.tagName, "Name")
+		if name != test.excepted || ok != test.ok {
+			t.Error(test.tag, "excepted is", test.excepted, test.ok)
+			t.Error(test.tag, "actual   is", name, ok)
+		}
+	}
+}
+
+func TestCheckStatementWithTag(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "check.go", `package check
+
+type User struct {
+	ID   int64  `+"`xorm:\"pk autoincr 'id'\"`"+`
+	Name string `+"`xorm:\"'user_name' notnull\"`"+`
+}
+
+type Dao interface {
+	Update(u *User) error
+}
+`, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg, err := (&types.Config{}).Check("check", fset, []*ast.File{f}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fn := pkg.Scope().Lookup("Dao").Type().Underlying().(*types.Interface).Method(0)
+	sig := fn.Type().(*types.Signature)
+	m := &Method{Itf: &Interface{File: &File{TagName: "xorm"}}, Name: fn.Name()}
+	m.Params = NewParams(m, sig.Params(), sig.Variadic())
+
+	if err := m.CheckStatement("update users set user_name=#{u.user_name} where id=#{u.id}"); err != nil {
+		t.Error(err)
+	}
+	err = m.CheckStatement("update users set user_name=#{u.username} where id=#{u.id}")
+	if excepted := "#{u.username} is invalid, 'username' isnot exists in the check.User"; err == nil || err.Error() != excepted {
+		t.Error("excepted is", excepted)
+		t.Error("actual   is", err)
+	}
+
+	// 按 db tag 检查时找不到 user_name
+	m.Itf.File.TagName = ""
+	if err := m.CheckStatement("update users set user_name=#{u.user_name} where id=#{u.id}"); err == nil {
+		t.Error("excepted error got ok")
+	}
+}
@@ -195,6 +195,14 @@ func (itf *Interface) detectRecordType(method *Method, guess bool) types.Type {
 				if fuzzyType == nil || types.Identical(resultType, fuzzyType) {
 					return resultType
 				}
+				// 如 SumAmount() (sql.NullFloat64, error)，返回值不是记录
+				if IsIgnoreStructTypes(resultType) {
+					return fuzzyType
+				}
+				// 如 StatsByDept() ([]DeptStat, error)，返回值是统计结果
+				if _, ok := gobatis.SplitStatsMethod(method.Name); ok {
+					return fuzzyType
+				}
 			}
 		}
 		return nil
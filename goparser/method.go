@@ -2,6 +2,7 @@ package goparser
 
 import (
 	"errors"
+	"go/types"
 	"strings"
 
 	gobatis "github.com/runner-mei/GoBatis"
@@ -35,10 +36,37 @@ func NewMethod(itf *Interface, pos int, name string, comments []string) (*Method
 			}
 		}
 	}
+	if returning, ok := cfg.Options["returning"]; ok && returning != "all" {
+		return nil, errors.New("method '" + m.Name + "' error : option returning must is 'all', actual is '" + returning + "'")
+	}
 	m.Config = cfg
 	return m, nil
 }
 
+// ReturningParam 返回有 @option returning all 时接收返回的整行的参数，它是 insert 或 update 方法的
+// 最后一个参数且是结构的指针，方法只返回 error，不满足时返回 nil
+func (m *Method) ReturningParam() *Param {
+	if m.Config == nil || m.Config.Options["returning"] != "all" {
+		return nil
+	}
+	if m.StatementType() != gobatis.StatementTypeInsert && m.StatementType() != gobatis.StatementTypeUpdate {
+		return nil
+	}
+	if m.Params == nil || len(m.Params.List) == 0 || m.Results == nil || m.Results.Len() != 1 {
+		return nil
+	}
+
+	param := &m.Params.List[len(m.Params.List)-1]
+	ptr, ok := param.Type.(*types.Pointer)
+	if !ok {
+		return nil
+	}
+	if _, ok := ptr.Elem().Underlying().(*types.Struct); !ok {
+		return nil
+	}
+	return param
+}
+
 func (m *Method) MethodSignature(ctx *PrintContext) string {
 	var sb strings.Builder
 	m.Print(ctx, false, &sb)
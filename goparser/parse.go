@@ -22,6 +22,8 @@ type File struct {
 	Imports    []string
 	ImportAlas map[string]string // database/sql => sql
 	Interfaces []*Interface
+	// TagName 是结构中列名所在的 tag，为空时是 db，见 ColumnName
+	TagName string
 }
 
 func Parse(filename string) (*File, error) {
@@ -137,13 +139,11 @@ func logErrorf(pos token.Pos, name string, fmtStr string, args ...interface{}) {
 
 func parseTypes(store *File, currentAST *ast.File, files []*ast.File, fset *token.FileSet, importer types.Importer) ([]*Interface, error) {
 	info := types.Info{Defs: make(map[*ast.Ident]types.Object)}
-	conf := types.Config{Importer: importer}
-	_, err := conf.Check(store.Package, fset, files, &info)
-	if err != nil {
-		logPrint(err)
-		//return nil, errors.New(err.Error())
-	}
+	// 出错时继续检查，这样未定义的类型(如还没有生成的 UserCriteria)不会影响其它的类型
+	conf := types.Config{Importer: importer, Error: logPrint}
+	pkg, _ := conf.Check(store.Package, fset, files, &info)
 
+	undefinedTypes := map[string]*types.Named{}
 	var ifList []*Interface
 	for k, obj := range info.Defs {
 		if k.Obj == nil {
@@ -209,6 +209,7 @@ func parseTypes(store *File, currentAST *ast.File, files []*ast.File, fset *toke
 			}
 			y := x.Type().(*types.Signature)
 			m.Params = NewParams(m, y.Params(), y.Variadic())
+			replaceUndefinedTypes(pkg, undefinedTypes, astMethod, m.Params)
 			m.Results = NewResults(m, y.Results())
 			itf.Methods = append(itf.Methods, m)
 		}
@@ -228,6 +229,58 @@ func parseTypes(store *File, currentAST *ast.File, files []*ast.File, fset *toke
 	return ifList, nil
 }
 
+// replaceUndefinedTypes 用一个空结构代替参数中未定义的类型，这些类型可能是由 gobatis 生成的(如 UserCriteria)，
+// 第一次生成代码时它们还不存在
+func replaceUndefinedTypes(pkg *types.Package, undefinedTypes map[string]*types.Named, astMethod *ast.Field, params *Params) {
+	if pkg == nil || astMethod == nil {
+		return
+	}
+	fn, ok := astMethod.Type.(*ast.FuncType)
+	if !ok || fn.Params == nil {
+		return
+	}
+
+	idx := 0
+	for _, field := range fn.Params.List {
+		count := len(field.Names)
+		if count == 0 {
+			count = 1
+		}
+		for i := 0; i < count; i, idx = i+1, idx+1 {
+			if idx >= len(params.List) || !isInvalidType(params.List[idx].Type) {
+				continue
+			}
+
+			expr, isPointer := field.Type, false
+			if star, ok := expr.(*ast.StarExpr); ok {
+				expr, isPointer = star.X, true
+			}
+			ident, ok := expr.(*ast.Ident)
+			if !ok {
+				continue
+			}
+			named := undefinedTypes[ident.Name]
+			if named == nil {
+				named = types.NewNamed(types.NewTypeName(ident.Pos(), pkg, ident.Name, nil), types.NewStruct(nil, nil), nil)
+				undefinedTypes[ident.Name] = named
+			}
+			if isPointer {
+				params.List[idx].Type = types.NewPointer(named)
+			} else {
+				params.List[idx].Type = named
+			}
+		}
+	}
+}
+
+func isInvalidType(typ types.Type) bool {
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	basic, ok := typ.(*types.Basic)
+	return ok && basic.Kind() == types.Invalid
+}
+
 func findMethodByName(ift *ast.InterfaceType, name string) *ast.Field {
 	if ift == nil {
 		return nil
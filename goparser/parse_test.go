@@ -3,6 +3,10 @@ package goparser
 import (
 	"bufio"
 	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -378,3 +382,45 @@ func TestParseCommentsFail(t *testing.T) {
 		t.Log(err)
 	}
 }
+
+func TestParseUndefinedTypes(t *testing.T) {
+	const text = `package undefined
+
+type User struct {
+	ID int64
+}
+
+type Users interface {
+	ListByCriteria(c *UserCriteria, offset, limit int) ([]User, error)
+	CountByCriteria(c *UserCriteria) (int64, error)
+}
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "undefined.go", text, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := parse(fset, nil, []*ast.File{f}, "undefined.go", f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(store.Interfaces) != 1 {
+		t.Fatal("excepted is 1 interface, actual is", len(store.Interfaces))
+	}
+
+	var criteria types.Type
+	for _, m := range store.Interfaces[0].Methods {
+		typ := m.Params.List[0].Type.String()
+		if typ != "*undefined.UserCriteria" {
+			t.Error(m.Name, "excepted is *undefined.UserCriteria")
+			t.Error(m.Name, "actual   is", typ)
+		}
+		if criteria != nil && !types.Identical(criteria, m.Params.List[0].Type) {
+			t.Error(m.Name, "UserCriteria isnot same")
+		}
+		criteria = m.Params.List[0].Type
+	}
+	if typ := store.Interfaces[0].Methods[0].Params.List[1].Type.String(); typ != "int" {
+		t.Error("excepted is int, actual is", typ)
+	}
+}
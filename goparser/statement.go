@@ -57,5 +57,11 @@ func isSelectStatement(name string) bool {
 		"read",
 		"statby",
 		"statsby",
+		"sum",
+		"max",
+		"min",
+		"avg",
+		"exists",
+		"distinct",
 	}, []string{"count"}, []string{"id", "all", "names", "titles"})
 }
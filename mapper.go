@@ -49,9 +49,18 @@ func (s *StructMap) primaryKey() [][]int {
 }
 
 type Mapper struct {
-	mapper *reflectx.Mapper
-	cache  atomic.Value
-	mutex  sync.Mutex
+	mapper       *reflectx.Mapper
+	typeHandlers *TypeHandlers
+	cache        atomic.Value
+	mutex        sync.Mutex
+
+	// quoteMode 是生成的语句中给表名和列名加引号的方式
+	quoteMode QuoteMode
+
+	// paths 和 plans 缓存属性路径和它们在各个类型上的求值步骤
+	paths     atomic.Value
+	plans     atomic.Value
+	pathMutex sync.Mutex
 }
 
 func (m *Mapper) getCache() map[reflect.Type]*StructMap {
@@ -115,13 +124,13 @@ func (m *Mapper) TypeMap(t reflect.Type) *StructMap {
 		cache = map[reflect.Type]*StructMap{}
 	}
 
-	mapping := getMapping(m.mapper, t)
+	mapping := getMapping(m.mapper, m.typeHandlers, t)
 	cache[t] = mapping
 	m.cache.Store(cache)
 	return mapping
 }
 
-func getMapping(mapper *reflectx.Mapper, t reflect.Type) *StructMap {
+func getMapping(mapper *reflectx.Mapper, typeHandlers *TypeHandlers, t reflect.Type) *StructMap {
 	mapping := mapper.TypeMap(t)
 	info := &StructMap{
 		Inner:      mapping,
@@ -130,7 +139,7 @@ func getMapping(mapper *reflectx.Mapper, t reflect.Type) *StructMap {
 		FieldNames: map[string]*FieldInfo{},
 	}
 	for idx := range mapping.Index {
-		info.Index = append(info.Index, getFeildInfo(mapping.Index[idx]))
+		info.Index = append(info.Index, getFeildInfo(mapping.Index[idx], typeHandlers))
 	}
 
 	find := func(field *reflectx.FieldInfo) *FieldInfo {
@@ -1034,12 +1043,15 @@ var _macType = reflect.TypeOf((*net.HardwareAddr)(nil)).Elem()
 var _macPtr = reflect.TypeOf((*net.HardwareAddr)(nil))
 var _macPtrPtr = reflect.TypeOf((**net.HardwareAddr)(nil))
 
-func getFeildInfo(field *reflectx.FieldInfo) *FieldInfo {
+func getFeildInfo(field *reflectx.FieldInfo, typeHandlers *TypeHandlers) *FieldInfo {
 	info := &FieldInfo{
 		FieldInfo: field,
 	}
 	info.LValue = info.makeLValue()
 	info.RValue = info.makeRValue()
+	if typeHandlers != nil {
+		typeHandlers.bindField(info)
+	}
 	return info
 }
 
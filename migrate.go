@@ -0,0 +1,366 @@
+package gobatis
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Migration 是一个版本的迁移脚本，脚本的文件名为 NNN_name.up.sql 和 NNN_name.down.sql
+type Migration struct {
+	Version  int64
+	Name     string
+	UpFile   string
+	DownFile string
+}
+
+// MigrationStatus 是一个迁移脚本的执行状态
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Migrator 按版本执行 Dir 目录中的迁移脚本，执行过的版本记录在 Table 表中。
+//
+// 脚本按 splitSQLStatements 的规则拆分成多个语句执行，可以用 -- +gobatis StatementBegin 和
+// -- +gobatis StatementEnd 包含有分号的语句，数据库支持在事务中执行 DDL 时(如 postgres 和 mssql)，
+// 每个脚本在一个事务中执行，脚本中有 -- +gobatis NoTransaction 时不使用事务
+type Migrator struct {
+	DB      *sql.DB
+	Dialect Dialect
+	// FS 不为 nil 时从它中读取脚本(如 embed.FS)
+	FS     fs.FS
+	Dir    string
+	Table  string
+	Logger *log.Logger
+}
+
+const defaultMigrationTable = "gobatis_migrations"
+
+const sqlNoTransaction = sqlCmdPrefix + "NoTransaction"
+
+func NewMigrator(db *sql.DB, dialect Dialect, fsys fs.FS, dir string) *Migrator {
+	return &Migrator{
+		DB:      db,
+		Dialect: dialect,
+		FS:      fsys,
+		Dir:     dir,
+		Table:   defaultMigrationTable,
+		Logger:  log.New(os.Stdout, "[gobatis] ", log.Flags()),
+	}
+}
+
+var migrationFile = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// Migrations 返回按版本排序的所有迁移脚本
+func (m *Migrator) Migrations() ([]Migration, error) {
+	entries, err := readXMLDir(m.FS, m.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		ss := migrationFile.FindStringSubmatch(entry.Name())
+		if ss == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(ss[1], 10, 64)
+		if err != nil {
+			return nil, errors.New("version of migration '" + entry.Name() + "' is invalid, " + err.Error())
+		}
+
+		migration := byVersion[version]
+		if migration == nil {
+			migration = &Migration{Version: version, Name: ss[2]}
+			byVersion[version] = migration
+		} else if migration.Name != ss[2] {
+			return nil, errors.New("version " + ss[1] + " of migration is duplicated - '" + migration.Name + "' and '" + ss[2] + "'")
+		}
+
+		filename := joinXMLPath(m.FS, m.Dir, entry.Name())
+		if ss[3] == "up" {
+			migration.UpFile = filename
+		} else {
+			migration.DownFile = filename
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.UpFile == "" {
+			return nil, errors.New("up script of migration '" + strconv.FormatInt(migration.Version, 10) + "_" + migration.Name + "' is missing")
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+func (m *Migrator) table() string {
+	if m.Table == "" {
+		return defaultMigrationTable
+	}
+	return m.Table
+}
+
+func (m *Migrator) logf(format string, args ...interface{}) {
+	if m.Logger != nil {
+		m.Logger.Printf(format, args...)
+	}
+}
+
+func (m *Migrator) sql(sqlStr string) string {
+	s, err := m.Dialect.Placeholder().ReplacePlaceholders(sqlStr)
+	if err != nil {
+		return sqlStr
+	}
+	return s
+}
+
+func (m *Migrator) ensureTable(ctx context.Context) error {
+	table := m.table()
+	// 没有 SchemaDialect 的数据库(如 oracle)中不能用标准的写法建表，在执行任何语句之前返回错误
+	schema := m.Dialect.Schema()
+	if schema == nil || schema.Types == nil {
+		return errors.New("migrations for '" + m.Dialect.Name() + "' is unsupported")
+	}
+	types := schema.Types
+	sqlStr := schema.createTableIfNotExistsSQL(table, "(version "+types.Bigint+" NOT NULL PRIMARY KEY, name "+
+		types.Varchar+", applied_at "+types.Timestamp+")")
+	_, err := m.DB.ExecContext(ctx, sqlStr)
+	if err != nil {
+		return errors.New("create table '" + table + "' fail, " + err.Error())
+	}
+	return nil
+}
+
+// applied 返回已执行的版本和它们的执行时间
+func (m *Migrator) applied(ctx context.Context) (map[int64]time.Time, error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+
+	rows, err := m.DB.QueryContext(ctx, "SELECT version, applied_at FROM "+m.table())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := map[int64]time.Time{}
+	for rows.Next() {
+		var version int64
+		var appliedAt interface{}
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		versions[version] = toMigrationTime(appliedAt)
+	}
+	return versions, rows.Err()
+}
+
+func toMigrationTime(value interface{}) time.Time {
+	switch v := value.(type) {
+	case time.Time:
+		return v
+	case []byte:
+		return toMigrationTime(string(v))
+	case string:
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999Z07:00", "2006-01-02 15:04:05.999999999"} {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// Status 返回所有迁移脚本的执行状态
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	migrations, err := m.Migrations()
+	if err != nil {
+		return nil, err
+	}
+	versions, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]MigrationStatus, 0, len(migrations))
+	for _, migration := range migrations {
+		appliedAt, ok := versions[migration.Version]
+		results = append(results, MigrationStatus{Migration: migration, Applied: ok, AppliedAt: appliedAt})
+	}
+	return results, nil
+}
+
+// Version 返回已执行的最大的版本，没有执行过任何版本时返回 0
+func (m *Migrator) Version(ctx context.Context) (int64, error) {
+	versions, err := m.applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var max int64
+	for version := range versions {
+		if version > max {
+			max = version
+		}
+	}
+	return max, nil
+}
+
+// Up 按版本顺序执行所有未执行的脚本
+func (m *Migrator) Up(ctx context.Context) error {
+	status, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	for _, s := range status {
+		if s.Applied {
+			continue
+		}
+		if err := m.run(ctx, s.Migration, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Down 回滚最后执行的一个版本
+func (m *Migrator) Down(ctx context.Context) error {
+	migration, err := m.last(ctx)
+	if err != nil {
+		return err
+	}
+	return m.run(ctx, migration, false)
+}
+
+// Redo 回滚最后执行的一个版本，然后再执行它
+func (m *Migrator) Redo(ctx context.Context) error {
+	migration, err := m.last(ctx)
+	if err != nil {
+		return err
+	}
+	if err := m.run(ctx, migration, false); err != nil {
+		return err
+	}
+	return m.run(ctx, migration, true)
+}
+
+func (m *Migrator) last(ctx context.Context) (Migration, error) {
+	status, err := m.Status(ctx)
+	if err != nil {
+		return Migration{}, err
+	}
+	for idx := len(status) - 1; idx >= 0; idx-- {
+		if status[idx].Applied {
+			return status[idx].Migration, nil
+		}
+	}
+	return Migration{}, errors.New("no migration has been applied")
+}
+
+func (m *Migrator) readScript(filename string) (string, error) {
+	f, err := openXMLFile(m.FS, filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	bs, err := io.ReadAll(f)
+	if err != nil {
+		return "", err
+	}
+	return string(bs), nil
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func (m *Migrator) run(ctx context.Context, migration Migration, up bool) error {
+	filename := migration.UpFile
+	direction := "up"
+	if !up {
+		filename = migration.DownFile
+		direction = "down"
+	}
+	id := strconv.FormatInt(migration.Version, 10) + "_" + migration.Name
+	if filename == "" {
+		return errors.New("down script of migration '" + id + "' is missing")
+	}
+
+	script, err := m.readScript(filename)
+	if err != nil {
+		return err
+	}
+
+	useTx := supportsTransactionalDDL(m.Dialect) && !strings.Contains(script, sqlNoTransaction)
+
+	var tx *sql.Tx
+	var db execer = m.DB
+	if useTx {
+		tx, err = m.DB.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		db = tx
+	}
+
+	err = m.exec(ctx, db, migration, script, up)
+	if err != nil {
+		if tx != nil {
+			tx.Rollback()
+		}
+		return errors.New("migrate " + direction + " '" + id + "' fail, " + err.Error())
+	}
+	if tx != nil {
+		if err := tx.Commit(); err != nil {
+			return errors.New("migrate " + direction + " '" + id + "' fail, " + err.Error())
+		}
+	}
+	m.logf("migrate %s - %s", direction, id)
+	return nil
+}
+
+func (m *Migrator) exec(ctx context.Context, db execer, migration Migration, script string, up bool) error {
+	sqlList, lines := splitSQLStatementsWithLines(strings.NewReader(script))
+	for idx, sqlStr := range sqlList {
+		if strings.TrimSpace(sqlStr) == "" {
+			continue
+		}
+		if _, err := db.ExecContext(ctx, sqlStr); err != nil {
+			// 除第一个外，语句的前面会多一个换行
+			line := lines[idx] + strings.Count(sqlStr[:len(sqlStr)-len(strings.TrimLeft(sqlStr, "\r\n"))], "\n")
+			return errors.New("line " + strconv.Itoa(line) + ": " + err.Error())
+		}
+	}
+
+	if up {
+		_, err := db.ExecContext(ctx, m.sql("INSERT INTO "+m.table()+"(version, name, applied_at) VALUES(?, ?, ?)"),
+			migration.Version, migration.Name, time.Now())
+		return err
+	}
+	_, err := db.ExecContext(ctx, m.sql("DELETE FROM "+m.table()+" WHERE version = ?"), migration.Version)
+	return err
+}
+
+// supportsTransactionalDDL 数据库是否支持在事务中执行 DDL 语句
+func supportsTransactionalDDL(dialect Dialect) bool {
+	return schemaOf(dialect).TransactionalDDL
+}
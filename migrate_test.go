@@ -0,0 +1,188 @@
+package gobatis
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+var migrateDriver = newFakeDriver("gobatis_migrate")
+
+func TestMigrator(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/001_create_users.up.sql":   {Data: []byte("CREATE TABLE users(id int);\r\nCREATE INDEX idx_users_id ON users(id);")},
+		"migrations/001_create_users.down.sql": {Data: []byte("DROP TABLE users;")},
+		"migrations/002_add_name.up.sql": {Data: []byte(`-- +gobatis StatementBegin
+CREATE FUNCTION f() RETURNS int AS $$ BEGIN RETURN 1; END; $$ LANGUAGE plpgsql;
+-- +gobatis StatementEnd
+ALTER TABLE users ADD name varchar(64);`)},
+		"migrations/002_add_name.down.sql": {Data: []byte("ALTER TABLE users DROP name;")},
+		"migrations/README.md":             {Data: []byte("readme")},
+	}
+
+	// 记录执行的语句，并在内存中保存版本表
+	versions := map[int64]time.Time{}
+	migrateDriver.recordTx = true
+	migrateDriver.exec = func(name, query string, args []driver.Value) (driver.Result, error) {
+		query = strings.TrimSpace(query)
+		switch {
+		case strings.HasPrefix(query, "CREATE TABLE IF NOT EXISTS gobatis_migrations"):
+			return driver.RowsAffected(0), nil
+		case strings.HasPrefix(query, "INSERT INTO gobatis_migrations"):
+			versions[args[0].(int64)] = args[2].(time.Time)
+			migrateDriver.record("", "INSERT "+args[1].(string))
+			return driver.RowsAffected(1), nil
+		case strings.HasPrefix(query, "DELETE FROM gobatis_migrations"):
+			delete(versions, args[0].(int64))
+			migrateDriver.record("", "DELETE")
+			return driver.RowsAffected(1), nil
+		}
+		migrateDriver.record("", query)
+		if strings.Contains(query, "fail") {
+			return nil, errors.New("syntax error")
+		}
+		return driver.RowsAffected(0), nil
+	}
+	migrateDriver.query = func(name, query string, args []driver.Value) (driver.Rows, error) {
+		var applied []int64
+		for version := range versions {
+			applied = append(applied, version)
+		}
+		sort.Slice(applied, func(i, j int) bool { return applied[i] < applied[j] })
+
+		rows := &fakeRows{columns: []string{"version", "applied_at"}}
+		for _, version := range applied {
+			rows.values = append(rows.values, []driver.Value{version, versions[version]})
+		}
+		return rows, nil
+	}
+	migrateDriver.reset()
+
+	db, err := sql.Open("gobatis_migrate", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	migrator := NewMigrator(db, DbTypePostgres, fsys, "migrations")
+	migrator.Logger = nil
+
+	migrations, err := migrator.Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 2 || migrations[0].Name != "create_users" || migrations[1].Version != 2 ||
+		migrations[1].DownFile != "migrations/002_add_name.down.sql" {
+		t.Error(migrations)
+	}
+
+	if err := migrator.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	excepted := []string{
+		"BEGIN",
+		"CREATE TABLE users(id int);",
+		"CREATE INDEX idx_users_id ON users(id);",
+		"INSERT create_users",
+		"COMMIT",
+		"BEGIN",
+		"CREATE FUNCTION f() RETURNS int AS $$ BEGIN RETURN 1; END; $$ LANGUAGE plpgsql;",
+		"ALTER TABLE users ADD name varchar(64);",
+		"INSERT add_name",
+		"COMMIT",
+	}
+	if actual := migrateDriver.reset(); !reflect.DeepEqual(actual, excepted) {
+		t.Error("excepted is", excepted)
+		t.Error("actual   is", actual)
+	}
+
+	version, err := migrator.Version(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != 2 {
+		t.Error("version is", version)
+	}
+
+	migrateDriver.reset()
+	if err := migrator.Redo(ctx); err != nil {
+		t.Fatal(err)
+	}
+	excepted = []string{
+		"BEGIN",
+		"ALTER TABLE users DROP name;",
+		"DELETE",
+		"COMMIT",
+		"BEGIN",
+		"CREATE FUNCTION f() RETURNS int AS $$ BEGIN RETURN 1; END; $$ LANGUAGE plpgsql;",
+		"ALTER TABLE users ADD name varchar(64);",
+		"INSERT add_name",
+		"COMMIT",
+	}
+	if actual := migrateDriver.reset(); !reflect.DeepEqual(actual, excepted) {
+		t.Error("excepted is", excepted)
+		t.Error("actual   is", actual)
+	}
+
+	// mysql 不支持在事务中执行 DDL
+	migrator.Dialect = DbTypeMysql
+	migrateDriver.reset()
+	if err := migrator.Down(ctx); err != nil {
+		t.Fatal(err)
+	}
+	excepted = []string{"ALTER TABLE users DROP name;", "DELETE"}
+	if actual := migrateDriver.reset(); !reflect.DeepEqual(actual, excepted) {
+		t.Error("excepted is", excepted)
+		t.Error("actual   is", actual)
+	}
+
+	status, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status) != 2 || !status[0].Applied || status[0].AppliedAt.IsZero() || status[1].Applied {
+		t.Error(status)
+	}
+
+	migrator.Dialect = DbTypePostgres
+	fsys["migrations/003_fail.up.sql"] = &fstest.MapFile{Data: []byte("ALTER TABLE users ADD name varchar(64);\nSELECT fail;")}
+	migrateDriver.reset()
+	err = migrator.Up(ctx)
+	if err == nil || err.Error() != "migrate up '3_fail' fail, line 2: syntax error" {
+		t.Error(err)
+	}
+	if execs := migrateDriver.reset(); len(execs) == 0 || execs[len(execs)-1] != "ROLLBACK" {
+		t.Error(execs)
+	}
+	if _, ok := versions[3]; ok {
+		t.Error("version 3 is applied")
+	}
+
+	delete(fsys, "migrations/003_fail.up.sql")
+	fsys["migrations/003_fail.down.sql"] = &fstest.MapFile{Data: []byte("SELECT 1;")}
+	_, err = migrator.Migrations()
+	if err == nil || err.Error() != "up script of migration '3_fail' is missing" {
+		t.Error(err)
+	}
+	delete(fsys, "migrations/003_fail.down.sql")
+
+	// oracle 没有 SchemaDialect，不执行任何语句
+	migrator.Dialect = DbTypeOracle
+	migrateDriver.reset()
+	err = migrator.Up(ctx)
+	if err == nil || err.Error() != "migrations for 'oracle' is unsupported" {
+		t.Error(err)
+	}
+	if execs := migrateDriver.reset(); len(execs) != 0 {
+		t.Error(execs)
+	}
+}
@@ -196,7 +196,7 @@ func (m *Multiple) Scan(dialect Dialect, mapper *Mapper, r colScanner, isUnsafe
 	for idx := range m.columns {
 		valueIndex := m.columns[idx].position
 		if m.columns[idx].fi == nil {
-			vp := m.Returns[valueIndex]
+			vp := mapper.scanValue(dialect, m.columns[idx].columnName, m.Returns[valueIndex])
 			if _, ok := vp.(sql.Scanner); ok {
 				values[idx] = m.commits[valueIndex].estimateWith(vp)
 				continue
@@ -0,0 +1,107 @@
+package gobatis
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// oracleExec 给 RETURNING ... INTO 的输出参数赋值
+func oracleExec(query string, args []interface{}) (sql.Result, error) {
+	for _, arg := range args {
+		if named, ok := arg.(sql.NamedArg); ok {
+			if out, ok := named.Value.(sql.Out); ok {
+				*out.Dest.(*int64) = 42
+			}
+		}
+	}
+	return driver.RowsAffected(1), nil
+}
+
+type oracleUser struct {
+	TableName TableName `db:"oracle_users"`
+	ID        int64     `db:"id,pk,sequence=users_seq"`
+	Name      string    `db:"name"`
+	CreatedAt int64     `db:"created_at"`
+}
+
+func TestOracleInsert(t *testing.T) {
+	mapper := CreateMapper("", nil, nil)
+	rType := reflect.TypeOf(&oracleUser{})
+
+	sqlStr, err := GenerateInsertSQL(DbTypeOracle, mapper, rType, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	excepted := "INSERT INTO oracle_users(id, name, created_at) VALUES(users_seq.NEXTVAL, #{name}, CURRENT_TIMESTAMP) RETURNING id INTO :out"
+	if sqlStr != excepted {
+		t.Error("excepted is", excepted)
+		t.Error("actual   is", sqlStr)
+	}
+
+	sqlStr, err = GenerateInsertSQL2(DbTypePostgres, mapper, rType, []string{"name"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	excepted = "INSERT INTO oracle_users(id, name, created_at) VALUES(nextval('users_seq'), #{name}, now())"
+	if sqlStr != excepted {
+		t.Error("excepted is", excepted)
+		t.Error("actual   is", sqlStr)
+	}
+
+	if _, err = GenerateInsertSQL2(DbTypeOracle, mapper, rType, []string{"id", "name"}, false); err == nil {
+		t.Error("excepted error got ok")
+	}
+
+	callbacks := SetInit([]func(ctx *InitContext) error{
+		func(ctx *InitContext) error {
+			sqlStr, err := GenerateInsertSQL(ctx.Dialect, ctx.Mapper, rType, false)
+			if err != nil {
+				return err
+			}
+			stmt, err := NewMapppedStatement(ctx, "oracle.insert", StatementTypeInsert, ResultStruct, sqlStr)
+			if err != nil {
+				return err
+			}
+			return ctx.RegisterStatement(stmt)
+		},
+	})
+	defer SetInit(callbacks)
+
+	runner := &fakeRunner{exec: oracleExec}
+	conn, err := newConnection(&Config{DriverName: "oracle", DB: runner})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := conn.Insert(context.Background(), "oracle.insert", []string{"u"}, []interface{}{&oracleUser{Name: "abc"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 42 {
+		t.Error("excepted is 42, actual is", id)
+	}
+	excepted = "INSERT INTO oracle_users(id, name, created_at) VALUES(users_seq.NEXTVAL, :1, CURRENT_TIMESTAMP) RETURNING id INTO :out"
+	if runner.query != excepted {
+		t.Error("excepted is", excepted)
+		t.Error("actual   is", runner.query)
+	}
+	if len(runner.args) != 2 || runner.args[0] != "abc" {
+		t.Error(runner.args)
+	}
+
+	runner.exec = func(query string, args []interface{}) (sql.Result, error) {
+		return nil, errors.New(`ORA-00001: unique constraint (SCOTT.ORACLE_USERS_NAME) violated`)
+	}
+	_, err = conn.Insert(context.Background(), "oracle.insert", []string{"u"}, []interface{}{&oracleUser{Name: "abc"}})
+	e, ok := err.(*Error)
+	if !ok {
+		t.Fatal("excepted is *Error, actual is", err)
+	}
+	if len(e.Validations) != 1 || e.Validations[0].Code != "unique_value_already_exists" {
+		t.Error(e.Validations)
+	}
+}
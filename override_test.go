@@ -0,0 +1,108 @@
+package gobatis
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStatementOverride(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "gobatis_override")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	for _, name := range []string{"a", "b"} {
+		txt := `<?xml version="1.0" encoding="utf-8"?>
+<gobatis>
+  <select id="override.x">SELECT ` + name + `</select>
+</gobatis>`
+		if err := ioutil.WriteFile(filepath.Join(tmp, name+".xml"), []byte(txt), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	callbacks := SetInit([]func(ctx *InitContext) error{
+		func(ctx *InitContext) error {
+			for _, id := range []string{"override.x", "override.y"} {
+				if !ctx.ShouldGenerate(id) {
+					continue
+				}
+				stmt, err := NewMapppedStatement(ctx, id, StatementTypeSelect, ResultStruct, "SELECT generated")
+				if err != nil {
+					return err
+				}
+				if err := ctx.RegisterStatement(stmt); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+	defer SetInit(callbacks)
+
+	for _, test := range []struct {
+		policy    OverridePolicy
+		excepted  string
+		overrides []string
+		err       string
+	}{
+		{policy: OverrideXMLOverridesGenerated,
+			excepted:  "SELECT b",
+			overrides: []string{"b.xml:3 > a.xml:3", "b.xml:3 > override_test.go"}},
+		{policy: OverrideFirstWins,
+			excepted:  "SELECT a",
+			overrides: []string{"a.xml:3 > b.xml:3", "a.xml:3 > override_test.go"}},
+		{policy: OverrideLastWins,
+			excepted:  "SELECT generated",
+			overrides: []string{"b.xml:3 > a.xml:3", "override_test.go > b.xml:3"}},
+		{policy: OverrideError,
+			err: "statement 'override.x' is duplicated"},
+	} {
+		conn, err := newConnection(&Config{DriverName: "postgres",
+			DataSource:        "aa",
+			XMLPaths:          []string{tmp},
+			StatementOverride: test.policy})
+		if test.err != "" {
+			if err == nil {
+				t.Error(test.policy, "want error")
+			} else if !strings.Contains(err.Error(), test.err) {
+				t.Error(test.policy, "excepted is", test.err)
+				t.Error(test.policy, "actual   is", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Error(test.policy, err)
+			continue
+		}
+
+		stmt, _ := conn.sqlStatements.get("override.x")
+		if stmt.rawSQL != test.excepted {
+			t.Error(test.policy, "excepted is", test.excepted)
+			t.Error(test.policy, "actual   is", stmt.rawSQL)
+		}
+		if stmt, _ := conn.sqlStatements.get("override.y"); stmt == nil || !stmt.Source().Generated {
+			t.Error(test.policy, "override.y isnot generated")
+		}
+
+		overrides := conn.StatementOverrides()
+		if len(overrides) != len(test.overrides) {
+			t.Error(test.policy, "excepted is", test.overrides)
+			t.Error(test.policy, "actual   is", overrides)
+			continue
+		}
+		for idx, override := range overrides {
+			ss := strings.SplitN(test.overrides[idx], " > ", 2)
+			if override.ID != "override.x" ||
+				!strings.Contains(override.Active.String(), ss[0]) ||
+				!strings.Contains(override.Overridden.String(), ss[1]) {
+				t.Error(test.policy, "excepted is", test.overrides[idx])
+				t.Error(test.policy, "actual   is", override.Active, ">", override.Overridden)
+			}
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package gobatis
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// pageExpression 是 <page offset="offset" limit="limit" /> 元素，它用 Dialect.Paginate 给语句加上分页，
+// offset 和 limit 是参数名，参数不存在、为 nil 或小于等于 0 时表示没有这一项
+type pageExpression struct {
+	offset string
+	limit  string
+}
+
+func (expr *pageExpression) String() string {
+	var sb strings.Builder
+	sb.WriteString("<page")
+	if expr.offset != "" {
+		sb.WriteString(` offset="`)
+		sb.WriteString(expr.offset)
+		sb.WriteString(`"`)
+	}
+	if expr.limit != "" {
+		sb.WriteString(` limit="`)
+		sb.WriteString(expr.limit)
+		sb.WriteString(`"`)
+	}
+	sb.WriteString(" />")
+	return sb.String()
+}
+
+func (expr *pageExpression) writeTo(printer *sqlPrinter) {
+	offset, err := pageValue(printer.ctx, expr.offset)
+	if err != nil {
+		printer.err = err
+		return
+	}
+	limit, err := pageValue(printer.ctx, expr.limit)
+	if err != nil {
+		printer.err = err
+		return
+	}
+	if offset <= 0 && limit <= 0 {
+		return
+	}
+
+	sqlStr := strings.TrimRightFunc(printer.sb.String(), unicode.IsSpace)
+	printer.sb.Reset()
+	printer.sb.WriteString(printer.ctx.Dialect.Paginate(sqlStr, offset, limit))
+}
+
+func pageValue(ctx *Context, name string) (int64, error) {
+	if name == "" {
+		return 0, nil
+	}
+	value, err := ctx.Get(name)
+	if err != nil {
+		if err == ErrNotFound {
+			return 0, nil
+		}
+		return 0, errors.New("search '" + name + "' fail, " + err.Error())
+	}
+	if value == nil {
+		return 0, nil
+	}
+	i, ok := int64Value(value)
+	if !ok {
+		return 0, fmt.Errorf("'%s' isnot a integer, got %T", name, value)
+	}
+	return i, nil
+}
+
+// int64Value 将整数(或指向整数的指针)转换为 int64
+func int64Value(value interface{}) (int64, bool) {
+	rValue := reflect.ValueOf(value)
+	for rValue.Kind() == reflect.Ptr {
+		if rValue.IsNil() {
+			return 0, false
+		}
+		rValue = rValue.Elem()
+	}
+	switch rValue.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rValue.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(rValue.Uint()), true
+	}
+	return 0, false
+}
+
+// paginateLimitOffset 是 postgres 和 sqlite 等数据库的分页语法: LIMIT m OFFSET n
+func paginateLimitOffset(sqlStr string, offset, limit int64) string {
+	if limit > 0 {
+		sqlStr += " LIMIT " + strconv.FormatInt(limit, 10)
+	}
+	if offset > 0 {
+		sqlStr += " OFFSET " + strconv.FormatInt(offset, 10)
+	}
+	return sqlStr
+}
+
+// paginateMysql 是 mysql 的分页语法，mysql 中 OFFSET 必须跟在 LIMIT 后面，没有 limit 时用最大值代替
+func paginateMysql(sqlStr string, offset, limit int64) string {
+	if limit > 0 {
+		sqlStr += " LIMIT " + strconv.FormatInt(limit, 10)
+	} else {
+		sqlStr += " LIMIT 18446744073709551615"
+	}
+	if offset > 0 {
+		sqlStr += " OFFSET " + strconv.FormatInt(offset, 10)
+	}
+	return sqlStr
+}
+
+// paginateOffsetFetch 是 SQL:2008 的分页语法: OFFSET n ROWS FETCH NEXT m ROWS ONLY
+func paginateOffsetFetch(sqlStr string, offset, limit int64) string {
+	if offset < 0 {
+		offset = 0
+	}
+	sqlStr += " OFFSET " + strconv.FormatInt(offset, 10) + " ROWS"
+	if limit > 0 {
+		sqlStr += " FETCH NEXT " + strconv.FormatInt(limit, 10) + " ROWS ONLY"
+	}
+	return sqlStr
+}
+
+// paginateMSSql 是 mssql 的分页语法，mssql 中的 OFFSET 必须有 ORDER BY，语句中没有时加上 ORDER BY (SELECT NULL)
+func paginateMSSql(sqlStr string, offset, limit int64) string {
+	if !hasOrderBy(sqlStr) {
+		sqlStr += " ORDER BY (SELECT NULL)"
+	}
+	return paginateOffsetFetch(sqlStr, offset, limit)
+}
+
+// hasOrderBy 判断语句的最外层是否有 ORDER BY，括号和字符串中的不算
+func hasOrderBy(sqlStr string) bool {
+	depth := 0
+	for pos := 0; pos < len(sqlStr); pos++ {
+		switch c := sqlStr[pos]; c {
+		case '\'', '"':
+			end := strings.IndexByte(sqlStr[pos+1:], c)
+			if end < 0 {
+				return false
+			}
+			pos += end + 1
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case 'o', 'O':
+			if depth != 0 || (pos > 0 && isIdentChar(sqlStr[pos-1])) {
+				continue
+			}
+			if len(sqlStr) < pos+5 || !strings.EqualFold(sqlStr[pos:pos+5], "order") {
+				continue
+			}
+			rest := strings.TrimLeft(sqlStr[pos+5:], " \t\r\n")
+			if len(rest) == len(sqlStr)-pos-5 || len(rest) < 2 || !strings.EqualFold(rest[:2], "by") {
+				continue
+			}
+			if len(rest) == 2 || !isIdentChar(rest[2]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || ('0' <= c && c <= '9') || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
@@ -0,0 +1,187 @@
+package gobatis
+
+import (
+	"context"
+	"log"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestPaginate(t *testing.T) {
+	for _, test := range []struct {
+		dialect  Dialect
+		sql      string
+		offset   int64
+		limit    int64
+		excepted string
+	}{
+		{dialect: DbTypePostgres, sql: "SELECT * FROM users", offset: 20, limit: 10, excepted: "SELECT * FROM users LIMIT 10 OFFSET 20"},
+		{dialect: DbTypePostgres, sql: "SELECT * FROM users", offset: 20, excepted: "SELECT * FROM users OFFSET 20"},
+		{dialect: DbTypePostgres, sql: "SELECT * FROM users", limit: 10, excepted: "SELECT * FROM users LIMIT 10"},
+		{dialect: DbTypeNone, sql: "SELECT * FROM users", offset: 20, limit: 10, excepted: "SELECT * FROM users LIMIT 10 OFFSET 20"},
+
+		{dialect: DbTypeMysql, sql: "SELECT * FROM users", offset: 20, limit: 10, excepted: "SELECT * FROM users LIMIT 10 OFFSET 20"},
+		{dialect: DbTypeMysql, sql: "SELECT * FROM users", offset: 20, excepted: "SELECT * FROM users LIMIT 18446744073709551615 OFFSET 20"},
+		{dialect: DbTypeMysql, sql: "SELECT * FROM users", limit: 10, excepted: "SELECT * FROM users LIMIT 10"},
+
+		{dialect: DbTypeMSSql, sql: "SELECT * FROM users ORDER BY id", offset: 20, limit: 10, excepted: "SELECT * FROM users ORDER BY id OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY"},
+		{dialect: DbTypeMSSql, sql: "SELECT * FROM users", offset: 20, excepted: "SELECT * FROM users ORDER BY (SELECT NULL) OFFSET 20 ROWS"},
+		{dialect: DbTypeMSSql, sql: "SELECT * FROM users", limit: 10, excepted: "SELECT * FROM users ORDER BY (SELECT NULL) OFFSET 0 ROWS FETCH NEXT 10 ROWS ONLY"},
+		{dialect: DbTypeMSSql, sql: "SELECT *, ROW_NUMBER() OVER (ORDER BY id) FROM users WHERE name = 'order by'", limit: 10,
+			excepted: "SELECT *, ROW_NUMBER() OVER (ORDER BY id) FROM users WHERE name = 'order by' ORDER BY (SELECT NULL) OFFSET 0 ROWS FETCH NEXT 10 ROWS ONLY"},
+		{dialect: DbTypeMSSql, sql: "SELECT * FROM users order\n  by name", limit: 10, excepted: "SELECT * FROM users order\n  by name OFFSET 0 ROWS FETCH NEXT 10 ROWS ONLY"},
+		{dialect: DbTypeMSSql, sql: "SELECT border_by FROM users", limit: 10, excepted: "SELECT border_by FROM users ORDER BY (SELECT NULL) OFFSET 0 ROWS FETCH NEXT 10 ROWS ONLY"},
+
+		{dialect: DbTypeOracle, sql: "SELECT * FROM users", offset: 20, limit: 10, excepted: "SELECT * FROM users OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY"},
+		{dialect: DbTypeOracle, sql: "SELECT * FROM users", limit: 10, excepted: "SELECT * FROM users OFFSET 0 ROWS FETCH NEXT 10 ROWS ONLY"},
+	} {
+		actual := test.dialect.Paginate(test.sql, test.offset, test.limit)
+		if actual != test.excepted {
+			t.Error(test.dialect.Name(), test.sql)
+			t.Error("excepted is", test.excepted)
+			t.Error("actual   is", actual)
+		}
+	}
+}
+
+func TestPageElement(t *testing.T) {
+	for _, test := range []struct {
+		dialect     Dialect
+		sql         string
+		paramNames  []string
+		paramValues []interface{}
+		exceptedSQL string
+		params      []interface{}
+		err         string
+	}{
+		{
+			dialect:     DbTypePostgres,
+			sql:         `SELECT * FROM users WHERE name = #{name} ORDER BY id <page offset="offset" limit="limit"/>`,
+			paramNames:  []string{"name", "offset", "limit"},
+			paramValues: []interface{}{"abc", 20, 10},
+			exceptedSQL: `SELECT * FROM users WHERE name = $1 ORDER BY id LIMIT 10 OFFSET 20`,
+			params:      []interface{}{"abc"},
+		},
+		{
+			dialect:     DbTypeMysql,
+			sql:         `SELECT * FROM users WHERE name = #{name} ORDER BY id <page offset="offset" limit="limit"/>`,
+			paramNames:  []string{"name", "offset", "limit"},
+			paramValues: []interface{}{"abc", 20, 10},
+			exceptedSQL: `SELECT * FROM users WHERE name = ? ORDER BY id LIMIT 10 OFFSET 20`,
+			params:      []interface{}{"abc"},
+		},
+		{
+			dialect:     DbTypeMSSql,
+			sql:         `SELECT * FROM users WHERE name = #{name} <page offset="offset" limit="limit"/>`,
+			paramNames:  []string{"name", "offset", "limit"},
+			paramValues: []interface{}{"abc", int64(20), uint(10)},
+			exceptedSQL: `SELECT * FROM users WHERE name = ? ORDER BY (SELECT NULL) OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY`,
+			params:      []interface{}{"abc"},
+		},
+		{
+			dialect:     DbTypeOracle,
+			sql:         `SELECT * FROM users ORDER BY id <page limit="page.size"/>`,
+			paramNames:  []string{"page"},
+			paramValues: []interface{}{map[string]interface{}{"size": 10}},
+			exceptedSQL: `SELECT * FROM users ORDER BY id OFFSET 0 ROWS FETCH NEXT 10 ROWS ONLY`,
+		},
+		{
+			dialect:     DbTypePostgres,
+			sql:         `SELECT * FROM users ORDER BY id <page offset="offset" limit="limit"/>`,
+			paramNames:  []string{"offset", "limit"},
+			paramValues: []interface{}{0, nil},
+			exceptedSQL: `SELECT * FROM users ORDER BY id `,
+		},
+		{
+			dialect:     DbTypePostgres,
+			sql:         `SELECT * FROM users <page offset="offset" limit="limit"/>`,
+			paramNames:  []string{"offset", "limit"},
+			paramValues: []interface{}{"a", 10},
+			err:         "'offset' isnot a integer, got string",
+		},
+	} {
+		initCtx := &InitContext{Config: &Config{},
+			Logger:     log.New(os.Stdout, "[gobatis] ", log.Flags()),
+			Dialect:    test.dialect,
+			Mapper:     CreateMapper("", nil, nil),
+			Statements: make(map[string]*MappedStatement)}
+
+		stmt, err := NewMapppedStatement(initCtx, "page", StatementTypeSelect, ResultStruct, test.sql)
+		if err != nil {
+			t.Error(test.sql, err)
+			continue
+		}
+
+		ctx, err := NewContext(initCtx.Dialect, initCtx.Mapper, test.paramNames, test.paramValues)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+
+		sqlAndParams, err := stmt.GenerateSQLs(ctx)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Error(test.sql, "excepted error is", test.err, "actual is", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Error(test.sql, err)
+			continue
+		}
+		if sqlAndParams[0].SQL != test.exceptedSQL {
+			t.Error(test.dialect.Name(), "excepted is", test.exceptedSQL)
+			t.Error(test.dialect.Name(), "actual   is", sqlAndParams[0].SQL)
+		}
+		if len(sqlAndParams[0].Params) != 0 || len(test.params) != 0 {
+			if !reflect.DeepEqual(sqlAndParams[0].Params, test.params) {
+				t.Error("excepted is", test.params)
+				t.Error("actual   is", sqlAndParams[0].Params)
+			}
+		}
+	}
+
+	initCtx := &InitContext{Config: &Config{},
+		Logger:     log.New(os.Stdout, "[gobatis] ", log.Flags()),
+		Dialect:    DbTypePostgres,
+		Mapper:     CreateMapper("", nil, nil),
+		Statements: make(map[string]*MappedStatement)}
+	_, err := NewMapppedStatement(initCtx, "page", StatementTypeSelect, ResultStruct, `SELECT * FROM users <page />`)
+	if err == nil {
+		t.Error("excepted error got ok")
+	}
+}
+
+func TestGenerateSelectSQLWithPage(t *testing.T) {
+	initCtx := &InitContext{Config: &Config{},
+		Logger:     log.New(os.Stdout, "[gobatis] ", log.Flags()),
+		Dialect:    DbTypeMSSql,
+		Mapper:     CreateMapper("", nil, nil),
+		Statements: make(map[string]*MappedStatement)}
+
+	sqlStr, err := GenerateSelectSQL(initCtx.Dialect, initCtx.Mapper, reflect.TypeOf(&tenantUser{}),
+		[]string{"name", "offset", "limit"}, nil, nil, "name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmt, err := NewMapppedStatement(initCtx, "page", StatementTypeSelect, ResultStruct, sqlStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, err := NewContext(initCtx.Dialect, initCtx.Mapper, []string{"name", "offset", "limit"}, []interface{}{"abc", 5, 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx.stdCtx = WithTenant(context.Background(), 1)
+
+	sqlAndParams, err := stmt.GenerateSQLs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	excepted := "SELECT * FROM tenant_users WHERE name=? AND tenant_id = ? ORDER BY name OFFSET 5 ROWS FETCH NEXT 10 ROWS ONLY"
+	if sqlAndParams[0].SQL != excepted {
+		t.Error("excepted is", excepted)
+		t.Error("actual   is", sqlAndParams[0].SQL)
+	}
+}
@@ -1,9 +1,9 @@
 package gobatis
 
 import (
+	"context"
 	"errors"
 	"reflect"
-	"strings"
 
 	"github.com/runner-mei/GoBatis/reflectx"
 )
@@ -28,7 +28,8 @@ func (*emptyFinder) RValue(dialect Dialect, param *Param) (interface{}, error) {
 var emptyParameters = &emptyFinder{}
 
 type singleFinder struct {
-	value interface{}
+	mapper *Mapper
+	value  interface{}
 }
 
 func (s singleFinder) Get(name string) (interface{}, error) {
@@ -36,29 +37,54 @@ func (s singleFinder) Get(name string) (interface{}, error) {
 }
 
 func (s singleFinder) RValue(dialect Dialect, param *Param) (interface{}, error) {
-	return toSQLType(dialect, param, s.value)
+	return s.mapper.toSQLType(dialect, param, s.value)
 }
 
-type mapFinder map[string]interface{}
+type mapFinder struct {
+	mapper *Mapper
+	values map[string]interface{}
+}
+
+func (m mapFinder) lookup(name string) (pathValue, error) {
+	return m.mapper.lookupNamedPath(name, func(root string) (interface{}, bool) {
+		v, ok := m.values[root]
+		return v, ok
+	}, nil)
+}
 
 func (m mapFinder) Get(name string) (interface{}, error) {
-	v, ok := m[name]
+	v, ok := m.values[name]
 	if !ok {
-		return nil, ErrNotFound
+		if !isPropertyPath(name) {
+			return nil, ErrNotFound
+		}
+		value, err := m.lookup(name)
+		if err != nil {
+			return nil, err
+		}
+		return value.Interface(), nil
 	}
 	return v, nil
 }
 
 func (m mapFinder) RValue(dialect Dialect, param *Param) (interface{}, error) {
-	value, ok := m[param.Name]
+	value, ok := m.values[param.Name]
 	if !ok {
-		return nil, ErrNotFound
+		if !isPropertyPath(param.Name) {
+			return nil, ErrNotFound
+		}
+		pv, err := m.lookup(param.Name)
+		if err != nil {
+			return nil, err
+		}
+		return pv.RValue(dialect, m.mapper, param)
 	}
 
-	return toSQLType(dialect, param, value)
+	return m.mapper.toSQLType(dialect, param, value)
 }
 
 type structFinder struct {
+	mapper   *Mapper
 	rawValue interface{}
 	rValue   reflect.Value
 	tm       *StructMap
@@ -69,7 +95,14 @@ func (sf structFinder) Get(name string) (interface{}, error) {
 	if !ok {
 		fi, ok = sf.tm.Names[name]
 		if !ok {
-			return nil, ErrNotFound
+			if !isPropertyPath(name) {
+				return nil, ErrNotFound
+			}
+			value, err := sf.mapper.lookupPath(sf.rawValue, name)
+			if err != nil {
+				return nil, err
+			}
+			return value.Interface(), nil
 		}
 	}
 
@@ -82,7 +115,14 @@ func (sf structFinder) RValue(dialect Dialect, param *Param) (interface{}, error
 	if !ok {
 		fi, ok = sf.tm.Names[param.Name]
 		if !ok {
-			return nil, ErrNotFound
+			if !isPropertyPath(param.Name) {
+				return nil, ErrNotFound
+			}
+			value, err := sf.mapper.lookupPath(sf.rawValue, param.Name)
+			if err != nil {
+				return nil, err
+			}
+			return value.RValue(dialect, sf.mapper, param)
 		}
 	}
 	return fi.RValue(dialect, param, sf.rValue)
@@ -92,116 +132,54 @@ type kvFinder struct {
 	mapper      *Mapper
 	paramNames  []string
 	paramValues []interface{}
-
-	rValues []reflect.Value
 }
 
-func (kvf *kvFinder) Get(name string) (interface{}, error) {
-	foundIdx := -1
+func (kvf *kvFinder) indexOf(name string) int {
 	for idx := range kvf.paramNames {
 		if kvf.paramNames[idx] == name {
-			foundIdx = idx
-			break
+			return idx
 		}
 	}
+	return -1
+}
 
-	if foundIdx >= 0 {
-		return kvf.paramValues[foundIdx], nil
-	}
-
-	dotIndex := strings.IndexByte(name, '.')
-	if dotIndex < 0 {
-		if len(kvf.paramValues) != 1 {
-			return nil, ErrNotFound
+// lookup 路径的第一段是参数名，否则当只有一个参数时在这个参数上求整个路径的值
+func (kvf *kvFinder) lookup(name string) (pathValue, error) {
+	return kvf.mapper.lookupNamedPath(name, func(root string) (interface{}, bool) {
+		if idx := kvf.indexOf(root); idx >= 0 {
+			return kvf.paramValues[idx], true
 		}
-		dotIndex = -1
-		foundIdx = 0
-	} else {
-		fieldName := name[:dotIndex]
-		for idx := range kvf.paramNames {
-			if kvf.paramNames[idx] == fieldName {
-				foundIdx = idx
-				break
-			}
-		}
-		if foundIdx < 0 {
-			return nil, ErrNotFound
+		return nil, false
+	}, func() (interface{}, bool) {
+		if len(kvf.paramValues) != 1 || kvf.paramValues[0] == nil {
+			return nil, false
 		}
-	}
+		return kvf.paramValues[0], true
+	})
+}
 
-	if kvf.rValues == nil {
-		kvf.rValues = make([]reflect.Value, len(kvf.paramValues))
-	}
-	rValue := kvf.rValues[foundIdx]
-	if !rValue.IsValid() {
-		rValue = reflect.ValueOf(kvf.paramValues[foundIdx])
-		kvf.rValues[foundIdx] = rValue
+func (kvf *kvFinder) Get(name string) (interface{}, error) {
+	if idx := kvf.indexOf(name); idx >= 0 {
+		return kvf.paramValues[idx], nil
 	}
 
-	tm := kvf.mapper.TypeMap(rValue.Type())
-
-	fi, ok := tm.FieldNames[name[dotIndex+1:]]
-	if !ok {
-		fi, ok = tm.Names[name[dotIndex+1:]]
-		if !ok {
-			return nil, ErrNotFound
-		}
+	value, err := kvf.lookup(name)
+	if err != nil {
+		return nil, err
 	}
-	field := reflectx.FieldByIndexesReadOnly(rValue, fi.Index)
-	return field.Interface(), nil
+	return value.Interface(), nil
 }
 
 func (kvf *kvFinder) RValue(dialect Dialect, param *Param) (interface{}, error) {
-	foundIdx := -1
-	for idx := range kvf.paramNames {
-		if kvf.paramNames[idx] == param.Name {
-			foundIdx = idx
-			break
-		}
-	}
-
-	if foundIdx >= 0 {
-		return toSQLType(dialect, param, kvf.paramValues[foundIdx])
+	if idx := kvf.indexOf(param.Name); idx >= 0 {
+		return kvf.mapper.toSQLType(dialect, param, kvf.paramValues[idx])
 	}
 
-	dotIndex := strings.IndexByte(param.Name, '.')
-	if dotIndex < 0 {
-		if len(kvf.paramValues) != 1 {
-			return nil, ErrNotFound
-		}
-		dotIndex = -1
-		foundIdx = 0
-	} else {
-		fieldName := param.Name[:dotIndex]
-		for idx := range kvf.paramNames {
-			if kvf.paramNames[idx] == fieldName {
-				foundIdx = idx
-				break
-			}
-		}
-		if foundIdx < 0 {
-			return nil, ErrNotFound
-		}
-	}
-
-	if kvf.rValues == nil {
-		kvf.rValues = make([]reflect.Value, len(kvf.paramValues))
-	}
-	rValue := kvf.rValues[foundIdx]
-	if !rValue.IsValid() {
-		rValue = reflect.ValueOf(kvf.paramValues[foundIdx])
-		kvf.rValues[foundIdx] = rValue
-	}
-
-	tm := kvf.mapper.TypeMap(rValue.Type())
-	fi, ok := tm.FieldNames[param.Name[dotIndex+1:]]
-	if !ok {
-		fi, ok = tm.Names[param.Name[dotIndex+1:]]
-		if !ok {
-			return nil, ErrNotFound
-		}
+	value, err := kvf.lookup(param.Name)
+	if err != nil {
+		return nil, err
 	}
-	return fi.RValue(dialect, param, rValue)
+	return value.RValue(dialect, kvf.mapper, param)
 }
 
 type Context struct {
@@ -212,6 +190,8 @@ type Context struct {
 	ParamValues []interface{}
 
 	finder Parameters
+	// stdCtx 是执行语句时的 context, 用于读取租户等
+	stdCtx context.Context
 }
 
 func (bc *Context) Get(name string) (interface{}, error) {
@@ -238,18 +218,18 @@ func NewContext(dialect Dialect, mapper *Mapper, paramNames []string, paramValue
 		if len(paramValues) <= 0 {
 			ctx.finder = emptyParameters
 		} else if mapArgs, ok := paramValues[0].(map[string]interface{}); ok {
-			ctx.finder = mapFinder(mapArgs)
+			ctx.finder = mapFinder{mapper: ctx.Mapper, values: mapArgs}
 		} else {
 			rValue := reflect.ValueOf(paramValues[0])
 			for rValue.Kind() == reflect.Ptr {
 				rValue = rValue.Elem()
 			}
 
-			if rValue.Kind() == reflect.Struct {
+			if rValue.Kind() == reflect.Struct && !ctx.Mapper.hasTypeHandler(rValue.Type()) {
 				tm := ctx.Mapper.TypeMap(rValue.Type())
-				ctx.finder = &structFinder{rawValue: paramValues[0], rValue: rValue, tm: tm}
+				ctx.finder = &structFinder{mapper: ctx.Mapper, rawValue: paramValues[0], rValue: rValue, tm: tm}
 			} else {
-				ctx.finder = singleFinder{value: paramValues[0]}
+				ctx.finder = singleFinder{mapper: ctx.Mapper, value: paramValues[0]}
 			}
 		}
 	} else {
@@ -0,0 +1,478 @@
+package gobatis
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// PathError 表示属性路径(如 order.items[0].sku)求值失败，Segment 为出错的那一段及之前的部分
+type PathError struct {
+	Path    string
+	Segment string
+	Message string
+}
+
+func (e *PathError) Error() string {
+	if e.Segment == "" || e.Segment == e.Path {
+		return "property '" + e.Path + "' is invalid, " + e.Message
+	}
+	return "property '" + e.Path + "' is invalid at '" + e.Segment + "', " + e.Message
+}
+
+type pathSegmentKind int
+
+const (
+	segmentName  pathSegmentKind = iota // .name
+	segmentIndex                        // [0]
+	segmentKey                          // ['key']
+)
+
+type pathSegment struct {
+	kind  pathSegmentKind
+	name  string
+	index int
+	end   int // 这一段在路径中的结束位置
+}
+
+// propertyPath 是编译后的属性路径，支持 a.b.c， items[0].sku 和 attrs['color'] 这几种写法
+type propertyPath struct {
+	raw      string
+	segments []pathSegment
+}
+
+func (p *propertyPath) segmentText(idx int) string {
+	return p.raw[:p.segments[idx].end]
+}
+
+func (p *propertyPath) errorAt(idx int, msg string) error {
+	return &PathError{Path: p.raw, Segment: p.segmentText(idx), Message: msg}
+}
+
+func isPathIdentStart(c rune) bool {
+	return unicode.IsLetter(c) || c == '_'
+}
+
+func isPathIdent(c rune) bool {
+	return unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_'
+}
+
+// isPropertyPath 判断 name 是否是一个需要求值的路径，而不是一个简单的名字
+func isPropertyPath(name string) bool {
+	return strings.ContainsAny(name, ".[")
+}
+
+func readPathIdent(s string, pos int) int {
+	for i, c := range s[pos:] {
+		if i == 0 && !isPathIdentStart(c) {
+			return pos
+		}
+		if !isPathIdent(c) {
+			return pos + i
+		}
+	}
+	return len(s)
+}
+
+func compilePath(raw string) (*propertyPath, error) {
+	path := &propertyPath{raw: raw}
+	invalid := func(pos int, msg string) error {
+		return &PathError{Path: raw, Message: msg + " at " + strconv.Itoa(pos+1)}
+	}
+
+	end := readPathIdent(raw, 0)
+	if end == 0 {
+		return nil, invalid(0, "name is excepted")
+	}
+	path.segments = append(path.segments, pathSegment{kind: segmentName, name: raw[:end], end: end})
+
+	pos := end
+	for pos < len(raw) {
+		switch raw[pos] {
+		case '.':
+			end := readPathIdent(raw, pos+1)
+			if end == pos+1 {
+				return nil, invalid(pos+1, "name is excepted")
+			}
+			path.segments = append(path.segments, pathSegment{kind: segmentName, name: raw[pos+1 : end], end: end})
+			pos = end
+		case '[':
+			start := pos + 1
+			for start < len(raw) && raw[start] == ' ' {
+				start++
+			}
+			if start >= len(raw) {
+				return nil, invalid(pos, "']' is missing")
+			}
+
+			var segment pathSegment
+			var next int
+			if quote := raw[start]; quote == '\'' || quote == '"' {
+				closeAt := strings.IndexByte(raw[start+1:], quote)
+				if closeAt < 0 {
+					return nil, invalid(start, "unclosed string")
+				}
+				segment = pathSegment{kind: segmentKey, name: raw[start+1 : start+1+closeAt]}
+				next = start + 1 + closeAt + 1
+			} else {
+				next = start
+				for next < len(raw) && raw[next] >= '0' && raw[next] <= '9' {
+					next++
+				}
+				if next == start {
+					return nil, invalid(start, "index or quoted key is excepted")
+				}
+				index, err := strconv.Atoi(raw[start:next])
+				if err != nil {
+					return nil, invalid(start, err.Error())
+				}
+				segment = pathSegment{kind: segmentIndex, index: index, name: raw[start:next]}
+			}
+			for next < len(raw) && raw[next] == ' ' {
+				next++
+			}
+			if next >= len(raw) || raw[next] != ']' {
+				return nil, invalid(next, "']' is missing")
+			}
+			segment.end = next + 1
+			path.segments = append(path.segments, segment)
+			pos = next + 1
+		default:
+			return nil, invalid(pos, "unexcepted '"+raw[pos:pos+1]+"'")
+		}
+	}
+	return path, nil
+}
+
+type pathStepKind int
+
+const (
+	stepField pathStepKind = iota
+	stepMethod
+	stepIndex
+	stepKey
+	stepDynamic
+)
+
+type pathStep struct {
+	kind  pathStepKind
+	field *FieldInfo
+	name  string
+	index int
+	key   reflect.Value
+}
+
+// pathPlan 是一个路径在某个类型上的求值步骤, 遇到 interface 时剩下的步骤在运行时按实际的类型再计算
+type pathPlan struct {
+	steps []pathStep
+	err   error
+}
+
+type pathPlanKey struct {
+	typ   reflect.Type
+	path  string
+	start int
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+func findPathMethod(t reflect.Type, name string) (reflect.Type, bool) {
+	method, ok := reflect.PtrTo(t).MethodByName(name)
+	if !ok {
+		return nil, false
+	}
+	// 第一个参数是接收者
+	if method.Type.NumIn() != 1 {
+		return nil, false
+	}
+	switch method.Type.NumOut() {
+	case 1:
+		return method.Type.Out(0), true
+	case 2:
+		if method.Type.Out(1) == _errorType {
+			return method.Type.Out(0), true
+		}
+	}
+	return nil, false
+}
+
+var _errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+func mapKeyOf(t reflect.Type, segment *pathSegment) (reflect.Value, bool) {
+	keyType := t.Key()
+	switch keyType.Kind() {
+	case reflect.String:
+		return reflect.ValueOf(segment.name).Convert(keyType), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(segment.name, 10, 64)
+		if err != nil {
+			return reflect.Value{}, false
+		}
+		return reflect.ValueOf(i).Convert(keyType), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(segment.name, 10, 64)
+		if err != nil {
+			return reflect.Value{}, false
+		}
+		return reflect.ValueOf(u).Convert(keyType), true
+	case reflect.Interface:
+		if segment.kind == segmentIndex {
+			return reflect.ValueOf(segment.index), true
+		}
+		return reflect.ValueOf(segment.name), true
+	}
+	return reflect.Value{}, false
+}
+
+func (m *Mapper) makePathPlan(t reflect.Type, path *propertyPath, start int) *pathPlan {
+	plan := &pathPlan{}
+	for idx := start; idx < len(path.segments); idx++ {
+		t = derefType(t)
+		if t.Kind() == reflect.Interface {
+			plan.steps = append(plan.steps, pathStep{kind: stepDynamic, index: idx})
+			return plan
+		}
+
+		segment := &path.segments[idx]
+		switch t.Kind() {
+		case reflect.Map:
+			key, ok := mapKeyOf(t, segment)
+			if !ok {
+				plan.err = path.errorAt(idx, "'"+segment.name+"' cannot convert to key of "+t.String())
+				return plan
+			}
+			plan.steps = append(plan.steps, pathStep{kind: stepKey, key: key})
+			t = t.Elem()
+			continue
+		case reflect.Slice, reflect.Array:
+			if segment.kind == segmentIndex {
+				plan.steps = append(plan.steps, pathStep{kind: stepIndex, index: segment.index})
+				t = t.Elem()
+				continue
+			}
+		case reflect.Struct:
+			if segment.kind == segmentName {
+				tm := m.TypeMap(t)
+				fi, ok := tm.FieldNames[segment.name]
+				if !ok {
+					fi, ok = tm.Names[segment.name]
+				}
+				if ok {
+					plan.steps = append(plan.steps, pathStep{kind: stepField, field: fi, name: segment.name})
+					t = fi.Field.Type
+					continue
+				}
+			}
+		}
+
+		if segment.kind == segmentName {
+			if out, ok := findPathMethod(t, segment.name); ok {
+				plan.steps = append(plan.steps, pathStep{kind: stepMethod, name: segment.name})
+				t = out
+				continue
+			}
+			plan.err = path.errorAt(idx, "'"+segment.name+"' isnot found in "+t.String())
+			return plan
+		}
+		plan.err = path.errorAt(idx, t.String()+" isnot slice, array or map")
+		return plan
+	}
+	return plan
+}
+
+func (m *Mapper) getPathCache() (map[string]*propertyPath, map[pathPlanKey]*pathPlan) {
+	paths, _ := m.paths.Load().(map[string]*propertyPath)
+	plans, _ := m.plans.Load().(map[pathPlanKey]*pathPlan)
+	return paths, plans
+}
+
+// compilePath 编译路径，结果按路径缓存
+func (m *Mapper) compilePath(raw string) (*propertyPath, error) {
+	paths, _ := m.getPathCache()
+	if path, ok := paths[raw]; ok {
+		return path, nil
+	}
+
+	path, err := compilePath(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	m.pathMutex.Lock()
+	defer m.pathMutex.Unlock()
+	paths, _ = m.getPathCache()
+	newPaths := make(map[string]*propertyPath, len(paths)+1)
+	for key, value := range paths {
+		newPaths[key] = value
+	}
+	newPaths[raw] = path
+	m.paths.Store(newPaths)
+	return path, nil
+}
+
+// pathPlan 返回路径在 t 类型上的求值步骤，结果按类型缓存
+func (m *Mapper) pathPlan(t reflect.Type, path *propertyPath, start int) *pathPlan {
+	key := pathPlanKey{typ: t, path: path.raw, start: start}
+	_, plans := m.getPathCache()
+	if plan, ok := plans[key]; ok {
+		return plan
+	}
+
+	// makePathPlan 中会调用 TypeMap，不要在锁中创建
+	plan := m.makePathPlan(t, path, start)
+
+	m.pathMutex.Lock()
+	defer m.pathMutex.Unlock()
+	_, plans = m.getPathCache()
+	if old, ok := plans[key]; ok {
+		return old
+	}
+	newPlans := make(map[pathPlanKey]*pathPlan, len(plans)+1)
+	for key, value := range plans {
+		newPlans[key] = value
+	}
+	newPlans[key] = plan
+	m.plans.Store(newPlans)
+	return plan
+}
+
+// pathValue 是路径求值的结果，当最后一段是结构的字段时 field 和 parent 不为空，
+// 中间的值为 nil 时 value 为无效值
+type pathValue struct {
+	value  reflect.Value
+	parent reflect.Value
+	field  *FieldInfo
+}
+
+func (pv pathValue) Interface() interface{} {
+	if !pv.value.IsValid() {
+		return nil
+	}
+	return pv.value.Interface()
+}
+
+func (pv pathValue) RValue(dialect Dialect, mapper *Mapper, param *Param) (interface{}, error) {
+	if pv.field != nil && pv.parent.IsValid() {
+		return pv.field.RValue(dialect, param, pv.parent)
+	}
+	return mapper.toSQLType(dialect, param, pv.Interface())
+}
+
+func derefValue(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func fieldByIndexes(v reflect.Value, indexes []int) reflect.Value {
+	for _, i := range indexes {
+		v = derefValue(v)
+		if !v.IsValid() {
+			return v
+		}
+		v = v.Field(i)
+	}
+	return v
+}
+
+// evalPath 从第 start 段开始在 root 上求路径的值，中间的值为 nil 时结果为 nil，
+// 第一段没有找到时返回 ErrNotFound
+func (m *Mapper) evalPath(root reflect.Value, path *propertyPath, start int) (pathValue, error) {
+	var result = pathValue{value: root}
+	for start < len(path.segments) {
+		current := derefValue(result.value)
+		if !current.IsValid() {
+			return pathValue{}, nil
+		}
+
+		plan := m.pathPlan(current.Type(), path, start)
+		if plan.err != nil {
+			if pe, ok := plan.err.(*PathError); ok && pe.Segment == path.segmentText(0) {
+				return pathValue{}, ErrNotFound
+			}
+			return pathValue{}, plan.err
+		}
+
+		for _, step := range plan.steps {
+			if step.kind == stepDynamic {
+				start = step.index
+				break
+			}
+
+			current = derefValue(result.value)
+			if !current.IsValid() {
+				return pathValue{}, nil
+			}
+
+			switch step.kind {
+			case stepField:
+				result = pathValue{value: fieldByIndexes(current, step.field.Index), parent: current, field: step.field}
+			case stepMethod:
+				method := current.MethodByName(step.name)
+				if !method.IsValid() {
+					if !current.CanAddr() {
+						copied := reflect.New(current.Type())
+						copied.Elem().Set(current)
+						current = copied.Elem()
+					}
+					method = current.Addr().MethodByName(step.name)
+				}
+				out := method.Call(nil)
+				if len(out) == 2 && !out[1].IsNil() {
+					return pathValue{}, path.errorAt(start, out[1].Interface().(error).Error())
+				}
+				result = pathValue{value: out[0]}
+			case stepIndex:
+				if step.index >= current.Len() {
+					return pathValue{}, path.errorAt(start, "index out of range, len is "+strconv.Itoa(current.Len()))
+				}
+				result = pathValue{value: current.Index(step.index)}
+			case stepKey:
+				result = pathValue{value: current.MapIndex(step.key)}
+				if !result.value.IsValid() {
+					return pathValue{}, nil
+				}
+			}
+			start++
+		}
+	}
+	return result, nil
+}
+
+// lookupPath 在 root 上求 name 的值， name 不包含根的名称
+func (m *Mapper) lookupPath(root interface{}, name string) (pathValue, error) {
+	path, err := m.compilePath(name)
+	if err != nil {
+		return pathValue{}, err
+	}
+	return m.evalPath(reflect.ValueOf(root), path, 0)
+}
+
+// lookupNamedPath 按 name 的第一段在 names 中找到根，然后求剩下的路径的值，
+// 没有找到并且只有一个值时在这个值上求整个路径的值
+func (m *Mapper) lookupNamedPath(name string, find func(root string) (interface{}, bool), single func() (interface{}, bool)) (pathValue, error) {
+	path, err := m.compilePath(name)
+	if err != nil {
+		return pathValue{}, err
+	}
+
+	if root, ok := find(path.segments[0].name); ok {
+		return m.evalPath(reflect.ValueOf(root), path, 1)
+	}
+	if single != nil {
+		if root, ok := single(); ok {
+			return m.evalPath(reflect.ValueOf(root), path, 0)
+		}
+	}
+	return pathValue{}, ErrNotFound
+}
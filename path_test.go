@@ -0,0 +1,88 @@
+package gobatis
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompilePath(t *testing.T) {
+	for _, test := range []struct {
+		path     string
+		segments []pathSegment
+		err      string
+	}{
+		{path: "a", segments: []pathSegment{{kind: segmentName, name: "a", end: 1}}},
+		{path: "a.b[0]['c'][ \"d\" ]", segments: []pathSegment{
+			{kind: segmentName, name: "a", end: 1},
+			{kind: segmentName, name: "b", end: 3},
+			{kind: segmentIndex, name: "0", index: 0, end: 6},
+			{kind: segmentKey, name: "c", end: 11},
+			{kind: segmentKey, name: "d", end: 18},
+		}},
+		{path: "a..b", err: "property 'a..b' is invalid, name is excepted at 3"},
+		{path: "a[", err: "property 'a[' is invalid, ']' is missing at 2"},
+		{path: "a[x]", err: "property 'a[x]' is invalid, index or quoted key is excepted at 3"},
+		{path: "a['x]", err: "property 'a['x]' is invalid, unclosed string at 3"},
+		{path: "a-b", err: "property 'a-b' is invalid, unexcepted '-' at 2"},
+	} {
+		path, err := compilePath(test.path)
+		if test.err != "" {
+			if err == nil {
+				t.Error(test.path, "want error")
+			} else if err.Error() != test.err {
+				t.Error(test.path, "excepted is", test.err)
+				t.Error(test.path, "actual   is", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Error(test.path, err)
+			continue
+		}
+		if !reflect.DeepEqual(path.segments, test.segments) {
+			t.Error(test.path, "excepted is", test.segments)
+			t.Error(test.path, "actual   is", path.segments)
+		}
+	}
+}
+
+func TestPathPlanCache(t *testing.T) {
+	type inner struct {
+		Name string `db:"name"`
+	}
+	type outer struct {
+		Inner inner                  `db:"inner"`
+		Any   interface{}            `db:"any"`
+		Attrs map[string]interface{} `db:"attrs"`
+	}
+
+	mapper := CreateMapper("", nil, nil)
+	for _, test := range []struct {
+		value interface{}
+		path  string
+	}{
+		{value: outer{Inner: inner{Name: "a"}}, path: "inner.name"},
+		{value: &outer{Any: inner{Name: "a"}}, path: "any.name"},
+		{value: outer{Attrs: map[string]interface{}{"x": &inner{Name: "a"}}}, path: "attrs.x.name"},
+	} {
+		for i := 0; i < 2; i++ {
+			pv, err := mapper.lookupPath(test.value, test.path)
+			if err != nil {
+				t.Error(test.path, err)
+				continue
+			}
+			if v := pv.Interface(); v != "a" {
+				t.Error(test.path, "excepted is a, actual is", v)
+			}
+		}
+	}
+
+	paths, plans := mapper.getPathCache()
+	if len(paths) != 3 {
+		t.Error("paths is", len(paths))
+	}
+	// outer 上每个路径一个，inner 上 any.name 和 attrs.x.name 中剩下的部分各一个
+	if len(plans) != 5 {
+		t.Error("plans is", len(plans))
+	}
+}
@@ -23,6 +23,12 @@ type SQLProvider interface {
 	WithDollar() string
 }
 
+// colonSQLProvider 是能直接提供 :N 占位符的 SQLProvider，
+// 其它的 SQLProvider 由 ? 占位符转换
+type colonSQLProvider interface {
+	WithColon() string
+}
+
 var (
 	// Question is a PlaceholderFormat instance that leaves placeholders as
 	// question marks.
@@ -31,6 +37,10 @@ var (
 	// Dollar is a PlaceholderFormat instance that replaces placeholders with
 	// dollar-prefixed positional placeholders (e.g. $1, $2, $3).
 	Dollar = dollarFormat{}
+
+	// Colon is a PlaceholderFormat instance that replaces placeholders with
+	// colon-prefixed positional placeholders (e.g. :1, :2, :3), it is used by oracle.
+	Colon = colonFormat{}
 )
 
 type questionFormat struct{}
@@ -50,6 +60,36 @@ func (_ questionFormat) Concat(fragments []string, names Params, startIndex int)
 type dollarFormat struct{}
 
 func (_ dollarFormat) ReplacePlaceholders(sql string) (string, error) {
+	return replacePositionalPlaceholders(sql, "$")
+}
+
+func (_ dollarFormat) Get(params SQLProvider) string {
+	return params.WithDollar()
+}
+
+func (_ dollarFormat) Concat(fragments []string, names Params, startIndex int) string {
+	return concatPositional(fragments, "$", startIndex)
+}
+
+type colonFormat struct{}
+
+func (_ colonFormat) ReplacePlaceholders(sql string) (string, error) {
+	return replacePositionalPlaceholders(sql, ":")
+}
+
+func (_ colonFormat) Get(params SQLProvider) string {
+	if p, ok := params.(colonSQLProvider); ok {
+		return p.WithColon()
+	}
+	sql, _ := replacePositionalPlaceholders(params.WithQuestion(), ":")
+	return sql
+}
+
+func (_ colonFormat) Concat(fragments []string, names Params, startIndex int) string {
+	return concatPositional(fragments, ":", startIndex)
+}
+
+func replacePositionalPlaceholders(sql, prefix string) (string, error) {
 	buf := &bytes.Buffer{}
 	i := 0
 	for {
@@ -68,7 +108,7 @@ func (_ dollarFormat) ReplacePlaceholders(sql string) (string, error) {
 		} else {
 			i++
 			buf.WriteString(sql[:p])
-			fmt.Fprintf(buf, "$%d", i)
+			fmt.Fprintf(buf, "%s%d", prefix, i)
 			sql = sql[p+1:]
 		}
 	}
@@ -77,15 +117,11 @@ func (_ dollarFormat) ReplacePlaceholders(sql string) (string, error) {
 	return buf.String(), nil
 }
 
-func (_ dollarFormat) Get(params SQLProvider) string {
-	return params.WithDollar()
-}
-
-func (_ dollarFormat) Concat(fragments []string, names Params, startIndex int) string {
+func concatPositional(fragments []string, prefix string, startIndex int) string {
 	var sb strings.Builder
 	sb.WriteString(fragments[0])
 	for i := 1; i < len(fragments); i++ {
-		sb.WriteString("$")
+		sb.WriteString(prefix)
 		sb.WriteString(strconv.Itoa(i + startIndex))
 		sb.WriteString(fragments[i])
 	}
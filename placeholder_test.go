@@ -25,6 +25,48 @@ func TestDollar(t *testing.T) {
 	}
 }
 
+func TestColon(t *testing.T) {
+	sql := "x = ? AND y = ?"
+	s, _ := Colon.ReplacePlaceholders(sql)
+
+	if excepted := "x = :1 AND y = :2"; excepted != s {
+		t.Error("excepted is", excepted)
+		t.Error("actual   is", s)
+	}
+
+	s = Colon.Concat([]string{"x = ", " AND y = ", ""}, nil, 0)
+	if excepted := "x = :1 AND y = :2"; excepted != s {
+		t.Error("excepted is", excepted)
+		t.Error("actual   is", s)
+	}
+}
+
+type questionSQL string
+
+func (sql questionSQL) WithQuestion() string {
+	return string(sql)
+}
+
+func (sql questionSQL) WithDollar() string {
+	s, _ := Dollar.ReplacePlaceholders(string(sql))
+	return s
+}
+
+func TestColonGet(t *testing.T) {
+	// 没有实现 WithColon 的 SQLProvider 由 ? 占位符转换
+	s := Colon.Get(questionSQL("x = ? AND y = ?"))
+	if excepted := "x = :1 AND y = :2"; excepted != s {
+		t.Error("excepted is", excepted)
+		t.Error("actual   is", s)
+	}
+
+	s = Colon.Get(&parameterizedSQL{questSQL: "x = ?", colonSQL: "x = :1"})
+	if excepted := "x = :1"; excepted != s {
+		t.Error("excepted is", excepted)
+		t.Error("actual   is", s)
+	}
+}
+
 func TestPlaceholders(t *testing.T) {
 	s := Placeholders(2)
 	if excepted := "?,?"; excepted != s {
@@ -0,0 +1,144 @@
+package gobatis
+
+import (
+	"log"
+	"os"
+	"reflect"
+	"testing"
+)
+
+type userRole struct {
+	TableName TableName `db:"user_roles"`
+	UserID    int64     `db:"user_id,pk"`
+	RoleID    int64     `db:"role_id,pk"`
+	Note      string    `db:"note"`
+}
+
+type userRoleKey struct {
+	UserID int64 `db:"user_id"`
+	RoleID int64 `db:"role_id"`
+}
+
+func TestCompositePrimaryKey(t *testing.T) {
+	mapper := CreateMapper("", nil, nil)
+	rType := reflect.TypeOf(&userRole{})
+	keyType := reflect.TypeOf(userRoleKey{})
+	keysType := reflect.TypeOf([]userRoleKey{})
+
+	for idx, test := range []struct {
+		dialect  Dialect
+		generate func(dbType Dialect) (string, error)
+		excepted string
+
+		paramNames  []string
+		paramValues []interface{}
+		sql         string
+		params      []interface{}
+	}{
+		{
+			dialect: DbTypePostgres,
+			generate: func(dbType Dialect) (string, error) {
+				return GenerateSelectSQL(dbType, mapper, rType, []string{"key"}, []reflect.Type{keyType}, nil, "")
+			},
+			excepted:    "SELECT * FROM user_roles WHERE user_id=#{key.user_id} AND role_id=#{key.role_id}",
+			paramNames:  []string{"key"},
+			paramValues: []interface{}{userRoleKey{UserID: 1, RoleID: 2}},
+			sql:         "SELECT * FROM user_roles WHERE user_id=$1 AND role_id=$2",
+			params:      []interface{}{int64(1), int64(2)},
+		},
+		{
+			dialect: DbTypePostgres,
+			generate: func(dbType Dialect) (string, error) {
+				return GenerateSelectSQL(dbType, mapper, rType, []string{"userID", "roleID"}, []reflect.Type{reflect.TypeOf(int64(0)), reflect.TypeOf(int64(0))}, nil, "")
+			},
+			excepted: "SELECT * FROM user_roles WHERE user_id=#{userID} AND role_id=#{roleID}",
+		},
+		{
+			dialect: DbTypePostgres,
+			generate: func(dbType Dialect) (string, error) {
+				return GenerateSelectSQL(dbType, mapper, rType, []string{"keys"}, []reflect.Type{keysType}, nil, "")
+			},
+			excepted:    `SELECT * FROM user_roles WHERE (user_id, role_id) in (<foreach collection="keys" item="item" separator="," >(#{item.user_id}, #{item.role_id})</foreach>)`,
+			paramNames:  []string{"keys"},
+			paramValues: []interface{}{[]userRoleKey{{UserID: 1, RoleID: 2}, {UserID: 3, RoleID: 4}}},
+			sql:         "SELECT * FROM user_roles WHERE (user_id, role_id) in (($1, $2),($3, $4))",
+			params:      []interface{}{int64(1), int64(2), int64(3), int64(4)},
+		},
+		{
+			dialect: DbTypeMSSql,
+			generate: func(dbType Dialect) (string, error) {
+				return GenerateDeleteSQL(dbType, mapper, rType, []string{"keys"}, []reflect.Type{keysType}, nil)
+			},
+			excepted:    `DELETE FROM user_roles WHERE (<foreach collection="keys" item="item" separator=" OR " >(user_id=#{item.user_id} AND role_id=#{item.role_id})</foreach>)`,
+			paramNames:  []string{"keys"},
+			paramValues: []interface{}{[]userRoleKey{{UserID: 1, RoleID: 2}, {UserID: 3, RoleID: 4}}},
+			sql:         "DELETE FROM user_roles WHERE ((user_id=? AND role_id=?) OR (user_id=? AND role_id=?))",
+			params:      []interface{}{int64(1), int64(2), int64(3), int64(4)},
+		},
+		{
+			dialect: DbTypeMysql,
+			generate: func(dbType Dialect) (string, error) {
+				return GenerateUpdateSQL(dbType, mapper, "u.", rType, []string{"key"}, []reflect.Type{reflect.PtrTo(keyType)})
+			},
+			excepted: "UPDATE user_roles SET note=#{u.note} WHERE user_id=#{key.user_id} AND role_id=#{key.role_id}",
+		},
+		{
+			dialect: DbTypeMysql,
+			generate: func(dbType Dialect) (string, error) {
+				return GenerateUpdateSQL(dbType, mapper, "", rType, nil, nil)
+			},
+			excepted: "UPDATE user_roles SET note=#{note} WHERE user_id=#{user_id} AND role_id=#{role_id}",
+		},
+	} {
+		sqlStr, err := test.generate(test.dialect)
+		if err != nil {
+			t.Error(idx, err)
+			continue
+		}
+		if sqlStr != test.excepted {
+			t.Error(idx, "excepted is", test.excepted)
+			t.Error(idx, "actual   is", sqlStr)
+			continue
+		}
+		if test.sql == "" {
+			continue
+		}
+
+		initCtx := &InitContext{Config: &Config{},
+			Logger:     log.New(os.Stdout, "[gobatis] ", log.Flags()),
+			Dialect:    test.dialect,
+			Mapper:     mapper,
+			Statements: make(map[string]*MappedStatement)}
+		stmt, err := NewMapppedStatement(initCtx, "pk", StatementTypeSelect, ResultStruct, sqlStr)
+		if err != nil {
+			t.Error(idx, err)
+			continue
+		}
+		ctx, err := NewContext(initCtx.Dialect, initCtx.Mapper, test.paramNames, test.paramValues)
+		if err != nil {
+			t.Error(idx, err)
+			continue
+		}
+		sqlAndParams, err := stmt.GenerateSQLs(ctx)
+		if err != nil {
+			t.Error(idx, err)
+			continue
+		}
+		if sqlAndParams[0].SQL != test.sql {
+			t.Error(idx, "excepted is", test.sql)
+			t.Error(idx, "actual   is", sqlAndParams[0].SQL)
+		}
+		if !reflect.DeepEqual(sqlAndParams[0].Params, test.params) {
+			t.Error(idx, "excepted is", test.params)
+			t.Error(idx, "actual   is", sqlAndParams[0].Params)
+		}
+	}
+
+	type otherKey struct {
+		UserID int64 `db:"user_id"`
+		Name   string
+	}
+	if _, err := GenerateSelectSQL(DbTypePostgres, mapper, rType, []string{"key"}, []reflect.Type{reflect.TypeOf(otherKey{})}, nil, ""); err == nil {
+		t.Error("excepted error got ok")
+	}
+}
@@ -0,0 +1,293 @@
+package gobatis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Operator 是 Query.Where 中的比较操作
+type Operator string
+
+const (
+	Equal        Operator = "="
+	NotEqual     Operator = "<>"
+	Less         Operator = "<"
+	LessEqual    Operator = "<="
+	Greater      Operator = ">"
+	GreaterEqual Operator = ">="
+	Like         Operator = "LIKE"
+	NotLike      Operator = "NOT LIKE"
+	In           Operator = "IN"      // 值必须是切片或数组
+	NotIn        Operator = "NOT IN"  // 值必须是切片或数组
+	Between      Operator = "BETWEEN" // 值必须是有两个元素的切片或数组
+	IsNull       Operator = "IS NULL"
+	IsNotNull    Operator = "IS NOT NULL"
+)
+
+// Query 是运行时构造的查询，用于条件在运行时才能确定的情况(如 API 中的过滤条件)，如
+//
+//	var users []User
+//	err := gobatis.From(sess, &User{}).
+//		Where("status", gobatis.In, []int{1, 2}).
+//		OrderBy("-created_at").
+//		Limit(20).
+//		All(ctx, &users)
+//
+// 列名可以是表中的列名或结构中的字段名，它们必须在结构中存在，和生成的语句一样，
+// 查询会自动加上软删除和租户的条件，它不会按分片路由，连接有分片时表名中有 {shard} 的结构会返回错误
+type Query struct {
+	conn       *Connection
+	rType      reflect.Type
+	table      string
+	deleted    *FieldInfo
+	conditions []string
+	params     []interface{}
+	orderBy    []string
+	offset     int64
+	limit      int64
+	err        error
+}
+
+// From 返回一个查询 record 对应的表的 Query，sess 是 *Connection 或包含它的 Reference，
+// 使用 SessionFactory 或 Tx 时可以传入它们的 SessionReference()
+func From(sess SqlSession, record interface{}) *Query {
+	q := &Query{rType: reflect.TypeOf(record)}
+	q.conn, q.err = sessionConnection(sess)
+	if q.err != nil {
+		return q
+	}
+	q.table, q.err = ReadTableName(q.conn.mapper, q.rType)
+	if q.err != nil {
+		return q
+	}
+	if q.conn.shards != nil && strings.Contains(q.table, shardPlaceholder) {
+		// 查询不会按分片路由，不能直接在 orders_{shard} 这样的表名上执行
+		q.err = errors.New("table '" + q.table + "' is sharded, it isnot supported by From")
+		return q
+	}
+	q.table = quoteName(q.conn.dialect, q.conn.mapper, q.table)
+	q.deleted = findDeletedField(q.conn.mapper, q.rType)
+	return q
+}
+
+func sessionConnection(sess SqlSession) (*Connection, error) {
+	for {
+		switch s := sess.(type) {
+		case *Connection:
+			return s, nil
+		case Reference:
+			sess = s.SqlSession
+		case *Reference:
+			sess = s.SqlSession
+		default:
+			return nil, fmt.Errorf("session '%T' isnot a *gobatis.Connection", sess)
+		}
+	}
+}
+
+// column 检查 name 是否在结构中，并返回加上引号后的列名
+func (q *Query) column(name string) (*FieldInfo, string) {
+	field, _, err := toFieldName(q.conn.mapper.TypeMap(q.rType), name, nil)
+	if err != nil {
+		q.err = err
+		return nil, ""
+	}
+	return field, quoteName(q.conn.dialect, q.conn.mapper, field.Name)
+}
+
+// Where 加上一个条件，多个条件之间是 AND 的关系，IsNull 和 IsNotNull 会忽略 value，
+// Between 的 value 是有两个元素的切片或数组
+func (q *Query) Where(name string, op Operator, value interface{}) *Query {
+	if q.err != nil {
+		return q
+	}
+	field, column := q.column(name)
+	if field == nil {
+		return q
+	}
+	if field == q.deleted {
+		// 自已指定了软删除列的条件时不再自动加上 IS NULL
+		q.deleted = nil
+	}
+
+	cond, params, err := condition(column, name, op, value)
+	if err != nil {
+		q.err = err
+		return q
+	}
+	if cond != "" {
+		q.conditions = append(q.conditions, cond)
+		q.params = append(q.params, params...)
+	}
+	return q
+}
+
+// condition 返回 column op value 的条件和它的参数，参数用 ? 表示，
+// 条件总是成立时(如空的 NOT IN)返回空字符串
+func condition(column, name string, op Operator, value interface{}) (string, []interface{}, error) {
+	switch op {
+	case Equal, NotEqual, Less, LessEqual, Greater, GreaterEqual, Like, NotLike:
+		return column + " " + string(op) + " ?", []interface{}{value}, nil
+	case In, NotIn:
+		rValue := reflect.ValueOf(value)
+		if rValue.Kind() != reflect.Slice && rValue.Kind() != reflect.Array {
+			return "", nil, errors.New("value of the '" + name + "' isnot a slice")
+		}
+		if rValue.Len() == 0 {
+			if op == In {
+				return "1 <> 1", nil, nil
+			}
+			return "", nil, nil
+		}
+		params := make([]interface{}, 0, rValue.Len())
+		for idx := 0; idx < rValue.Len(); idx++ {
+			params = append(params, rValue.Index(idx).Interface())
+		}
+		return column + " " + string(op) + " (" + Placeholders(rValue.Len()) + ")", params, nil
+	case Between:
+		rValue := reflect.ValueOf(value)
+		if (rValue.Kind() != reflect.Slice && rValue.Kind() != reflect.Array) || rValue.Len() != 2 {
+			return "", nil, errors.New("value of the '" + name + "' isnot a slice with 2 elements")
+		}
+		return column + " BETWEEN ? AND ?", []interface{}{rValue.Index(0).Interface(), rValue.Index(1).Interface()}, nil
+	case IsNull, IsNotNull:
+		return column + " " + string(op), nil, nil
+	default:
+		return "", nil, errors.New("operator '" + string(op) + "' is unsupported")
+	}
+}
+
+// OrderBy 设置排序的列，列名前加上 - 表示降序，如 OrderBy("-created_at", "id")
+func (q *Query) OrderBy(names ...string) *Query {
+	if q.err != nil {
+		return q
+	}
+	for _, name := range names {
+		direction := " ASC"
+		if strings.HasPrefix(name, "-") {
+			name, direction = name[1:], " DESC"
+		} else {
+			name = strings.TrimPrefix(name, "+")
+		}
+		field, column := q.column(name)
+		if field == nil {
+			return q
+		}
+		q.orderBy = append(q.orderBy, column+direction)
+	}
+	return q
+}
+
+// Offset 设置跳过的记录数
+func (q *Query) Offset(offset int64) *Query {
+	q.offset = offset
+	return q
+}
+
+// Limit 设置返回的最大记录数
+func (q *Query) Limit(limit int64) *Query {
+	q.limit = limit
+	return q
+}
+
+// build 返回 SELECT selection FROM ... 语句和它的参数，paginate 为 true 时加上排序和分页
+func (q *Query) build(ctx context.Context, selection string, paginate bool) (string, []interface{}, error) {
+	if q.err != nil {
+		return "", nil, q.err
+	}
+
+	conditions := q.conditions
+	params := q.params
+	if q.deleted != nil {
+		conditions = append(conditions[:len(conditions):len(conditions)],
+			quoteName(q.conn.dialect, q.conn.mapper, q.deleted.Name)+" IS NULL")
+	}
+	if tenantField := findTenantField(q.conn.mapper, q.rType); tenantField != nil {
+		tenant, ok := TenantFromContext(ctx)
+		if !ok {
+			return "", nil, ErrTenantMissing
+		}
+		conditions = append(conditions[:len(conditions):len(conditions)],
+			quoteName(q.conn.dialect, q.conn.mapper, tenantField.Name)+" = ?")
+		params = append(params[:len(params):len(params)], tenant)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("SELECT ")
+	sb.WriteString(selection)
+	sb.WriteString(" FROM ")
+	sb.WriteString(q.table)
+	writeConditions(&sb, conditions)
+
+	sqlStr := sb.String()
+	if paginate {
+		if len(q.orderBy) > 0 {
+			sqlStr += " ORDER BY " + strings.Join(q.orderBy, ", ")
+		}
+		sqlStr = q.conn.dialect.Paginate(sqlStr, q.offset, q.limit)
+	}
+
+	sqlStr, err := q.conn.dialect.Placeholder().ReplacePlaceholders(sqlStr)
+	if err != nil {
+		return "", nil, err
+	}
+	return sqlStr, params, nil
+}
+
+// SQL 返回查询的语句和参数，用于调试
+func (q *Query) SQL(ctx context.Context) (string, []interface{}, error) {
+	return q.build(ctx, "*", true)
+}
+
+// All 执行查询并将所有记录读到 dest 中，dest 是结构切片的指针
+func (q *Query) All(ctx context.Context, dest interface{}) error {
+	sqlStr, params, err := q.build(ctx, "*", true)
+	if err != nil {
+		return err
+	}
+	results := &Results{o: q.conn,
+		ctx:       ctx,
+		db:        q.conn.readDB(ctx),
+		id:        "From(" + q.table + ")",
+		sql:       sqlStr,
+		sqlParams: params,
+	}
+	return results.ScanSlice(dest)
+}
+
+// One 执行查询并将第一条记录读到 dest 中，没有记录时返回 sql.ErrNoRows
+func (q *Query) One(ctx context.Context, dest interface{}) error {
+	sqlStr, params, err := q.build(ctx, "*", true)
+	if err != nil {
+		return err
+	}
+	result := Result{o: q.conn,
+		ctx:       ctx,
+		db:        q.conn.readDB(ctx),
+		id:        "From(" + q.table + ")",
+		sql:       sqlStr,
+		sqlParams: params,
+	}
+	return result.Scan(dest)
+}
+
+// Count 返回符合条件的记录数，它会忽略排序和分页
+func (q *Query) Count(ctx context.Context) (int64, error) {
+	sqlStr, params, err := q.build(ctx, "count(*)", false)
+	if err != nil {
+		return 0, err
+	}
+	result := Result{o: q.conn,
+		ctx:       ctx,
+		db:        q.conn.readDB(ctx),
+		id:        "From(" + q.table + ")",
+		sql:       sqlStr,
+		sqlParams: params,
+	}
+	var count int64
+	err = result.Scan(&count)
+	return count, err
+}
@@ -0,0 +1,216 @@
+package gobatis
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type queryUser struct {
+	TableName TableName  `db:"query_users"`
+	ID        int64      `db:"id,pk,autoincr"`
+	Name      string     `db:"name"`
+	Status    int        `db:"status"`
+	CreatedAt time.Time  `db:"created_at"`
+	DeletedAt *time.Time `db:"deleted_at,deleted"`
+}
+
+type queryTenantUser struct {
+	TableName TableName `db:"query_tenant_users"`
+	ID        int64     `db:"id,pk,autoincr"`
+	Name      string    `db:"name"`
+	TenantID  int64     `db:"tenant_id,tenant"`
+}
+
+func TestQuery(t *testing.T) {
+	ctx := context.Background()
+
+	for idx, test := range []struct {
+		driver   string
+		build    func(sess SqlSession) *Query
+		excepted string
+		params   []interface{}
+		err      string
+	}{
+		{
+			driver: "postgres",
+			build: func(sess SqlSession) *Query {
+				return From(sess, &queryUser{}).
+					Where("status", In, []int{1, 2}).
+					Where("Name", Like, "a%").
+					OrderBy("-created_at", "id").
+					Limit(20)
+			},
+			excepted: "SELECT * FROM query_users WHERE status IN ($1,$2) AND name LIKE $3 AND deleted_at IS NULL ORDER BY created_at DESC, id ASC LIMIT 20",
+			params:   []interface{}{1, 2, "a%"},
+		},
+		{
+			driver: "mysql",
+			build: func(sess SqlSession) *Query {
+				return From(sess, &queryUser{}).
+					Where("deleted_at", IsNotNull, nil).
+					Where("status", NotIn, []int{}).
+					Offset(10).
+					Limit(5)
+			},
+			excepted: "SELECT * FROM query_users WHERE deleted_at IS NOT NULL LIMIT 5 OFFSET 10",
+		},
+		{
+			driver: "mssql",
+			build: func(sess SqlSession) *Query {
+				return From(sess, &queryUser{}).
+					Where("status", In, []int{}).
+					Where("id", GreaterEqual, 3).
+					Offset(10)
+			},
+			excepted: "SELECT * FROM query_users WHERE 1 <> 1 AND id >= ? AND deleted_at IS NULL ORDER BY (SELECT NULL) OFFSET 10 ROWS",
+			params:   []interface{}{3},
+		},
+		{
+			driver: "postgres",
+			build: func(sess SqlSession) *Query {
+				return From(sess, &queryUser{}).Where("id", Between, [2]int64{1, 9})
+			},
+			excepted: "SELECT * FROM query_users WHERE id BETWEEN $1 AND $2 AND deleted_at IS NULL",
+			params:   []interface{}{int64(1), int64(9)},
+		},
+		{
+			driver: "postgres",
+			build: func(sess SqlSession) *Query {
+				return From(Reference{sess}, &queryUser{}).Where("age", Equal, 1)
+			},
+			err: "field 'age' is missing",
+		},
+		{
+			driver: "postgres",
+			build: func(sess SqlSession) *Query {
+				return From(sess, &queryUser{}).Where("status", In, 1)
+			},
+			err: "value of the 'status' isnot a slice",
+		},
+		{
+			driver: "postgres",
+			build: func(sess SqlSession) *Query {
+				return From(sess, &queryUser{}).Where("status", Operator("~"), 1)
+			},
+			err: "operator '~' is unsupported",
+		},
+		{
+			driver: "postgres",
+			build: func(sess SqlSession) *Query {
+				return From(sess, &queryTenantUser{}).Where("name", Equal, "abc")
+			},
+			err: ErrTenantMissing.Error(),
+		},
+	} {
+		conn, err := newConnection(&Config{DriverName: test.driver, DB: &fakeRunner{name: "query"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sqlStr, params, err := test.build(conn).SQL(ctx)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Error(idx, "excepted error is", test.err)
+				t.Error(idx, "actual   error is", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Error(idx, err)
+			continue
+		}
+		if sqlStr != test.excepted {
+			t.Error(idx, "excepted is", test.excepted)
+			t.Error(idx, "actual   is", sqlStr)
+		}
+		if len(params) != 0 || len(test.params) != 0 {
+			if !reflect.DeepEqual(params, test.params) {
+				t.Error(idx, "excepted is", test.params)
+				t.Error(idx, "actual   is", params)
+			}
+		}
+	}
+}
+
+func TestQueryExecute(t *testing.T) {
+	runner := &fakeRunner{name: "query"}
+	conn, err := newConnection(&Config{DriverName: "postgres", DB: runner})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := WithTenant(context.Background(), 2)
+	var users []queryTenantUser
+	err = From(conn, &queryTenantUser{}).Where("name", Equal, "abc").All(ctx, &users)
+	if err == nil || err.Error() != "query" {
+		t.Error("excepted is query, actual is", err)
+	}
+	excepted := "SELECT * FROM query_tenant_users WHERE name = $1 AND tenant_id = $2"
+	if runner.query != excepted {
+		t.Error("excepted is", excepted)
+		t.Error("actual   is", runner.query)
+	}
+	if !reflect.DeepEqual(runner.args, []interface{}{"abc", 2}) {
+		t.Error(runner.args)
+	}
+
+	_, err = From(conn, &queryTenantUser{}).Where("name", Equal, "abc").OrderBy("id").Limit(1).Count(ctx)
+	if err == nil || err.Error() != "query" {
+		t.Error("excepted is query, actual is", err)
+	}
+	excepted = "SELECT count(*) FROM query_tenant_users WHERE name = $1 AND tenant_id = $2"
+	if runner.query != excepted {
+		t.Error("excepted is", excepted)
+		t.Error("actual   is", runner.query)
+	}
+
+	var user queryTenantUser
+	if err = From(conn, &queryTenantUser{}).Where("id", Equal, 1).One(ctx, &user); err == nil || err.Error() != "query" {
+		t.Error("excepted is query, actual is", err)
+	}
+
+	if err = From(&otherSession{}, &queryUser{}).All(ctx, &users); err == nil {
+		t.Error("excepted error got ok")
+	}
+}
+
+type queryOrder struct {
+	TableName TableName `db:"query_orders_{shard}"`
+	ID        int64     `db:"id,pk,autoincr"`
+	UserID    int64     `db:"user_id"`
+}
+
+func TestQueryWithShards(t *testing.T) {
+	runner := &fakeRunner{name: "query"}
+	conn, err := newConnection(&Config{DriverName: "postgres", DB: runner, Sharding: &Sharding{
+		Key:    "user_id",
+		Count:  2,
+		Shards: []DBRunner{runner},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	var orders []queryOrder
+	err = From(conn, &queryOrder{}).Where("user_id", Equal, 1).All(ctx, &orders)
+	if err == nil || err.Error() != "table 'query_orders_{shard}' is sharded, it isnot supported by From" {
+		t.Error(err)
+	}
+	if runner.query != "" {
+		t.Error("excepted no query, actual is", runner.query)
+	}
+
+	// 没有分片的表不受影响
+	var users []queryUser
+	if err = From(conn, &queryUser{}).All(ctx, &users); err == nil || err.Error() != "query" {
+		t.Error("excepted is query, actual is", err)
+	}
+}
+
+// otherSession 不是 *Connection，From 不支持它
+type otherSession struct {
+	SqlSession
+}
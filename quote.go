@@ -0,0 +1,82 @@
+package gobatis
+
+import (
+	"strings"
+)
+
+// QuoteMode 是生成的语句中给表名和列名加引号的方式
+type QuoteMode int
+
+const (
+	QuoteIfNeeded QuoteMode = iota // 名称是保留字、有大写字母或特殊字符时才加引号
+	QuoteAlways                    // 总是加引号
+	QuoteNever                     // 从不加引号
+)
+
+// reservedWords 是各个数据库中常见的保留字，列名或表名是它们时必须加引号
+var reservedWords = map[string]struct{}{}
+
+func init() {
+	for _, word := range strings.Fields(`all alter and any as asc between by case check column comment constraint
+		create cross current current_date current_time current_timestamp current_user database date default delete desc
+		distinct drop else end except exists false fetch for foreign from full grant group having in index inner insert
+		intersect into is join key left level like limit not null number offset on option or order outer primary range
+		rank references right row rownum rows select session set size table then to top true union unique update user
+		using values view when where window with`) {
+		reservedWords[word] = struct{}{}
+	}
+}
+
+// IsReservedWord 判断 name 是否是保留字
+func IsReservedWord(name string) bool {
+	_, ok := reservedWords[strings.ToLower(name)]
+	return ok
+}
+
+// QuoteIdentifier 按 mode 给表名或列名加上 dbType 的引号，带有 schema 的名称(如 public.users)会分别处理每一段，
+// 已经有引号的段和含有空格或括号的表达式保持不变
+func QuoteIdentifier(dbType Dialect, mode QuoteMode, name string) string {
+	if mode == QuoteNever || name == "" || strings.ContainsAny(name, " \t\r\n()") {
+		return name
+	}
+
+	parts := strings.Split(name, ".")
+	for idx, part := range parts {
+		if part == "" || part == "*" || isQuoted(part) {
+			continue
+		}
+		if mode == QuoteAlways || needQuote(part) {
+			parts[idx] = dbType.Quote(part)
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+func isQuoted(name string) bool {
+	switch name[0] {
+	case '"', '`', '[':
+		return true
+	}
+	return false
+}
+
+// needQuote 判断名称是否必须加引号，只有小写字母、数字和下划线组成的非保留字不用加，
+// 分片表名中的 {shard} 会被替换为分片序号，所以当作数字处理
+func needQuote(name string) bool {
+	name = strings.Replace(name, shardPlaceholder, "0", -1)
+	if '0' <= name[0] && name[0] <= '9' {
+		return true
+	}
+	for idx := 0; idx < len(name); idx++ {
+		c := name[idx]
+		if c != '_' && c != '$' && !('0' <= c && c <= '9') && !('a' <= c && c <= 'z') {
+			return true
+		}
+	}
+	return IsReservedWord(name)
+}
+
+// quoteName 按 mapper 的 QuoteMode 给表名或列名加上引号
+func quoteName(dbType Dialect, mapper *Mapper, name string) string {
+	return QuoteIdentifier(dbType, mapper.quoteMode, name)
+}
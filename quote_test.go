@@ -0,0 +1,125 @@
+package gobatis
+
+import (
+	"context"
+	"log"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestQuoteIdentifier(t *testing.T) {
+	for _, test := range []struct {
+		dialect  Dialect
+		mode     QuoteMode
+		name     string
+		excepted string
+	}{
+		{dialect: DbTypePostgres, mode: QuoteIfNeeded, name: "users", excepted: "users"},
+		{dialect: DbTypePostgres, mode: QuoteIfNeeded, name: "order", excepted: `"order"`},
+		{dialect: DbTypePostgres, mode: QuoteIfNeeded, name: "UserName", excepted: `"UserName"`},
+		{dialect: DbTypePostgres, mode: QuoteIfNeeded, name: "public.user", excepted: `public."user"`},
+		{dialect: DbTypePostgres, mode: QuoteIfNeeded, name: `public."User"`, excepted: `public."User"`},
+		{dialect: DbTypePostgres, mode: QuoteIfNeeded, name: "1st", excepted: `"1st"`},
+		{dialect: DbTypePostgres, mode: QuoteIfNeeded, name: "users AS u", excepted: "users AS u"},
+		{dialect: DbTypePostgres, mode: QuoteIfNeeded, name: "orders_{shard}", excepted: "orders_{shard}"},
+		{dialect: DbTypePostgres, mode: QuoteIfNeeded, name: "Orders_{shard}", excepted: `"Orders_{shard}"`},
+		{dialect: DbTypePostgres, mode: QuoteAlways, name: "public.users", excepted: `"public"."users"`},
+		{dialect: DbTypePostgres, mode: QuoteNever, name: "order", excepted: "order"},
+		{dialect: DbTypeMysql, mode: QuoteIfNeeded, name: "group", excepted: "`group`"},
+		{dialect: DbTypeMSSql, mode: QuoteAlways, name: "dbo.user", excepted: "[dbo].[user]"},
+	} {
+		if actual := QuoteIdentifier(test.dialect, test.mode, test.name); actual != test.excepted {
+			t.Error(test.dialect.Name(), test.name, "excepted is", test.excepted, "actual is", actual)
+		}
+	}
+}
+
+type quoteOrder struct {
+	TableName TableName `db:"shop.order"`
+	ID        int64     `db:"id,pk,autoincr"`
+	User      string    `db:"user"`
+	Group     string    `db:"group"`
+	TenantID  int64     `db:"TenantID,tenant"`
+}
+
+func TestGenerateSQLWithQuote(t *testing.T) {
+	mapper := CreateMapper("", nil, nil)
+	rType := reflect.TypeOf(&quoteOrder{})
+
+	sqlStr, err := GenerateInsertSQL(DbTypePostgres, mapper, rType, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	excepted := `INSERT INTO shop."order"("user", "group", "TenantID") VALUES(#{user}, #{group}, <tenant placeholder="true"/>) RETURNING id`
+	if sqlStr != excepted {
+		t.Error("excepted is", excepted)
+		t.Error("actual   is", sqlStr)
+	}
+
+	sqlStr, err = GenerateSelectSQL(DbTypeMysql, mapper, rType, []string{"group"}, nil, nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	excepted = "SELECT * FROM shop.`order` WHERE `group`=#{group} AND <tenant column=\"`TenantID`\"/>"
+	if sqlStr != excepted {
+		t.Error("excepted is", excepted)
+		t.Error("actual   is", sqlStr)
+	}
+
+	sqlStr, err = GenerateUpdateSQL(DbTypePostgres, mapper, "", rType, []string{"id"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	excepted = `UPDATE shop."order" SET "user"=#{user}, "group"=#{group} WHERE id=#{id} AND <tenant column="&quot;TenantID&quot;"/>`
+	if sqlStr != excepted {
+		t.Error("excepted is", excepted)
+		t.Error("actual   is", sqlStr)
+	}
+
+	initCtx := &InitContext{Config: &Config{},
+		Logger:     log.New(os.Stdout, "[gobatis] ", log.Flags()),
+		Dialect:    DbTypePostgres,
+		Mapper:     mapper,
+		Statements: make(map[string]*MappedStatement)}
+	stmt, err := NewMapppedStatement(initCtx, "quote", StatementTypeUpdate, ResultStruct, sqlStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, err := NewContext(initCtx.Dialect, initCtx.Mapper, []string{"id", "user", "group"}, []interface{}{1, "a", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx.stdCtx = WithTenant(context.Background(), 2)
+	sqlAndParams, err := stmt.GenerateSQLs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	excepted = `UPDATE shop."order" SET "user"=$1, "group"=$2 WHERE id=$3 AND "TenantID" = $4`
+	if sqlAndParams[0].SQL != excepted {
+		t.Error("excepted is", excepted)
+		t.Error("actual   is", sqlAndParams[0].SQL)
+	}
+
+	mapper.quoteMode = QuoteAlways
+	sqlStr, err = GenerateDeleteSQL(DbTypeMSSql, mapper, rType, []string{"id"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	excepted = `DELETE FROM [shop].[order] WHERE [id]=#{id} AND <tenant column="[TenantID]"/>`
+	if sqlStr != excepted {
+		t.Error("excepted is", excepted)
+		t.Error("actual   is", sqlStr)
+	}
+
+	mapper.quoteMode = QuoteNever
+	sqlStr, err = GenerateCountSQL(DbTypePostgres, mapper, rType, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	excepted = `SELECT count(*) FROM shop.order WHERE <tenant column="TenantID"/>`
+	if sqlStr != excepted {
+		t.Error("excepted is", excepted)
+		t.Error("actual   is", sqlStr)
+	}
+}
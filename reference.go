@@ -0,0 +1,238 @@
+package gobatis
+
+import (
+	"encoding/xml"
+	"errors"
+	"io/ioutil"
+	"log"
+	"regexp"
+
+	"github.com/Knetic/govaluate"
+)
+
+// ReferenceKind 是引用出现的位置
+type ReferenceKind int
+
+const (
+	ReferenceParam      ReferenceKind = iota // #{name}
+	ReferencePrint                           // <print value="name" />
+	ReferenceCollection                      // <foreach collection="name">
+	ReferenceTest                            // <if test="name"> 和 <when test="name">
+	ReferencePage                            // <page offset="name" limit="name" />
+	ReferenceCriteria                        // <criteria value="name" />
+)
+
+func (kind ReferenceKind) String() string {
+	switch kind {
+	case ReferenceParam:
+		return "param"
+	case ReferencePrint:
+		return "print"
+	case ReferenceCollection:
+		return "collection"
+	case ReferenceTest:
+		return "test"
+	case ReferencePage:
+		return "page"
+	case ReferenceCriteria:
+		return "criteria"
+	default:
+		return "unknown"
+	}
+}
+
+// ForeachScope 是包含一个引用的 foreach 元素，引用的第一段可能是它的 item 或 index
+type ForeachScope struct {
+	Collection string
+	Item       string
+	Index      string
+}
+
+// ParamReference 是 sql 语句中引用的一个参数
+type ParamReference struct {
+	Kind ReferenceKind
+	Name string
+	// Path 是 Name 拆分后的各段， 如 items[0].sku 为 items, [0], sku
+	Path []string
+	// Foreach 是包含这个引用的 foreach 元素，外层的在前
+	Foreach []ForeachScope
+}
+
+// StatementReferences 返回 sql 语句中引用的所有参数，包括 #{}、<print value>、
+// <foreach collection>、<page offset limit>、<criteria value> 和 test 表达式中的变量，go 模板中的引用无法得到，会被忽略。
+// 它用于在生成代码时检查参数名是否正确，test 表达式中未知的函数不会被当作错误
+func StatementReferences(sqlStr string) ([]ParamReference, error) {
+	ctx := &InitContext{Config: &Config{},
+		Logger:     log.New(ioutil.Discard, "", 0),
+		Dialect:    DbTypePostgres,
+		Mapper:     CreateMapper("", nil, nil),
+		Statements: map[string]*MappedStatement{},
+		lenient:    true,
+	}
+	stmt, err := NewMapppedStatement(ctx, "references", StatementTypeNone, ResultUnknown, sqlStr)
+	if err != nil {
+		return nil, err
+	}
+
+	var refs []ParamReference
+	add := func(kind ReferenceKind, name string, scopes []ForeachScope) error {
+		path, err := compilePath(name)
+		if err != nil {
+			return err
+		}
+		ref := ParamReference{Kind: kind, Name: name, Foreach: scopes}
+		for _, segment := range path.segments {
+			switch segment.kind {
+			case segmentIndex:
+				ref.Path = append(ref.Path, "["+segment.name+"]")
+			case segmentKey:
+				ref.Path = append(ref.Path, "['"+segment.name+"']")
+			default:
+				ref.Path = append(ref.Path, segment.name)
+			}
+		}
+		refs = append(refs, ref)
+		return nil
+	}
+	for _, dynamicSQL := range stmt.dynamicSQLs {
+		if err := collectReferences(dynamicSQL, nil, add); err != nil {
+			return nil, err
+		}
+	}
+	return refs, nil
+}
+
+func collectReferences(value interface{}, scopes []ForeachScope, add func(ReferenceKind, string, []ForeachScope) error) error {
+	addParams := func(params Params) error {
+		for idx := range params {
+			if err := add(ReferenceParam, params[idx].Name, scopes); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	switch expr := value.(type) {
+	case *parameterizedSQL:
+		return addParams(expr.bindParams)
+	case *rawStringWithParams:
+		return addParams(expr.bindParams)
+	case ifExpression:
+		for _, name := range expr.test.Vars() {
+			if path, ok := expr.paths[name]; ok {
+				name = path
+			}
+			if err := add(ReferenceTest, name, scopes); err != nil {
+				return err
+			}
+		}
+		return collectReferences(expr.segement, scopes, add)
+	case *choseExpression:
+		for idx := range expr.when {
+			if err := collectReferences(expr.when[idx], scopes, add); err != nil {
+				return err
+			}
+		}
+		if expr.otherwise != nil {
+			return collectReferences(expr.otherwise, scopes, add)
+		}
+	case *forEachExpression:
+		if err := add(ReferenceCollection, expr.el.collection, scopes); err != nil {
+			return err
+		}
+		inner := make([]ForeachScope, len(scopes), len(scopes)+1)
+		copy(inner, scopes)
+		inner = append(inner, ForeachScope{Collection: expr.el.collection, Item: expr.el.item, Index: expr.el.index})
+		for _, segement := range expr.segements {
+			if err := collectReferences(segement, inner, add); err != nil {
+				return err
+			}
+		}
+	case printExpression:
+		return add(ReferencePrint, expr.value, scopes)
+	case *printExpression:
+		return add(ReferencePrint, expr.value, scopes)
+	case *pageExpression:
+		for _, name := range []string{expr.offset, expr.limit} {
+			if name == "" {
+				continue
+			}
+			if err := add(ReferencePage, name, scopes); err != nil {
+				return err
+			}
+		}
+	case *criteriaExpression:
+		return add(ReferenceCriteria, expr.value, scopes)
+	case *whereExpression:
+		return collectReferences(expr.expressions, scopes, add)
+	case *setExpression:
+		return collectReferences(expr.expressions, scopes, add)
+	case expressionArray:
+		for _, segement := range expr {
+			if err := collectReferences(segement, scopes, add); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+var functionInTest = regexp.MustCompile(`([\pL_][\pL\pN_]*)\s*\(`)
+
+// lenientFunctions 给 test 中未知的函数加上一个空的实现，这样在生成代码时不需要知道自定义的函数
+func lenientFunctions(test string, funcs map[string]govaluate.ExpressionFunction) map[string]govaluate.ExpressionFunction {
+	var copied map[string]govaluate.ExpressionFunction
+	for _, ss := range functionInTest.FindAllStringSubmatch(test, -1) {
+		if _, ok := funcs[ss[1]]; ok {
+			continue
+		}
+		if copied == nil {
+			copied = make(map[string]govaluate.ExpressionFunction, len(funcs)+1)
+			for name, fn := range funcs {
+				copied[name] = fn
+			}
+			funcs = copied
+		}
+		funcs[ss[1]] = func(args ...interface{}) (interface{}, error) {
+			return nil, errors.New("function is unsupported")
+		}
+	}
+	return funcs
+}
+
+// XMLStatement 是 xml 文件中的一个语句
+type XMLStatement struct {
+	ID   string
+	Type StatementType
+	SQL  string
+	File string
+	Line int
+}
+
+// ReadXMLStatements 读取 xml 文件中的所有语句，不会解析语句的内容
+func ReadXMLStatements(filename string) ([]XMLStatement, error) {
+	xmlFile, err := openXMLFile(nil, filename)
+	if err != nil {
+		return nil, errors.New("Error opening file: " + err.Error())
+	}
+	defer xmlFile.Close()
+
+	decoder := xml.NewDecoder(xmlFile)
+	stmts, err := readStatementXMLs(decoder)
+	if err != nil {
+		line, column := decoder.InputPos()
+		return nil, &SQLError{File: filename, Line: line, Column: column, Message: err.Error()}
+	}
+
+	statements := make([]XMLStatement, 0, len(stmts))
+	for _, stmt := range stmts {
+		statements = append(statements, XMLStatement{
+			ID:   stmt.ID,
+			Type: stmt.sqlType,
+			SQL:  stmt.SQL,
+			File: filename,
+			Line: stmt.line,
+		})
+	}
+	return statements, nil
+}
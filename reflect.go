@@ -17,10 +17,10 @@ var _valuerInterface = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
 // isScannable takes the reflect.Type and the actual dest value and returns
 // whether or not it's Scannable.  Something is scannable if:
 //   * it is not a struct
-//   * it implements sql.Scanner
+//   * it implements sql.Scanner or has a registered TypeHandler
 //   * it has no exported fields
 func isScannable(mapper *Mapper, t reflect.Type) bool {
-	if reflect.PtrTo(t).Implements(_scannerInterface) {
+	if reflect.PtrTo(t).Implements(_scannerInterface) || mapper.hasTypeHandler(t) {
 		return true
 	}
 	if t.Kind() != reflect.Struct {
@@ -108,7 +108,7 @@ func scanAny(dialect Dialect, mapper *Mapper, r colScanner, dest interface{}, st
 	}
 
 	if scannable {
-		return r.Scan(dest)
+		return r.Scan(mapper.scanValue(dialect, columns[0], dest))
 	}
 
 	fields := traversalsByName(mapper, v.Type(), columns)
@@ -244,7 +244,7 @@ func scanAll(dialect Dialect, mapper *Mapper, rows rowsi, dest interface{}, stru
 	} else {
 		for rows.Next() {
 			vp = reflect.New(base)
-			err = rows.Scan(vp.Interface())
+			err = rows.Scan(mapper.scanValue(dialect, columns[0], vp.Interface()))
 			if err != nil {
 				return err
 			}
@@ -0,0 +1,194 @@
+package gobatis
+
+import (
+	"io/fs"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// mappedStatements 保存所有的 sql 语句和被覆盖的语句，读时不加锁，写时整个替换
+type mappedStatements struct {
+	value        atomic.Value
+	overrideList atomic.Value
+}
+
+func newMappedStatements(statements map[string]*MappedStatement, overrides []StatementOverride) *mappedStatements {
+	s := &mappedStatements{}
+	s.store(statements, overrides)
+	return s
+}
+
+func (s *mappedStatements) all() map[string]*MappedStatement {
+	if s == nil {
+		return nil
+	}
+	statements, _ := s.value.Load().(map[string]*MappedStatement)
+	return statements
+}
+
+func (s *mappedStatements) get(id string) (*MappedStatement, bool) {
+	stmt, ok := s.all()[id]
+	return stmt, ok
+}
+
+func (s *mappedStatements) overrides() []StatementOverride {
+	if s == nil {
+		return nil
+	}
+	overrides, _ := s.overrideList.Load().([]StatementOverride)
+	return overrides
+}
+
+// store 替换所有的语句，对读者来说这是一个原子操作
+func (s *mappedStatements) store(statements map[string]*MappedStatement, overrides []StatementOverride) {
+	s.value.Store(statements)
+	s.overrideList.Store(overrides)
+}
+
+type xmlFileState struct {
+	modTime    time.Time
+	size       int64
+	statements []*MappedStatement
+}
+
+// xmlWatcher 定时检查 xml 文件，当文件改变或被删除时重新加载它，然后和代码中定义的语句一起按
+// Config.StatementOverride 重新合并，文件加载失败时保留这个文件原来的语句，合并失败时保留原来的所有语句
+type xmlWatcher struct {
+	ctx        *InitContext
+	fsys       fs.FS
+	xmlPaths   []string
+	dbName     string
+	interval   time.Duration
+	statements *mappedStatements
+	files      map[string]xmlFileState
+	// generated 是代码中定义的所有语句，包括被 xml 覆盖的
+	generated []*MappedStatement
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+func newXMLWatcher(ctx *InitContext, fsys fs.FS, xmlPaths []string, dbName string, interval time.Duration) *xmlWatcher {
+	return &xmlWatcher{
+		ctx:      ctx,
+		fsys:     fsys,
+		xmlPaths: xmlPaths,
+		dbName:   dbName,
+		interval: interval,
+		files:    map[string]xmlFileState{},
+		closed:   make(chan struct{}),
+	}
+}
+
+func (w *xmlWatcher) loaded(path string, fileInfo fs.FileInfo, statements []*MappedStatement) {
+	w.files[path] = xmlFileState{
+		modTime:    fileInfo.ModTime(),
+		size:       fileInfo.Size(),
+		statements: statements,
+	}
+}
+
+func (w *xmlWatcher) start(statements *mappedStatements, generated []*MappedStatement) {
+	w.statements = statements
+	w.generated = generated
+	go w.run()
+}
+
+func (w *xmlWatcher) Close() error {
+	w.closeOnce.Do(func() {
+		close(w.closed)
+	})
+	return nil
+}
+
+func (w *xmlWatcher) run() {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.closed:
+			return
+		case <-ticker.C:
+			w.check()
+		}
+	}
+}
+
+func (w *xmlWatcher) check() {
+	xmlPaths, err := findXMLFiles(w.fsys, w.xmlPaths, w.dbName)
+	if err != nil {
+		w.ctx.Logger.Println("reload xml fail,", err)
+		return
+	}
+
+	changed := false
+	found := make(map[string]bool, len(xmlPaths))
+	for _, xmlPath := range xmlPaths {
+		found[xmlPath] = true
+		fileInfo, err := statXMLFile(w.fsys, xmlPath)
+		if err != nil {
+			w.ctx.Logger.Println("reload xml -", xmlPath, "fail,", err)
+			continue
+		}
+
+		old, exists := w.files[xmlPath]
+		if exists && old.modTime.Equal(fileInfo.ModTime()) && old.size == fileInfo.Size() {
+			continue
+		}
+
+		statements, err := readMappedStatements(w.ctx, w.fsys, xmlPath)
+		if err != nil {
+			// 记下这个版本，避免每次都打印同样的错误，文件再次修改后会重新加载
+			w.ctx.Logger.Println("reload xml -", xmlPath, "fail,", err)
+			old.modTime = fileInfo.ModTime()
+			old.size = fileInfo.Size()
+			w.files[xmlPath] = old
+			continue
+		}
+
+		w.ctx.Logger.Println("reload xml -", xmlPath)
+		w.loaded(xmlPath, fileInfo, statements)
+		changed = true
+	}
+
+	// 文件被删除时去掉它的语句，被它覆盖的代码中定义的语句会在合并时恢复
+	for xmlPath := range w.files {
+		if !found[xmlPath] {
+			w.ctx.Logger.Println("reload xml -", xmlPath, "is removed")
+			delete(w.files, xmlPath)
+			changed = true
+		}
+	}
+
+	if changed {
+		if err := w.merge(xmlPaths); err != nil {
+			w.ctx.Logger.Println("reload xml fail,", err)
+		}
+	}
+}
+
+// merge 和初始化时一样，先按文件的顺序注册 xml 中的语句，再注册代码中定义的语句
+func (w *xmlWatcher) merge(xmlPaths []string) error {
+	ctx := &InitContext{Config: w.ctx.Config,
+		Logger:     w.ctx.Logger,
+		Dialect:    w.ctx.Dialect,
+		Mapper:     w.ctx.Mapper,
+		Statements: make(map[string]*MappedStatement)}
+
+	for _, xmlPath := range xmlPaths {
+		for _, stmt := range w.files[xmlPath].statements {
+			if err := ctx.registerStatement(stmt); err != nil {
+				return err
+			}
+		}
+	}
+	for _, stmt := range w.generated {
+		if err := ctx.registerStatement(stmt); err != nil {
+			return err
+		}
+	}
+	w.statements.store(ctx.Statements, ctx.overrides)
+	return nil
+}
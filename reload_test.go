@@ -0,0 +1,279 @@
+package gobatis
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReloadXML(t *testing.T) {
+	callbacks := ClearInit()
+	defer SetInit(callbacks)
+
+	tmp, err := ioutil.TempDir("", "gobatis_reload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	filename := filepath.Join(tmp, "a.xml")
+	modTime := time.Now()
+	writeXML := func(txt string) {
+		if err := ioutil.WriteFile(filename, []byte(txt), 0644); err != nil {
+			t.Fatal(err)
+		}
+		// 有的文件系统的时间精度不够，这里强制改一下修改时间
+		modTime = modTime.Add(2 * time.Second)
+		if err := os.Chtimes(filename, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeXML(`<?xml version="1.0" encoding="utf-8"?>
+<gobatis>
+  <select id="reload.a">SELECT 1</select>
+  <select id="reload.b">SELECT 2</select>
+</gobatis>`)
+
+	conn, err := newConnection(&Config{DriverName: "postgres",
+		DataSource:        "aa",
+		XMLPaths:          []string{tmp},
+		XMLReloadInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.watcher.Close()
+
+	readSQL := func(id string) string {
+		stmt, ok := conn.sqlStatements.get(id)
+		if !ok {
+			return ""
+		}
+		return stmt.rawSQL
+	}
+	waitSQL := func(id, excepted string) {
+		for i := 0; i < 200; i++ {
+			if readSQL(id) == excepted {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Error("excepted", excepted)
+		t.Error("actual  ", readSQL(id))
+	}
+
+	if sqlStr := readSQL("reload.a"); sqlStr != "SELECT 1" {
+		t.Error("excepted is SELECT 1")
+		t.Error("actual   is", sqlStr)
+	}
+
+	writeXML(`<?xml version="1.0" encoding="utf-8"?>
+<gobatis>
+  <select id="reload.a">SELECT 3</select>
+</gobatis>`)
+	waitSQL("reload.a", "SELECT 3")
+	waitSQL("reload.b", "")
+
+	// 解析出错时保留原来的语句
+	writeXML(`<?xml version="1.0" encoding="utf-8"?>
+<gobatis>
+  <select id="reload.a">SELECT 4
+</gobatis>`)
+	time.Sleep(100 * time.Millisecond)
+	if sqlStr := readSQL("reload.a"); sqlStr != "SELECT 3" {
+		t.Error("excepted is SELECT 3")
+		t.Error("actual   is", sqlStr)
+	}
+
+	writeXML(`<?xml version="1.0" encoding="utf-8"?>
+<gobatis>
+  <select id="reload.a">SELECT 5</select>
+</gobatis>`)
+	waitSQL("reload.a", "SELECT 5")
+}
+
+func TestReloadXMLOverride(t *testing.T) {
+	callbacks := SetInit([]func(ctx *InitContext) error{
+		func(ctx *InitContext) error {
+			if !ctx.ShouldGenerate("reload.g") {
+				return nil
+			}
+			stmt, err := NewMapppedStatement(ctx, "reload.g", StatementTypeSelect, ResultStruct, "SELECT generated")
+			if err != nil {
+				return err
+			}
+			return ctx.RegisterStatement(stmt)
+		},
+	})
+	defer SetInit(callbacks)
+
+	for _, policy := range []OverridePolicy{OverrideXMLOverridesGenerated, OverrideError} {
+		tmp, err := ioutil.TempDir("", "gobatis_reload")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(tmp)
+
+		filename := filepath.Join(tmp, "a.xml")
+		modTime := time.Now()
+		writeXML := func(body string) {
+			txt := `<?xml version="1.0" encoding="utf-8"?>
+<gobatis>
+  <select id="reload.a">SELECT 1</select>
+  ` + body + `
+</gobatis>`
+			if err := ioutil.WriteFile(filename, []byte(txt), 0644); err != nil {
+				t.Fatal(err)
+			}
+			modTime = modTime.Add(2 * time.Second)
+			if err := os.Chtimes(filename, modTime, modTime); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if policy == OverrideError {
+			writeXML("")
+		} else {
+			writeXML(`<select id="reload.g">SELECT xml</select>`)
+		}
+
+		conn, err := newConnection(&Config{DriverName: "postgres",
+			DataSource:        "aa",
+			XMLPaths:          []string{tmp},
+			XMLReloadInterval: time.Hour,
+			StatementOverride: policy})
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.watcher.Close()
+
+		readSQL := func(id string) string {
+			stmt, ok := conn.sqlStatements.get(id)
+			if !ok {
+				return ""
+			}
+			return stmt.rawSQL
+		}
+
+		if policy == OverrideError {
+			// 加入重复的语句时合并失败，保留原来的语句
+			writeXML(`<select id="reload.g">SELECT xml</select>`)
+			conn.watcher.check()
+			if sqlStr := readSQL("reload.g"); sqlStr != "SELECT generated" {
+				t.Error(policy, "excepted is SELECT generated")
+				t.Error(policy, "actual   is", sqlStr)
+			}
+			continue
+		}
+
+		if sqlStr := readSQL("reload.g"); sqlStr != "SELECT xml" {
+			t.Error(policy, "excepted is SELECT xml")
+			t.Error(policy, "actual   is", sqlStr)
+		}
+		if overrides := conn.StatementOverrides(); len(overrides) != 1 || overrides[0].ID != "reload.g" {
+			t.Error(policy, overrides)
+		}
+
+		// 从 xml 中删除后恢复为代码生成的语句
+		writeXML("")
+		conn.watcher.check()
+		if sqlStr := readSQL("reload.g"); sqlStr != "SELECT generated" {
+			t.Error(policy, "excepted is SELECT generated")
+			t.Error(policy, "actual   is", sqlStr)
+		}
+		if overrides := conn.StatementOverrides(); len(overrides) != 0 {
+			t.Error(policy, overrides)
+		}
+
+		writeXML(`<select id="reload.g">SELECT xml2</select>`)
+		conn.watcher.check()
+		if sqlStr := readSQL("reload.g"); sqlStr != "SELECT xml2" {
+			t.Error(policy, "excepted is SELECT xml2")
+			t.Error(policy, "actual   is", sqlStr)
+		}
+		if overrides := conn.StatementOverrides(); len(overrides) != 1 || overrides[0].ID != "reload.g" {
+			t.Error(policy, overrides)
+		}
+	}
+}
+
+func TestReloadXMLRemoved(t *testing.T) {
+	callbacks := SetInit([]func(ctx *InitContext) error{
+		func(ctx *InitContext) error {
+			if !ctx.ShouldGenerate("reload.g") {
+				return nil
+			}
+			stmt, err := NewMapppedStatement(ctx, "reload.g", StatementTypeSelect, ResultStruct, "SELECT generated")
+			if err != nil {
+				return err
+			}
+			return ctx.RegisterStatement(stmt)
+		},
+	})
+	defer SetInit(callbacks)
+
+	tmp, err := ioutil.TempDir("", "gobatis_reload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	for name, txt := range map[string]string{
+		"a.xml": `<?xml version="1.0" encoding="utf-8"?>
+<gobatis>
+  <select id="reload.a">SELECT 1</select>
+</gobatis>`,
+		"b.xml": `<?xml version="1.0" encoding="utf-8"?>
+<gobatis>
+  <select id="reload.b">SELECT 2</select>
+  <select id="reload.g">SELECT xml</select>
+</gobatis>`,
+	} {
+		if err := ioutil.WriteFile(filepath.Join(tmp, name), []byte(txt), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	conn, err := newConnection(&Config{DriverName: "postgres",
+		DataSource:        "aa",
+		XMLPaths:          []string{tmp},
+		XMLReloadInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.watcher.Close()
+
+	readSQL := func(id string) string {
+		stmt, ok := conn.sqlStatements.get(id)
+		if !ok {
+			return ""
+		}
+		return stmt.rawSQL
+	}
+
+	if sqlStr := readSQL("reload.g"); sqlStr != "SELECT xml" {
+		t.Error("excepted is SELECT xml")
+		t.Error("actual   is", sqlStr)
+	}
+
+	if err := os.Remove(filepath.Join(tmp, "b.xml")); err != nil {
+		t.Fatal(err)
+	}
+	conn.watcher.check()
+
+	for id, excepted := range map[string]string{
+		"reload.a": "SELECT 1",
+		"reload.b": "",
+		"reload.g": "SELECT generated",
+	} {
+		if sqlStr := readSQL(id); sqlStr != excepted {
+			t.Error(id, "excepted is", excepted)
+			t.Error(id, "actual   is", sqlStr)
+		}
+	}
+	if overrides := conn.StatementOverrides(); len(overrides) != 0 {
+		t.Error(overrides)
+	}
+}
@@ -0,0 +1,95 @@
+package gobatis
+
+import (
+	"context"
+	"database/sql"
+	"sync/atomic"
+)
+
+// ReplicaPolicy 是选择从库的策略
+type ReplicaPolicy int
+
+const (
+	// ReplicaRoundRobin 轮流使用各个从库，这是缺省的策略
+	ReplicaRoundRobin ReplicaPolicy = iota
+	// ReplicaLeastLoaded 使用正在使用的连接最少的从库(从库为 *sql.DB 时按 Stats().InUse 比较)，
+	// 一样多时轮流使用
+	ReplicaLeastLoaded
+)
+
+func (p ReplicaPolicy) String() string {
+	switch p {
+	case ReplicaRoundRobin:
+		return "roundRobin"
+	case ReplicaLeastLoaded:
+		return "leastLoaded"
+	default:
+		return "unknown"
+	}
+}
+
+type primaryKey struct{}
+
+// WithPrimary 返回一个强制在主库上查询的 context，用于在写入后马上读取刚写入的数据
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// IsPrimaryRequired 判断 context 是否要求在主库上查询
+func IsPrimaryRequired(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	required, _ := ctx.Value(primaryKey{}).(bool)
+	return required
+}
+
+type dbStater interface {
+	Stats() sql.DBStats
+}
+
+type replicaSet struct {
+	dbs    []DBRunner
+	policy ReplicaPolicy
+	next   uint32
+}
+
+func newReplicaSet(dbs []DBRunner, policy ReplicaPolicy) *replicaSet {
+	if len(dbs) == 0 {
+		return nil
+	}
+	return &replicaSet{dbs: dbs, policy: policy}
+}
+
+func (r *replicaSet) pick() DBRunner {
+	start := int(atomic.AddUint32(&r.next, 1)-1) % len(r.dbs)
+	if r.policy != ReplicaLeastLoaded {
+		return r.dbs[start]
+	}
+
+	var selected DBRunner
+	var minInUse int
+	for i := 0; i < len(r.dbs); i++ {
+		db := r.dbs[(start+i)%len(r.dbs)]
+		inUse := 0
+		if stater, ok := db.(dbStater); ok {
+			inUse = stater.Stats().InUse
+		}
+		if selected == nil || inUse < minInUse {
+			selected = db
+			minInUse = inUse
+		}
+	}
+	return selected
+}
+
+// readDB 返回执行 select 语句的数据库，在事务中或 context 要求时使用主库
+func (conn *Connection) readDB(ctx context.Context) DBRunner {
+	if conn.replicas == nil || IsPrimaryRequired(ctx) {
+		return conn.db
+	}
+	if _, ok := conn.db.(*sql.Tx); ok {
+		return conn.db
+	}
+	return conn.replicas.pick()
+}
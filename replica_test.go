@@ -0,0 +1,77 @@
+package gobatis
+
+import (
+	"context"
+	"testing"
+)
+
+func TestReplicas(t *testing.T) {
+	callbacks := SetInit([]func(ctx *InitContext) error{
+		func(ctx *InitContext) error {
+			for _, stmt := range []struct {
+				id      string
+				sqlType StatementType
+				sql     string
+			}{
+				{id: "replica.get", sqlType: StatementTypeSelect, sql: "SELECT * FROM users WHERE id = #{id}"},
+				{id: "replica.update", sqlType: StatementTypeUpdate, sql: "UPDATE users SET name = #{name}"},
+			} {
+				s, err := NewMapppedStatement(ctx, stmt.id, stmt.sqlType, ResultStruct, stmt.sql)
+				if err != nil {
+					return err
+				}
+				if err := ctx.RegisterStatement(s); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+	defer SetInit(callbacks)
+
+	primary := &fakeRunner{name: "primary"}
+	r1 := &fakeRunner{name: "r1", inUse: 5}
+	r2 := &fakeRunner{name: "r2", inUse: 1}
+
+	conn, err := newConnection(&Config{DriverName: "postgres", DB: primary, Replicas: []DBRunner{r1, r2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runOn := func(ctx context.Context, session SqlSession) string {
+		var id int64
+		err := session.SelectOne(ctx, "replica.get", []string{"id"}, []interface{}{1}).Scan(&id)
+		if err == nil {
+			return ""
+		}
+		return err.Error()
+	}
+
+	ctx := context.Background()
+	for idx, excepted := range []string{"r1", "r2", "r1"} {
+		if actual := runOn(ctx, conn); actual != excepted {
+			t.Error(idx, "excepted is", excepted, "actual is", actual)
+		}
+	}
+
+	if actual := runOn(WithPrimary(ctx), conn); actual != "primary" {
+		t.Error("excepted is primary, actual is", actual)
+	}
+
+	if _, err := conn.Update(ctx, "replica.update", []string{"name"}, []interface{}{"a"}); err == nil || err.Error() != "primary" {
+		t.Error("excepted is primary, actual is", err)
+	}
+
+	factory := &SessionFactory{Session: Session{base: *conn}}
+	tx := factory.WithTx(&fakeRunner{name: "tx"})
+	if actual := runOn(ctx, &tx.base); actual != "tx" {
+		t.Error("excepted is tx, actual is", actual)
+	}
+
+	conn.replicas.policy = ReplicaLeastLoaded
+	for idx := 0; idx < 3; idx++ {
+		if actual := runOn(ctx, conn); actual != "r2" {
+			t.Error(idx, "excepted is r2, actual is", actual)
+		}
+	}
+}
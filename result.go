@@ -9,10 +9,13 @@ import (
 type Result struct {
 	o         *Connection
 	ctx       context.Context
+	db        DBRunner
 	id        string
 	sql       string
 	sqlParams []interface{}
-	err       error
+	// shards 是 ScatterGather 时其它分片上的语句
+	shards []shardSQL
+	err    error
 }
 
 func (result Result) Scan(value interface{}) error {
@@ -30,7 +33,7 @@ func (result Result) scan(cb func(colScanner) error) error {
 		result.o.logger.Printf(`id:"%s", sql:"%s", params:"%+v"`, result.id, result.sql, result.sqlParams)
 	}
 
-	rows, err := result.o.db.QueryContext(result.ctx, result.sql, result.sqlParams...)
+	rows, err := result.db.QueryContext(result.ctx, result.sql, result.sqlParams...)
 	if err != nil {
 		return result.o.dialect.HandleError(err)
 	}
@@ -40,10 +43,55 @@ func (result Result) scan(cb func(colScanner) error) error {
 		if err := rows.Err(); err != nil {
 			return result.o.dialect.HandleError(err)
 		}
+		if len(result.shards) > 0 {
+			rows.Close()
+			return result.nextShard().scan(cb)
+		}
 		return sql.ErrNoRows
 	}
 
-	return cb(rows)
+	if err := cb(rows); err != nil || len(result.shards) == 0 {
+		return err
+	}
+	rows.Close()
+	return result.nextShard().checkNoRows()
+}
+
+// checkNoRows 检查剩下的分片都没有记录，ScatterGather 时只能有一个分片返回记录，
+// 否则(如 count 语句在每个分片上都返回一条记录)只返回第一个分片的记录是错误的
+func (result Result) checkNoRows() error {
+	for {
+		if result.o.showSQL {
+			result.o.logger.Printf(`id:"%s", sql:"%s", params:"%+v"`, result.id, result.sql, result.sqlParams)
+		}
+
+		rows, err := result.db.QueryContext(result.ctx, result.sql, result.sqlParams...)
+		if err != nil {
+			return result.o.dialect.HandleError(err)
+		}
+		hasRows := rows.Next()
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return result.o.dialect.HandleError(err)
+		}
+		if hasRows {
+			return ErrMultipleShardRows
+		}
+		if len(result.shards) == 0 {
+			return nil
+		}
+		result = result.nextShard()
+	}
+}
+
+// nextShard 返回在下一个分片上执行的 Result
+func (result Result) nextShard() Result {
+	result.db = result.shards[0].db
+	result.sql = result.shards[0].sqlAndParams[0].SQL
+	result.sqlParams = result.shards[0].sqlAndParams[0].Params
+	result.shards = result.shards[1:]
+	return result
 }
 
 func (result Result) ScanMultiple(multiple *Multiple) error {
@@ -55,11 +103,14 @@ func (result Result) ScanMultiple(multiple *Multiple) error {
 type Results struct {
 	o         *Connection
 	ctx       context.Context
+	db        DBRunner
 	id        string
 	sql       string
 	sqlParams []interface{}
-	rows      *sql.Rows
-	err       error
+	// shards 是 ScatterGather 时其它分片上的语句，它们的结果按顺序合并
+	shards []shardSQL
+	rows   *sql.Rows
+	err    error
 }
 
 func (results *Results) Close() error {
@@ -78,19 +129,42 @@ func (results *Results) Next() bool {
 		return false
 	}
 
-	if results.rows == nil {
-		if results.o.showSQL {
-			results.o.logger.Printf(`id:"%s", sql:"%s", params:"%+v"`, results.id, results.sql, results.sqlParams)
+	for {
+		if results.rows == nil {
+			if results.o.showSQL {
+				results.o.logger.Printf(`id:"%s", sql:"%s", params:"%+v"`, results.id, results.sql, results.sqlParams)
+			}
+
+			results.rows, results.err = results.db.QueryContext(results.ctx, results.sql, results.sqlParams...)
+			if results.err != nil {
+				results.err = results.o.dialect.HandleError(results.err)
+				return false
+			}
 		}
 
-		results.rows, results.err = results.o.db.QueryContext(results.ctx, results.sql, results.sqlParams...)
-		if results.err != nil {
-			results.err = results.o.dialect.HandleError(results.err)
+		if results.rows.Next() {
+			return true
+		}
+		if len(results.shards) == 0 {
+			return false
+		}
+		if err := results.rows.Err(); err != nil {
+			results.err = results.o.dialect.HandleError(err)
 			return false
 		}
+
+		// 当前分片已读完，继续读下一个分片
+		results.rows.Close()
+		results.rows = nil
+		results.nextShard()
 	}
+}
 
-	return results.rows.Next()
+func (results *Results) nextShard() {
+	results.db = results.shards[0].db
+	results.sql = results.shards[0].sqlAndParams[0].SQL
+	results.sqlParams = results.shards[0].sqlAndParams[0].Params
+	results.shards = results.shards[1:]
 }
 
 func (results *Results) Scan(value interface{}) error {
@@ -129,7 +203,7 @@ func (results *Results) scanAll(cb func(rowsi) error) error {
 		results.o.logger.Printf(`id:"%s", sql:"%s", params:"%+v"`, results.id, results.sql, results.sqlParams)
 	}
 
-	rows, err := results.o.db.QueryContext(results.ctx, results.sql, results.sqlParams...)
+	rows, err := results.db.QueryContext(results.ctx, results.sql, results.sqlParams...)
 	if err != nil {
 		return results.o.dialect.HandleError(err)
 	}
@@ -140,7 +214,15 @@ func (results *Results) scanAll(cb func(rowsi) error) error {
 		return err
 	}
 
-	return rows.Close()
+	if err = rows.Close(); err != nil {
+		return err
+	}
+	if len(results.shards) > 0 {
+		// 结果追加到同一个 slice 或 map 中
+		results.nextShard()
+		return results.scanAll(cb)
+	}
+	return nil
 }
 
 func (results *Results) ScanBasicMap(value interface{}) error {
@@ -0,0 +1,211 @@
+package gobatis
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/runner-mei/GoBatis/reflectx"
+)
+
+// ResultCheck 是一个 select 语句返回的列和它声明的结果类型的比较结果
+type ResultCheck struct {
+	ID         string
+	ResultType reflect.Type
+	// UnmappedColumns 是结果中没有对应字段的列，扫描时会出错
+	UnmappedColumns []string
+	// MissingFields 是结果类型中没有被返回的字段(db 名称)
+	MissingFields []string
+	// Err 是生成或执行语句时的错误
+	Err error
+}
+
+func (r *ResultCheck) String() string {
+	var sb strings.Builder
+	sb.WriteString(r.ID)
+	if r.Err != nil {
+		sb.WriteString(": ")
+		sb.WriteString(r.Err.Error())
+		return sb.String()
+	}
+	if len(r.UnmappedColumns) > 0 {
+		sb.WriteString(": columns ")
+		sb.WriteString(strings.Join(r.UnmappedColumns, ","))
+		sb.WriteString(" isnot found in ")
+		sb.WriteString(r.ResultType.String())
+	}
+	if len(r.MissingFields) > 0 {
+		sb.WriteString(": fields ")
+		sb.WriteString(strings.Join(r.MissingFields, ","))
+		sb.WriteString(" of ")
+		sb.WriteString(r.ResultType.String())
+		sb.WriteString(" isnot returned")
+	}
+	return sb.String()
+}
+
+// nullFinder 所有的参数都为 nil，用于在没有参数时生成 sql
+type nullFinder struct{}
+
+func (nullFinder) Get(name string) (interface{}, error) {
+	return nil, nil
+}
+
+func (nullFinder) RValue(dialect Dialect, param *Param) (interface{}, error) {
+	return toSQLType(dialect, param, nil)
+}
+
+// CheckResultColumns 执行所有的 select 语句(所有的参数都为 nil，并且用方言的分页语法只取一条记录)，
+// 然后比较返回的列和登记的结果类型的字段(没有登记结果类型的语句，如 xml 中的语句，只检查能否执行)，
+// 它返回有问题的语句，用于在部署前对一个真实的数据库(或一个结构一样的测试数据库)检查语句和结构是否一致
+func (conn *Connection) CheckResultColumns(ctx context.Context) []ResultCheck {
+	ids := make([]string, 0, len(conn.resultTypes))
+	for id := range conn.resultTypes {
+		ids = append(ids, id)
+	}
+	for id, stmt := range conn.sqlStatements.all() {
+		if _, ok := conn.resultTypes[id]; !ok && stmt.sqlType == StatementTypeSelect {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	var results []ResultCheck
+	for _, id := range ids {
+		result := conn.checkResultColumns(ctx, id, conn.resultTypes[id])
+		if result.Err != nil || len(result.UnmappedColumns) > 0 || len(result.MissingFields) > 0 {
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+func (conn *Connection) checkResultColumns(ctx context.Context, id string, resultType reflect.Type) ResultCheck {
+	result := ResultCheck{ID: id, ResultType: resultType}
+
+	stmt, ok := conn.sqlStatements.get(id)
+	if !ok {
+		result.Err = errors.New("statement not found")
+		return result
+	}
+	if stmt.sqlType != StatementTypeSelect {
+		return result
+	}
+
+	sqlAndParams, err := stmt.GenerateSQLs(&Context{
+		Dialect:     conn.dialect,
+		Mapper:      conn.mapper,
+		ParamValues: []interface{}{map[string]interface{}{}},
+		finder:      nullFinder{},
+	})
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	if len(sqlAndParams) == 0 {
+		return result
+	}
+
+	db := conn.db
+	if conn.shards != nil && hasShardPlaceholder(sqlAndParams) {
+		// 分片的表结构都一样，只检查第一个分片
+		at, err := conn.shards.at(conn, 0, sqlAndParams)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		db, sqlAndParams = at.db, at.sqlAndParams
+	}
+	last := sqlAndParams[len(sqlAndParams)-1]
+	if _, ok := stmt.dynamicSQLs[len(stmt.dynamicSQLs)-1].(allParamsSQL); ok {
+		// 没有命名参数的语句会把调用时的参数原样传给数据库，检查时没有参数
+		last.Params = nil
+	}
+
+	columns, err := queryColumns(ctx, conn.dialect, db, strings.TrimSuffix(strings.TrimSpace(last.SQL), ";"), last.Params)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	if resultType != nil {
+		result.UnmappedColumns, result.MissingFields = compareResultColumns(conn.mapper, resultType, columns)
+	}
+	return result
+}
+
+// queryColumns 执行语句并返回结果中的列，语句没有被包在子查询中，以免 mssql 中子查询不能有
+// ORDER BY 和 mysql 中子查询不能有重复的列之类的问题，而是用方言的分页语法只取一条记录，
+// 语句中已经有分页(如 LIMIT 10)时加上分页会出错，这时直接执行原来的语句
+func queryColumns(ctx context.Context, dialect Dialect, db DBRunner, sqlStr string, params []interface{}) ([]string, error) {
+	rows, err := db.QueryContext(ctx, dialect.Paginate(sqlStr, 0, 1), params...)
+	if err != nil {
+		rows, err = db.QueryContext(ctx, sqlStr, params...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	defer rows.Close()
+	return rows.Columns()
+}
+
+// compareResultColumns 按扫描时的规则比较列和结构的字段
+func compareResultColumns(mapper *Mapper, resultType reflect.Type, columns []string) (unmapped, missing []string) {
+	resultType = reflectx.Deref(resultType)
+	if isScannable(mapper, resultType) {
+		return nil, nil
+	}
+
+	tm := mapper.TypeMap(resultType)
+	returned := map[*FieldInfo]bool{}
+	for _, column := range columns {
+		fi := tm.Names[column]
+		if fi == nil {
+			fi = tm.Names[strings.ToLower(column)]
+		}
+		if fi == nil {
+			if !strings.HasPrefix(column, "deprecated_") {
+				unmapped = append(unmapped, column)
+			}
+			continue
+		}
+		returned[fi] = true
+	}
+
+	isReturned := func(fi *FieldInfo) bool {
+		if returned[fi] {
+			return true
+		}
+		for f := range returned {
+			// 返回了它的上级或下级字段，如 address 和 address.city
+			if strings.HasPrefix(f.Path, fi.Path+".") || strings.HasPrefix(fi.Path, f.Path+".") {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, fi := range tm.Index {
+		if fi.Embedded || fi.Name == "" || fi.Field.Name == "TableName" {
+			continue
+		}
+		if _, ok := fi.Options["-"]; ok {
+			continue
+		}
+
+		// 只检查最上层的字段，匿名字段中的字段也算最上层的字段
+		parent := fi.Parent
+		for parent != nil && parent.Embedded {
+			parent = parent.Parent
+		}
+		if parent != nil && parent.Parent != nil {
+			continue
+		}
+
+		if !isReturned(fi) {
+			missing = append(missing, fi.Path)
+		}
+	}
+	return unmapped, missing
+}
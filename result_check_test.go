@@ -0,0 +1,124 @@
+package gobatis
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var checkDriver = newFakeDriver("gobatis_check")
+
+func TestCheckResultColumns(t *testing.T) {
+	type checkBase struct {
+		ID int64 `db:"id"`
+	}
+	type checkAddress struct {
+		City string `db:"city"`
+	}
+	type checkUser struct {
+		TableName TableName `db:"check_users"`
+		checkBase
+		Name    string        `db:"name"`
+		Phone   string        `db:"phone"`
+		Address *checkAddress `db:"address"`
+	}
+
+	tables := map[string][]string{
+		"check_users":    {"id", "NAME", "phone", "address.city"},
+		"check_accounts": {"id", "name", "nickname", "deprecated_phone"},
+	}
+	// 按 sql 中的表名返回结果列，不会返回任何记录，有两个 LIMIT 时是语法错误
+	checkDriver.query = func(name, query string, args []driver.Value) (driver.Rows, error) {
+		checkDriver.record(name, query)
+		if strings.Count(query, "LIMIT") > 1 {
+			return nil, errors.New("syntax error")
+		}
+		for table, columns := range tables {
+			if strings.Contains(query, " "+table+" ") {
+				return &fakeRows{columns: columns}, nil
+			}
+		}
+		return nil, errors.New("relation isnot exists")
+	}
+	checkDriver.reset()
+
+	userType := reflect.TypeOf(checkUser{})
+	callbacks := SetInit([]func(ctx *InitContext) error{
+		func(ctx *InitContext) error {
+			for _, stmt := range []struct {
+				id         string
+				sql        string
+				resultType reflect.Type
+			}{
+				{id: "check.users", sql: `SELECT * FROM check_users <where><if test="isNotEmpty(name)">name = #{name}</if></where>;`, resultType: userType},
+				{id: "check.accounts", sql: "SELECT * FROM check_accounts WHERE id = #{id}", resultType: userType},
+				{id: "check.missing", sql: "SELECT * FROM check_missing WHERE id = #{id}", resultType: userType},
+				// 没有登记结果类型的语句(如 xml 中的语句)只检查能否执行
+				{id: "check.limited", sql: "SELECT * FROM check_accounts LIMIT 10"},
+				{id: "check.xml", sql: "SELECT * FROM check_xml"},
+			} {
+				s, err := NewMapppedStatement(ctx, stmt.id, StatementTypeSelect, ResultStruct, stmt.sql)
+				if err != nil {
+					return err
+				}
+				if err := ctx.RegisterStatement(s); err != nil {
+					return err
+				}
+				if stmt.resultType != nil {
+					ctx.RegisterResultType(stmt.id, stmt.resultType)
+				}
+			}
+			ctx.RegisterResultType("check.notexists", userType)
+			return nil
+		},
+	})
+	defer SetInit(callbacks)
+
+	db, err := sql.Open("gobatis_check", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	conn, err := newConnection(&Config{DriverName: "postgres", DB: db})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := conn.CheckResultColumns(context.Background())
+	var actual []string
+	for idx := range results {
+		actual = append(actual, results[idx].String())
+	}
+
+	excepted := []string{
+		"check.accounts: columns nickname isnot found in gobatis.checkUser: fields phone,address of gobatis.checkUser isnot returned",
+		"check.missing: relation isnot exists",
+		"check.notexists: statement not found",
+		"check.xml: relation isnot exists",
+	}
+	if !reflect.DeepEqual(actual, excepted) {
+		t.Error("excepted is", excepted)
+		t.Error("actual   is", actual)
+	}
+
+	// 语句没有被包在子查询中，而是加上分页，已经有分页时直接执行原来的语句
+	excepted = []string{
+		"SELECT * FROM check_accounts WHERE id = $1 LIMIT 1",
+		"SELECT * FROM check_accounts LIMIT 10 LIMIT 1",
+		"SELECT * FROM check_accounts LIMIT 10",
+		"SELECT * FROM check_missing WHERE id = $1 LIMIT 1",
+		"SELECT * FROM check_missing WHERE id = $1",
+		"SELECT * FROM check_users  LIMIT 1",
+		"SELECT * FROM check_xml LIMIT 1",
+		"SELECT * FROM check_xml",
+	}
+	if queries := checkDriver.reset(); !reflect.DeepEqual(queries, excepted) {
+		t.Error("excepted is", excepted)
+		t.Error("actual   is", queries)
+	}
+}
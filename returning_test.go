@@ -0,0 +1,117 @@
+package gobatis
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+type returningUser struct {
+	TableName TableName `db:"returning_users"`
+	ID        int64     `db:"id,pk,autoincr"`
+	Name      string    `db:"name"`
+	CreatedAt int64     `db:"created_at"`
+	UpdatedAt int64     `db:"updated_at"`
+}
+
+func TestGenerateReturningSQL(t *testing.T) {
+	mapper := CreateMapper("", nil, nil)
+	rType := reflect.TypeOf(&returningUser{})
+
+	for _, test := range []struct {
+		dialect  Dialect
+		insert   string
+		update   string
+		excepted string
+	}{
+		{dialect: DbTypePostgres,
+			insert: "INSERT INTO returning_users(name, created_at, updated_at) VALUES(#{name}, now(), now()) RETURNING *",
+			update: "UPDATE returning_users SET name=#{u.name}, updated_at=now() WHERE id=#{id} RETURNING *"},
+		{dialect: DbTypeMSSql,
+			insert: "INSERT INTO returning_users(name, created_at, updated_at) OUTPUT INSERTED.* VALUES(#{name}, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)",
+			update: "UPDATE returning_users SET name=#{u.name}, updated_at=CURRENT_TIMESTAMP OUTPUT INSERTED.* WHERE id=#{id}"},
+		{dialect: DbTypeMysql,
+			excepted: "returning all columns is unsupported for the 'mysql'"},
+	} {
+		insert, err := GenerateInsertReturningSQL(test.dialect, mapper, rType)
+		if test.excepted != "" {
+			if err == nil || err.Error() != test.excepted {
+				t.Error(test.dialect.Name(), "excepted error is", test.excepted, "actual is", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Error(test.dialect.Name(), err)
+			continue
+		}
+		if insert != test.insert {
+			t.Error(test.dialect.Name())
+			t.Error("excepted is", test.insert)
+			t.Error("actual   is", insert)
+		}
+
+		update, err := GenerateUpdateReturningSQL(test.dialect, mapper, "u.", rType, []string{"id"}, []reflect.Type{reflect.TypeOf(int64(0))})
+		if err != nil {
+			t.Error(test.dialect.Name(), err)
+			continue
+		}
+		if update != test.update {
+			t.Error(test.dialect.Name())
+			t.Error("excepted is", test.update)
+			t.Error("actual   is", update)
+		}
+	}
+}
+
+func TestInsertReturning(t *testing.T) {
+	callbacks := SetInit([]func(ctx *InitContext) error{
+		func(ctx *InitContext) error {
+			rType := reflect.TypeOf(&returningUser{})
+			insertSQL, err := GenerateInsertReturningSQL(ctx.Dialect, ctx.Mapper, rType)
+			if err != nil {
+				return err
+			}
+			updateSQL, err := GenerateUpdateReturningSQL(ctx.Dialect, ctx.Mapper, "u.", rType, []string{"id"}, []reflect.Type{reflect.TypeOf(int64(0))})
+			if err != nil {
+				return err
+			}
+			for _, stmt := range []struct {
+				id      string
+				sqlType StatementType
+				sql     string
+			}{
+				{id: "returning.insert", sqlType: StatementTypeInsert, sql: insertSQL},
+				{id: "returning.update", sqlType: StatementTypeUpdate, sql: updateSQL},
+			} {
+				s, err := NewMapppedStatement(ctx, stmt.id, stmt.sqlType, ResultStruct, stmt.sql)
+				if err != nil {
+					return err
+				}
+				if err := ctx.RegisterStatement(s); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+	defer SetInit(callbacks)
+
+	conn, err := newConnection(&Config{DriverName: "postgres",
+		DB:       &fakeRunner{name: "primary"},
+		Replicas: []DBRunner{&fakeRunner{name: "r1"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	u := &returningUser{Name: "abc"}
+	if err := conn.InsertReturning(ctx, "returning.insert", []string{"u"}, []interface{}{u}, u); err == nil || err.Error() != "primary" {
+		t.Error("excepted is primary, actual is", err)
+	}
+	if err := conn.UpdateReturning(ctx, "returning.update", []string{"id", "u"}, []interface{}{1, u}, u); err == nil || err.Error() != "primary" {
+		t.Error("excepted is primary, actual is", err)
+	}
+	if err := conn.UpdateReturning(ctx, "returning.insert", []string{"u"}, []interface{}{u}, u); err == nil {
+		t.Error("excepted error got ok")
+	}
+}
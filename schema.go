@@ -0,0 +1,542 @@
+package gobatis
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// ColumnSchema 是表中的一个列
+type ColumnSchema struct {
+	Name     string
+	Type     string
+	NotNull  bool
+	Default  string
+	Autoincr bool
+	// Unique 为 true 时表示列上有一个唯一约束(从数据库中读取时唯一约束在 Indexes 中)
+	Unique bool
+}
+
+// IndexSchema 是表中的一个索引
+type IndexSchema struct {
+	Name    string
+	Unique  bool
+	Columns []string
+	// Constraint 为 true 时表示它是一个唯一约束，需要用 DROP CONSTRAINT 来删除
+	Constraint bool
+}
+
+// TableSchema 是一个表的结构，可以从结构的定义(ReadStructSchema)或数据库(ReadTableSchema)中读取
+type TableSchema struct {
+	Name       string
+	Columns    []ColumnSchema
+	PrimaryKey []string
+	Indexes    []IndexSchema
+
+	// quoteMode 是生成语句时给表名、列名和索引名加引号的方式，由 ReadStructSchema 按 Mapper 设置
+	quoteMode QuoteMode
+}
+
+func (schema *TableSchema) column(name string) (ColumnSchema, bool) {
+	for _, column := range schema.Columns {
+		if strings.EqualFold(column.Name, name) {
+			return column, true
+		}
+	}
+	return ColumnSchema{}, false
+}
+
+// SchemaQueries 是从系统表中读取表结构的查询，它们的参数是表名，用 ? 作为占位符。
+//
+// Columns 返回 列名, 类型, 是否可以为 null('YES' 或 'NO')
+// Indexes 返回 索引名, 是否唯一, 是否为主键, 是否为约束, 列名, 并按索引中列的顺序排序
+type SchemaQueries struct {
+	Columns string
+	Indexes string
+}
+
+var (
+	postgresSchemaQueries = &SchemaQueries{
+		Columns: `SELECT a.attname, format_type(a.atttypid, a.atttypmod), CASE WHEN a.attnotnull THEN 'NO' ELSE 'YES' END
+FROM pg_attribute a JOIN pg_class c ON c.oid = a.attrelid JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE c.relname = ? AND n.nspname = ANY(current_schemas(false)) AND a.attnum > 0 AND NOT a.attisdropped
+ORDER BY a.attnum`,
+		Indexes: `SELECT i.relname, ix.indisunique, ix.indisprimary, EXISTS(SELECT 1 FROM pg_constraint con WHERE con.conindid = ix.indexrelid AND con.contype = 'u'), a.attname
+FROM pg_index ix JOIN pg_class t ON t.oid = ix.indrelid JOIN pg_class i ON i.oid = ix.indexrelid
+JOIN pg_namespace n ON n.oid = t.relnamespace
+JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = ANY(ix.indkey)
+WHERE t.relname = ? AND n.nspname = ANY(current_schemas(false))
+ORDER BY i.relname, array_position(ix.indkey::int2[], a.attnum)`,
+	}
+	mysqlSchemaQueries = &SchemaQueries{
+		Columns: `SELECT COLUMN_NAME, COLUMN_TYPE, IS_NULLABLE FROM information_schema.COLUMNS
+WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? ORDER BY ORDINAL_POSITION`,
+		Indexes: `SELECT INDEX_NAME, NON_UNIQUE = 0, INDEX_NAME = 'PRIMARY', 0, COLUMN_NAME FROM information_schema.STATISTICS
+WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? ORDER BY INDEX_NAME, SEQ_IN_INDEX`,
+	}
+	mssqlSchemaQueries = &SchemaQueries{
+		Columns: `SELECT COLUMN_NAME, CASE
+  WHEN CHARACTER_MAXIMUM_LENGTH = -1 THEN DATA_TYPE + '(max)'
+  WHEN DATA_TYPE IN ('text', 'ntext', 'image') THEN DATA_TYPE
+  WHEN CHARACTER_MAXIMUM_LENGTH IS NOT NULL THEN DATA_TYPE + '(' + CAST(CHARACTER_MAXIMUM_LENGTH AS varchar(10)) + ')'
+  WHEN DATA_TYPE IN ('decimal', 'numeric') THEN DATA_TYPE + '(' + CAST(NUMERIC_PRECISION AS varchar(10)) + ',' + CAST(NUMERIC_SCALE AS varchar(10)) + ')'
+  ELSE DATA_TYPE END, IS_NULLABLE
+FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_NAME = ? ORDER BY ORDINAL_POSITION`,
+		Indexes: `SELECT i.name, i.is_unique, i.is_primary_key, i.is_unique_constraint, c.name FROM sys.indexes i
+JOIN sys.index_columns ic ON ic.object_id = i.object_id AND ic.index_id = i.index_id
+JOIN sys.columns c ON c.object_id = ic.object_id AND c.column_id = ic.column_id
+WHERE i.object_id = OBJECT_ID(?) AND ic.is_included_column = 0 ORDER BY i.name, ic.key_ordinal`,
+	}
+)
+
+// ReadTableSchema 从数据库的系统表中读取表的列、主键和索引，表不存在时返回 nil
+func ReadTableSchema(ctx context.Context, db DBRunner, dbType Dialect, table string) (*TableSchema, error) {
+	ddl := dbType.Schema()
+	if ddl == nil || ddl.Queries == nil {
+		return nil, errors.New("read schema from '" + dbType.Name() + "' is unsupported")
+	}
+	queries := ddl.Queries
+
+	replace := func(sqlStr string) string {
+		s, err := dbType.Placeholder().ReplacePlaceholders(sqlStr)
+		if err != nil {
+			return sqlStr
+		}
+		return s
+	}
+
+	rows, err := db.QueryContext(ctx, replace(queries.Columns), table)
+	if err != nil {
+		return nil, errors.New("read columns of '" + table + "' fail, " + err.Error())
+	}
+	defer rows.Close()
+
+	schema := &TableSchema{Name: table}
+	for rows.Next() {
+		var column ColumnSchema
+		var nullable string
+		if err := rows.Scan(&column.Name, &column.Type, &nullable); err != nil {
+			return nil, errors.New("read columns of '" + table + "' fail, " + err.Error())
+		}
+		column.NotNull = strings.EqualFold(nullable, "NO")
+		schema.Columns = append(schema.Columns, column)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("read columns of '" + table + "' fail, " + err.Error())
+	}
+	rows.Close()
+	if len(schema.Columns) == 0 {
+		return nil, nil
+	}
+
+	rows, err = db.QueryContext(ctx, replace(queries.Indexes), table)
+	if err != nil {
+		return nil, errors.New("read indexes of '" + table + "' fail, " + err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, column string
+		var unique, primary, constraint bool
+		if err := rows.Scan(&name, &unique, &primary, &constraint, &column); err != nil {
+			return nil, errors.New("read indexes of '" + table + "' fail, " + err.Error())
+		}
+		if primary {
+			schema.PrimaryKey = append(schema.PrimaryKey, column)
+			continue
+		}
+		if last := len(schema.Indexes) - 1; last >= 0 && schema.Indexes[last].Name == name {
+			schema.Indexes[last].Columns = append(schema.Indexes[last].Columns, column)
+			continue
+		}
+		schema.Indexes = append(schema.Indexes, IndexSchema{Name: name, Unique: unique, Constraint: constraint, Columns: []string{column}})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("read indexes of '" + table + "' fail, " + err.Error())
+	}
+	return schema, nil
+}
+
+var (
+	postgresSchema = &SchemaDialect{
+		Types:   postgresDDLTypes,
+		Queries: postgresSchemaQueries,
+		TypeAliases: map[string]string{
+			"character varying": "varchar",
+			"character":         "char",
+			"int":               "integer",
+			"int4":              "integer",
+			"serial":            "integer",
+			"int8":              "bigint",
+			"bigserial":         "bigint",
+			"int2":              "smallint",
+			"bool":              "boolean",
+			"float8":            "double precision",
+			"float4":            "real",
+			"decimal":           "numeric",
+			"timestamptz":       "timestamp with time zone",
+		},
+		Serial:           true,
+		TransactionalDDL: true,
+	}
+	mysqlSchema = &SchemaDialect{
+		Types:   mysqlDDLTypes,
+		Queries: mysqlSchemaQueries,
+		TypeAliases: map[string]string{
+			"bool":    "tinyint(1)",
+			"boolean": "tinyint(1)",
+			"integer": "int",
+			"numeric": "decimal",
+		},
+		IgnoreIntWidth: true,
+		Unsigned:       true,
+		Autoincr:       "AUTO_INCREMENT",
+		AlterColumn: func(table, column string, from, to ColumnSchema, definition string) []string {
+			return []string{"ALTER TABLE " + table + " MODIFY COLUMN " + definition}
+		},
+		DropIndex: func(table, name string, constraint bool) string {
+			// mysql 中唯一约束就是唯一索引
+			return "DROP INDEX " + name + " ON " + table
+		},
+	}
+	mssqlSchema = &SchemaDialect{
+		Types:   mssqlDDLTypes,
+		Queries: mssqlSchemaQueries,
+		TypeAliases: map[string]string{
+			"integer":          "int",
+			"double precision": "float",
+			"numeric":          "decimal",
+		},
+		Autoincr:  "IDENTITY(1,1)",
+		AddColumn: "ADD",
+		AlterColumn: func(table, column string, from, to ColumnSchema, definition string) []string {
+			if to.NotNull {
+				return []string{"ALTER TABLE " + table + " ALTER COLUMN " + column + " " + to.Type + " NOT NULL"}
+			}
+			return []string{"ALTER TABLE " + table + " ALTER COLUMN " + column + " " + to.Type + " NULL"}
+		},
+		DropIndex: func(table, name string, constraint bool) string {
+			if constraint {
+				return "ALTER TABLE " + table + " DROP CONSTRAINT " + name
+			}
+			return "DROP INDEX " + name + " ON " + table
+		},
+		CreateTableIfNotExists: func(table, definition string) string {
+			return "IF OBJECT_ID(N'" + table + "', N'U') IS NULL CREATE TABLE " + table + " " + definition
+		},
+		TransactionalDDL: true,
+	}
+
+	mysqlIntWidth = regexp.MustCompile(`^(tinyint|smallint|mediumint|int|bigint)\(\d+\)`)
+)
+
+// normalizeColumnType 将列类型转成统一的写法，以便比较
+func (schema *SchemaDialect) normalizeColumnType(typ string) string {
+	typ = strings.ToLower(strings.Join(strings.Fields(typ), " "))
+	typ = strings.Replace(typ, " (", "(", -1)
+
+	base, suffix := typ, ""
+	if idx := strings.IndexAny(typ, "(["); idx >= 0 {
+		base, suffix = strings.TrimSpace(typ[:idx]), typ[idx:]
+	}
+	if alias, ok := schema.TypeAliases[base]; ok {
+		base = alias
+	}
+	typ = base + suffix
+
+	if schema.IgnoreIntWidth && typ != "tinyint(1)" {
+		typ = mysqlIntWidth.ReplaceAllString(typ, "$1")
+	}
+	return typ
+}
+
+// ColumnChange 是一个列的变化
+type ColumnChange struct {
+	From ColumnSchema
+	To   ColumnSchema
+}
+
+// SchemaDiff 是结构的定义和数据库中的表结构的差异
+type SchemaDiff struct {
+	Dialect  Dialect
+	Table    string
+	Expected *TableSchema
+	// Actual 为 nil 时表示数据库中没有这个表
+	Actual *TableSchema
+
+	AddColumns   []ColumnSchema
+	DropColumns  []ColumnSchema
+	AlterColumns []ColumnChange
+	AddIndexes   []IndexSchema
+	DropIndexes  []IndexSchema
+
+	PrimaryKeyChanged bool
+}
+
+// IsEmpty 没有差异时返回 true
+func (d *SchemaDiff) IsEmpty() bool {
+	return d.Actual != nil &&
+		len(d.AddColumns) == 0 &&
+		len(d.DropColumns) == 0 &&
+		len(d.AlterColumns) == 0 &&
+		len(d.AddIndexes) == 0 &&
+		len(d.DropIndexes) == 0 &&
+		!d.PrimaryKeyChanged
+}
+
+func indexKey(index IndexSchema) string {
+	var sb strings.Builder
+	if index.Unique {
+		sb.WriteString("unique:")
+	}
+	sb.WriteString(strings.ToLower(strings.Join(index.Columns, ",")))
+	return sb.String()
+}
+
+// DiffTableSchema 比较结构的定义(expected)和数据库中的表结构(actual)，actual 为 nil 时表示表不存在。
+//
+// 列按名称比较，类型会先转成统一的写法; 索引按列和是否唯一比较，不比较名称
+func DiffTableSchema(dbType Dialect, expected, actual *TableSchema) *SchemaDiff {
+	diff := &SchemaDiff{Dialect: dbType, Table: expected.Name, Expected: expected, Actual: actual}
+	if actual == nil {
+		return diff
+	}
+	schema := schemaOf(dbType)
+
+	for _, column := range expected.Columns {
+		old, ok := actual.column(column.Name)
+		if !ok {
+			diff.AddColumns = append(diff.AddColumns, column)
+			continue
+		}
+		if schema.normalizeColumnType(old.Type) != schema.normalizeColumnType(column.Type) ||
+			old.NotNull != column.NotNull {
+			diff.AlterColumns = append(diff.AlterColumns, ColumnChange{From: old, To: column})
+		}
+	}
+	for _, column := range actual.Columns {
+		if _, ok := expected.column(column.Name); !ok {
+			diff.DropColumns = append(diff.DropColumns, column)
+		}
+	}
+
+	expectedIndexes := append([]IndexSchema(nil), expected.Indexes...)
+	for _, column := range expected.Columns {
+		if column.Unique {
+			expectedIndexes = append(expectedIndexes, IndexSchema{
+				Name:    "uq_" + expected.Name + "_" + column.Name,
+				Unique:  true,
+				Columns: []string{column.Name},
+			})
+		}
+	}
+	actualKeys := map[string]bool{}
+	for _, index := range actual.Indexes {
+		actualKeys[indexKey(index)] = true
+	}
+	expectedKeys := map[string]bool{}
+	for _, index := range expectedIndexes {
+		key := indexKey(index)
+		expectedKeys[key] = true
+		if !actualKeys[key] {
+			diff.AddIndexes = append(diff.AddIndexes, index)
+		}
+	}
+	for _, index := range actual.Indexes {
+		if !expectedKeys[indexKey(index)] {
+			diff.DropIndexes = append(diff.DropIndexes, index)
+		}
+	}
+
+	diff.PrimaryKeyChanged = !strings.EqualFold(strings.Join(expected.PrimaryKey, ","), strings.Join(actual.PrimaryKey, ","))
+	return diff
+}
+
+func columnTypeString(column ColumnSchema) string {
+	if column.NotNull {
+		return column.Type + " NOT NULL"
+	}
+	return column.Type
+}
+
+func indexString(index IndexSchema) string {
+	if index.Unique {
+		return "unique index " + index.Name + "(" + strings.Join(index.Columns, ", ") + ")"
+	}
+	return "index " + index.Name + "(" + strings.Join(index.Columns, ", ") + ")"
+}
+
+// String 返回可读的差异，+ 表示需要添加，- 表示需要删除，~ 表示需要修改
+func (d *SchemaDiff) String() string {
+	var sb strings.Builder
+	sb.WriteString("table ")
+	sb.WriteString(d.Table)
+	if d.Actual == nil {
+		sb.WriteString(" isnot exists")
+		return sb.String()
+	}
+	if d.IsEmpty() {
+		sb.WriteString(" is same")
+		return sb.String()
+	}
+	sb.WriteString(":")
+	for _, column := range d.AddColumns {
+		sb.WriteString("\r\n  + column " + column.Name + " " + columnTypeString(column))
+	}
+	for _, column := range d.DropColumns {
+		sb.WriteString("\r\n  - column " + column.Name + " " + columnTypeString(column))
+	}
+	for _, change := range d.AlterColumns {
+		sb.WriteString("\r\n  ~ column " + change.To.Name + " " + columnTypeString(change.From) + " -> " + columnTypeString(change.To))
+	}
+	for _, index := range d.AddIndexes {
+		sb.WriteString("\r\n  + " + indexString(index))
+	}
+	for _, index := range d.DropIndexes {
+		sb.WriteString("\r\n  - " + indexString(index))
+	}
+	if d.PrimaryKeyChanged {
+		sb.WriteString("\r\n  ~ primary key (" + strings.Join(d.Actual.PrimaryKey, ", ") + ") -> (" + strings.Join(d.Expected.PrimaryKey, ", ") + ")")
+	}
+	return sb.String()
+}
+
+// quote 按结构定义中的 QuoteMode 给表名、列名或索引名加上引号
+func (d *SchemaDiff) quote(name string) string {
+	return QuoteIdentifier(d.Dialect, d.Expected.quoteMode, name)
+}
+
+func (d *SchemaDiff) createIndexSQL(index IndexSchema) string {
+	return createIndexSQL(d.Dialect, d.Expected.quoteMode, d.Table, index)
+}
+
+func (d *SchemaDiff) addColumnSQL(column ColumnSchema) string {
+	// 唯一约束作为索引来添加
+	column.Unique = false
+	addColumn := schemaOf(d.Dialect).AddColumn
+	if addColumn == "" {
+		addColumn = "ADD COLUMN"
+	}
+	return "ALTER TABLE " + d.quote(d.Table) + " " + addColumn + " " + columnDefinition(d.Dialect, d.Expected.quoteMode, column)
+}
+
+func (d *SchemaDiff) dropColumnSQL(column ColumnSchema) string {
+	return "ALTER TABLE " + d.quote(d.Table) + " DROP COLUMN " + d.quote(column.Name)
+}
+
+func (d *SchemaDiff) alterColumnSQL(from, to ColumnSchema) []string {
+	to.Unique = false
+	return schemaOf(d.Dialect).alterColumnSQL(d.quote(d.Table), d.quote(to.Name), from, to,
+		columnDefinition(d.Dialect, d.Expected.quoteMode, to))
+}
+
+func (d *SchemaDiff) dropIndexSQL(index IndexSchema) string {
+	return schemaOf(d.Dialect).dropIndexSQL(d.quote(d.Table), d.quote(index.Name), index.Constraint)
+}
+
+// UpSQL 返回将数据库中的表改成结构定义的语句，不会删除结构中没有的列
+func (d *SchemaDiff) UpSQL() []string {
+	if d.Actual == nil {
+		return createTableSQL(d.Dialect, d.Expected)
+	}
+
+	var sqlList []string
+	for _, index := range d.DropIndexes {
+		sqlList = append(sqlList, d.dropIndexSQL(index))
+	}
+	for _, column := range d.AddColumns {
+		sqlList = append(sqlList, d.addColumnSQL(column))
+	}
+	for _, change := range d.AlterColumns {
+		sqlList = append(sqlList, d.alterColumnSQL(change.From, change.To)...)
+	}
+	for _, index := range d.AddIndexes {
+		sqlList = append(sqlList, d.createIndexSQL(index))
+	}
+	return sqlList
+}
+
+// DownSQL 返回回滚 UpSQL 的语句
+func (d *SchemaDiff) DownSQL() []string {
+	if d.Actual == nil {
+		return []string{"DROP TABLE " + d.quote(d.Table)}
+	}
+
+	var sqlList []string
+	for idx := len(d.AddIndexes) - 1; idx >= 0; idx-- {
+		sqlList = append(sqlList, d.dropIndexSQL(d.AddIndexes[idx]))
+	}
+	for idx := len(d.AlterColumns) - 1; idx >= 0; idx-- {
+		change := d.AlterColumns[idx]
+		sqlList = append(sqlList, d.alterColumnSQL(change.To, change.From)...)
+	}
+	for idx := len(d.AddColumns) - 1; idx >= 0; idx-- {
+		sqlList = append(sqlList, d.dropColumnSQL(d.AddColumns[idx]))
+	}
+	for idx := len(d.DropIndexes) - 1; idx >= 0; idx-- {
+		sqlList = append(sqlList, d.createIndexSQL(d.DropIndexes[idx]))
+	}
+	return sqlList
+}
+
+// Scripts 返回升级和回滚的迁移脚本，可以保存为 NNN_name.up.sql 和 NNN_name.down.sql 后用 Migrator 执行。
+//
+// 结构中没有的列和主键的变化只作为注释写在升级脚本中，请确认后自已修改
+func (d *SchemaDiff) Scripts() (up, down string) {
+	var sb strings.Builder
+	for _, column := range d.DropColumns {
+		sb.WriteString("-- column " + column.Name + " isnot exists in the struct\n")
+		sb.WriteString("-- " + d.dropColumnSQL(column) + ";\n")
+	}
+	if d.Actual != nil && d.PrimaryKeyChanged {
+		sb.WriteString("-- primary key is changed from (" + strings.Join(d.Actual.PrimaryKey, ", ") + ") to (" + strings.Join(d.Expected.PrimaryKey, ", ") + ")\n")
+	}
+	writeScript(&sb, d.UpSQL())
+	up = sb.String()
+
+	sb.Reset()
+	writeScript(&sb, d.DownSQL())
+	return up, sb.String()
+}
+
+// writeScript 按 splitSQLStatements 的规则写语句，有分号的语句用 StatementBegin 和 StatementEnd 包起来
+func writeScript(sb *strings.Builder, sqlList []string) {
+	for _, sqlStr := range sqlList {
+		sqlStr = strings.Replace(sqlStr, "\r\n", "\n", -1)
+		if strings.Contains(sqlStr, ";") {
+			sb.WriteString(sqlCmdPrefix + "StatementBegin\n")
+			sb.WriteString(sqlStr)
+			sb.WriteString(";\n")
+			sb.WriteString(sqlCmdPrefix + "StatementEnd\n")
+			continue
+		}
+		sb.WriteString(sqlStr)
+		sb.WriteString(";\n")
+	}
+}
+
+// DiffSchema 比较结构的定义和数据库中的表结构，返回有差异的表
+func DiffSchema(ctx context.Context, db DBRunner, dbType Dialect, mapper *Mapper, types ...reflect.Type) ([]*SchemaDiff, error) {
+	var diffs []*SchemaDiff
+	for _, rType := range types {
+		expected, err := ReadStructSchema(dbType, mapper, rType)
+		if err != nil {
+			return nil, err
+		}
+		actual, err := ReadTableSchema(ctx, db, dbType, expected.Name)
+		if err != nil {
+			return nil, err
+		}
+		if diff := DiffTableSchema(dbType, expected, actual); !diff.IsEmpty() {
+			diffs = append(diffs, diff)
+		}
+	}
+	return diffs, nil
+}
+
+// DiffSchema 比较结构的定义和数据库中的表结构，返回有差异的表
+func (conn *Connection) DiffSchema(ctx context.Context, types ...reflect.Type) ([]*SchemaDiff, error) {
+	return DiffSchema(ctx, conn.db, conn.dialect, conn.mapper, types...)
+}
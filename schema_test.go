@@ -0,0 +1,133 @@
+package gobatis_test
+
+import (
+	"reflect"
+	"testing"
+
+	gobatis "github.com/runner-mei/GoBatis"
+)
+
+type SchemaUser struct {
+	TableName gobatis.TableName `db:"schema_users"`
+	ID        int64             `db:"id,pk,autoincr"`
+	Name      string            `db:"name,notnull,unique"`
+	Nickname  string            `db:"nickname,varchar(64)"`
+	Age       int32             `db:"age"`
+	Email     string            `db:"email,index"`
+}
+
+func TestDiffTableSchema(t *testing.T) {
+	mapper := gobatis.CreateMapper("", nil, nil)
+	expected, err := gobatis.ReadStructSchema(gobatis.DbTypePostgres, mapper, reflect.TypeOf(&SchemaUser{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	diff := gobatis.DiffTableSchema(gobatis.DbTypePostgres, expected, nil)
+	if s := diff.String(); s != "table schema_users isnot exists" {
+		t.Error(s)
+	}
+	up, down := diff.Scripts()
+	if up != "CREATE TABLE schema_users (\n  id bigserial NOT NULL,\n  name varchar(255) NOT NULL UNIQUE,\n  nickname varchar(64),\n  age integer,\n  email varchar(255),\n  PRIMARY KEY(id)\n);\nCREATE INDEX idx_schema_users_email ON schema_users(email);\n" {
+		t.Error(up)
+	}
+	if down != "DROP TABLE schema_users;\n" {
+		t.Error(down)
+	}
+
+	actual := &gobatis.TableSchema{
+		Name: "schema_users",
+		Columns: []gobatis.ColumnSchema{
+			{Name: "id", Type: "bigint", NotNull: true},
+			{Name: "name", Type: "character varying(255)"},
+			{Name: "age", Type: "smallint"},
+			{Name: "email", Type: "character varying(255)"},
+			{Name: "phone", Type: "character varying(20)"},
+		},
+		PrimaryKey: []string{"id"},
+		Indexes: []gobatis.IndexSchema{
+			{Name: "schema_users_name_key", Unique: true, Constraint: true, Columns: []string{"name"}},
+			{Name: "idx_phone", Columns: []string{"phone"}},
+		},
+	}
+
+	diff = gobatis.DiffTableSchema(gobatis.DbTypePostgres, expected, actual)
+	if diff.IsEmpty() {
+		t.Error("diff is empty")
+	}
+	if s := diff.String(); s != "table schema_users:\r\n"+
+		"  + column nickname varchar(64)\r\n"+
+		"  - column phone character varying(20)\r\n"+
+		"  ~ column name character varying(255) -> varchar(255) NOT NULL\r\n"+
+		"  ~ column age smallint -> integer\r\n"+
+		"  + index idx_schema_users_email(email)\r\n"+
+		"  - index idx_phone(phone)" {
+		t.Error(s)
+	}
+
+	up, down = diff.Scripts()
+	if excepted := "-- column phone isnot exists in the struct\n" +
+		"-- ALTER TABLE schema_users DROP COLUMN phone;\n" +
+		"DROP INDEX idx_phone;\n" +
+		"ALTER TABLE schema_users ADD COLUMN nickname varchar(64);\n" +
+		"ALTER TABLE schema_users ALTER COLUMN name SET NOT NULL;\n" +
+		"ALTER TABLE schema_users ALTER COLUMN age TYPE integer;\n" +
+		"CREATE INDEX idx_schema_users_email ON schema_users(email);\n"; up != excepted {
+		t.Error("excepted is", excepted)
+		t.Error("actual   is", up)
+	}
+	if excepted := "DROP INDEX idx_schema_users_email;\n" +
+		"ALTER TABLE schema_users ALTER COLUMN age TYPE smallint;\n" +
+		"ALTER TABLE schema_users ALTER COLUMN name DROP NOT NULL;\n" +
+		"ALTER TABLE schema_users DROP COLUMN nickname;\n" +
+		"CREATE INDEX idx_phone ON schema_users(phone);\n"; down != excepted {
+		t.Error("excepted is", excepted)
+		t.Error("actual   is", down)
+	}
+
+	expected, err = gobatis.ReadStructSchema(gobatis.DbTypeMysql, mapper, reflect.TypeOf(&SchemaUser{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual = &gobatis.TableSchema{
+		Name: "schema_users",
+		Columns: []gobatis.ColumnSchema{
+			{Name: "id", Type: "bigint(20)", NotNull: true},
+			{Name: "name", Type: "varchar(255)", NotNull: true},
+			{Name: "nickname", Type: "varchar(64)"},
+			{Name: "age", Type: "int(11)"},
+			{Name: "email", Type: "varchar(255)"},
+		},
+		PrimaryKey: []string{"id"},
+		Indexes: []gobatis.IndexSchema{
+			{Name: "name", Unique: true, Columns: []string{"name"}},
+			{Name: "email", Columns: []string{"email"}},
+		},
+	}
+	diff = gobatis.DiffTableSchema(gobatis.DbTypeMysql, expected, actual)
+	if !diff.IsEmpty() {
+		t.Error(diff.String())
+	}
+
+	expected, err = gobatis.ReadStructSchema(gobatis.DbTypePostgres, mapper, reflect.TypeOf(&DDLOrder{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual = &gobatis.TableSchema{
+		Name:       "order",
+		Columns:    []gobatis.ColumnSchema{{Name: "id", Type: "bigint", NotNull: true}},
+		PrimaryKey: []string{"id"},
+	}
+	diff = gobatis.DiffTableSchema(gobatis.DbTypePostgres, expected, actual)
+	up, down = diff.Scripts()
+	if excepted := "ALTER TABLE \"order\" ADD COLUMN \"user\" varchar(255);\n" +
+		"CREATE INDEX idx_order_user ON \"order\"(\"user\");\n"; up != excepted {
+		t.Error("excepted is", excepted)
+		t.Error("actual   is", up)
+	}
+	if excepted := "DROP INDEX idx_order_user;\n" +
+		"ALTER TABLE \"order\" DROP COLUMN \"user\";\n"; down != excepted {
+		t.Error("excepted is", excepted)
+		t.Error("actual   is", down)
+	}
+}
@@ -69,6 +69,9 @@ func (o *SessionFactory) Begin(nativeTx ...DBRunner) (tx *Tx, err error) {
 	}
 
 	tx.base.db = native
+	// 事务中的查询总是在事务中执行
+	tx.base.replicas = nil
+	tx.base.shards = tx.base.shards.inTx(o.base.db)
 	return tx, err
 }
 
@@ -77,6 +80,8 @@ func (o *SessionFactory) WithTx(nativeTx DBRunner) *Tx {
 	tx := new(Tx)
 	tx.Session = o.Session
 	tx.base.db = nativeTx
+	tx.base.replicas = nil
+	tx.base.shards = tx.base.shards.inTx(o.base.db)
 	return tx
 }
 
@@ -85,6 +90,11 @@ func (o *SessionFactory) WithTx(nativeTx DBRunner) *Tx {
 //如：
 //  err := o.Close()
 func (o *SessionFactory) Close() (err error) {
+	if o.base.watcher != nil {
+		o.base.watcher.Close()
+		o.base.watcher = nil
+	}
+
 	if o.base.db == nil {
 		err = fmt.Errorf("db no opened")
 	} else {
@@ -146,6 +156,16 @@ func (sess *Session) Dialect() Dialect {
 	return sess.base.Dialect()
 }
 
+// StatementOverrides 返回初始化时被覆盖的语句
+func (sess *Session) StatementOverrides() []StatementOverride {
+	return sess.base.StatementOverrides()
+}
+
+// CheckResultColumns 检查 select 语句返回的列和它声明的结果类型的字段是否一致，见 Connection.CheckResultColumns
+func (sess *Session) CheckResultColumns(ctx context.Context) []ResultCheck {
+	return sess.base.CheckResultColumns(ctx)
+}
+
 func (sess *Session) Reference() Reference {
 	return Reference{&sess.base}
 }
@@ -655,3 +655,75 @@ func TestSession(t *testing.T) {
 		})
 	})
 }
+
+func TestLoadXMLErrorPosition(t *testing.T) {
+	tmp := filepath.Join(getGoBatis(), "tmp")
+	if err := os.MkdirAll(tmp, 0666); err != nil && !os.IsExist(err) {
+		t.Error(err)
+		return
+	}
+
+	for _, test := range []struct {
+		xml          string
+		line, column int
+	}{
+		{
+			xml: `<?xml version="1.0" encoding="utf-8"?>
+<gobatis>
+	<select id="selectError" >
+		SELECT FROM #{
+	</select>
+</gobatis>`,
+			line:   4,
+			column: 15,
+		},
+		{
+			xml: `<?xml version="1.0" encoding="utf-8"?>
+<gobatis>
+	<select id="selectError" >SELECT <where> <if test="+++">a</if></where></select>
+</gobatis>`,
+			line:   3,
+			column: 43,
+		},
+		{
+			xml: `<?xml version="1.0" encoding="utf-8"?>
+<gobatis>
+	<select id="selectError" result="map"></select>
+</gobatis>`,
+			line:   3,
+			column: 40,
+		},
+		{
+			xml: `<?xml version="1.0" encoding="utf-8"?>
+<gobatis>
+	<select id="selectError" >
+		SELECT FROM #{
+	</select>`,
+			line: 5,
+		},
+	} {
+		pa := filepath.Join(tmp, "a.xml")
+		if err := ioutil.WriteFile(pa, []byte(test.xml), 0644); err != nil {
+			t.Error(err)
+			break
+		}
+
+		_, err := gobatis.New(&gobatis.Config{DriverName: tests.TestDrv,
+			DataSource: tests.TestConnURL,
+			XMLPaths:   []string{pa}})
+		if err == nil {
+			t.Error("excepted is error got ok")
+			continue
+		}
+
+		sqlErr, ok := err.(*gobatis.SQLError)
+		if !ok {
+			t.Errorf("except *SQLError got %T", err)
+			continue
+		}
+		if sqlErr.File != pa || sqlErr.Line != test.line || (test.column > 0 && sqlErr.Column != test.column) {
+			t.Errorf("except %s:%d:%d", pa, test.line, test.column)
+			t.Error("actual", err)
+		}
+	}
+}
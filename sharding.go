@@ -0,0 +1,239 @@
+package gobatis
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ErrShardKeyMissing 语句需要分片但参数中没有分片键时返回
+var ErrShardKeyMissing = errors.New("shard key isnot found in the parameters")
+
+// ErrMultipleShardRows ScatterGather 时 SelectOne 的语句在多个分片上都返回了记录时返回，
+// 如没有分片键的 count 语句，这时只能按分片键查询
+var ErrMultipleShardRows = errors.New("more than one shard returns rows, the shard key is required")
+
+// shardPlaceholder 是表名中分片序号的占位符，如 `db:"orders_{shard}"`
+const shardPlaceholder = "{shard}"
+
+// ShardTableName 返回表名在指定分片上的名称，如 orders_{shard} 在分片 1 上为 orders_1
+func ShardTableName(table string, shard int) string {
+	return strings.Replace(table, shardPlaceholder, strconv.Itoa(shard), -1)
+}
+
+// ShardRouter 根据分片键的值返回分片的序号(从 0 开始)
+type ShardRouter func(key interface{}) (int, error)
+
+// HashShards 返回一个按分片键取模的 ShardRouter，分片键不是整数时按它的 fnv hash 值取模
+func HashShards(n int) ShardRouter {
+	return func(key interface{}) (int, error) {
+		if i, ok := int64Value(key); ok {
+			shard := int(i % int64(n))
+			if shard < 0 {
+				shard += n
+			}
+			return shard, nil
+		}
+
+		h := fnv.New32a()
+		h.Write([]byte(fmt.Sprint(key)))
+		return int(h.Sum32() % uint32(n)), nil
+	}
+}
+
+// ShardRange 是分片键的一个范围，包含 Min 但不包含 Max
+type ShardRange struct {
+	Min   int64
+	Max   int64
+	Shard int
+}
+
+// RangeShards 返回一个按分片键的范围分片的 ShardRouter，分片键必须是整数
+func RangeShards(ranges ...ShardRange) ShardRouter {
+	return func(key interface{}) (int, error) {
+		i, ok := int64Value(key)
+		if !ok {
+			return 0, errors.New("shard key '" + fmt.Sprint(key) + "' isnot a integer")
+		}
+		for _, r := range ranges {
+			if r.Min <= i && i < r.Max {
+				return r.Shard, nil
+			}
+		}
+		return 0, errors.New("shard key '" + strconv.FormatInt(i, 10) + "' isnot in any range")
+	}
+}
+
+// Sharding 是分库分表的配置
+type Sharding struct {
+	// Key 是分片键的参数名，参数是一个结构时也可以是它的字段名或列名
+	Key string
+	// Count 是分片的数量，为 0 时等于 len(Shards)
+	Count int
+	// Shards 是分片所在的数据库，分片 i 在 Shards[i % len(Shards)] 上，
+	// 为空时所有的分片都在 Config.DB 上(只分表不分库)。事务是在 Config.DB 上打开的，
+	// 所以事务中只能访问在 Config.DB 上的分片
+	Shards []DBRunner
+	// Router 根据分片键选择分片，为 nil 时为 HashShards(Count)
+	Router ShardRouter
+	// ScatterGather 为 true 时，参数中没有分片键的 select 语句会在所有的分片上执行，
+	// 结果按分片的顺序合并，否则返回 ErrShardKeyMissing。只返回一条记录的语句(SelectOne)
+	// 在多个分片上都有记录时返回 ErrMultipleShardRows，而不是只返回其中一个分片的结果
+	ScatterGather bool
+}
+
+type shardSet struct {
+	key     string
+	count   int
+	dbs     []DBRunner
+	router  ShardRouter
+	scatter bool
+	// txDB 是事务所在的数据库，不为 nil 时表示在事务中
+	txDB DBRunner
+}
+
+func newShardSet(cfg *Sharding) (*shardSet, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	if cfg.Key == "" {
+		return nil, errors.New("shard key is empty")
+	}
+
+	count := cfg.Count
+	if count <= 0 {
+		count = len(cfg.Shards)
+	}
+	if count <= 0 {
+		return nil, errors.New("shard count is zero")
+	}
+
+	router := cfg.Router
+	if router == nil {
+		router = HashShards(count)
+	}
+	return &shardSet{
+		key:     cfg.Key,
+		count:   count,
+		dbs:     cfg.Shards,
+		router:  router,
+		scatter: cfg.ScatterGather,
+	}, nil
+}
+
+// inTx 返回在 db 上打开的事务中使用的分片，事务中的语句仍然会替换表名，
+// 分片在 db 上时在事务中执行，在其它数据库上时返回错误
+func (s *shardSet) inTx(db DBRunner) *shardSet {
+	if s == nil {
+		return nil
+	}
+	txShards := *s
+	txShards.txDB = db
+	return &txShards
+}
+
+func (s *shardSet) readKey(dialect Dialect, mapper *Mapper, paramNames []string, paramValues []interface{}) (interface{}, bool) {
+	sqlCtx, err := NewContext(dialect, mapper, paramNames, paramValues)
+	if err != nil {
+		return nil, false
+	}
+	// 没有参数名时无法知道唯一的参数是不是分片键
+	if _, ok := sqlCtx.finder.(singleFinder); ok {
+		return nil, false
+	}
+
+	key, err := sqlCtx.Get(s.key)
+	if err != nil || key == nil {
+		return nil, false
+	}
+	rValue := reflect.ValueOf(key)
+	for rValue.Kind() == reflect.Ptr {
+		if rValue.IsNil() {
+			return nil, false
+		}
+		rValue = rValue.Elem()
+	}
+	return rValue.Interface(), true
+}
+
+type shardSQL struct {
+	db           DBRunner
+	sqlAndParams []sqlAndParam
+}
+
+func (s *shardSet) at(conn *Connection, shard int, sqlAndParams []sqlAndParam) (shardSQL, error) {
+	db := conn.db
+	if len(s.dbs) > 0 {
+		db = s.dbs[shard%len(s.dbs)]
+		if s.txDB != nil {
+			if db != s.txDB {
+				return shardSQL{}, errors.New("shard '" + strconv.Itoa(shard) + "' isnot on the database of the transaction")
+			}
+			db = conn.db
+		}
+	}
+
+	rewritten := make([]sqlAndParam, len(sqlAndParams))
+	for idx := range sqlAndParams {
+		rewritten[idx] = sqlAndParam{
+			SQL:    ShardTableName(sqlAndParams[idx].SQL, shard),
+			Params: sqlAndParams[idx].Params,
+		}
+	}
+	return shardSQL{db: db, sqlAndParams: rewritten}, nil
+}
+
+func hasShardPlaceholder(sqlAndParams []sqlAndParam) bool {
+	for idx := range sqlAndParams {
+		if strings.Contains(sqlAndParams[idx].SQL, shardPlaceholder) {
+			return true
+		}
+	}
+	return false
+}
+
+// route 返回语句要执行的分片，没有配置分片或语句与分片无关时返回 nil。
+//
+// 参数中有分片键时返回它所在的分片，没有分片键但表名中有 {shard} 时，
+// isSelect 为 true 且允许 ScatterGather 则返回所有的分片，否则返回 ErrShardKeyMissing
+func (conn *Connection) route(paramNames []string, paramValues []interface{}, sqlAndParams []sqlAndParam, isSelect bool) ([]shardSQL, error) {
+	if conn.shards == nil {
+		return nil, nil
+	}
+
+	key, ok := conn.shards.readKey(conn.dialect, conn.mapper, paramNames, paramValues)
+	if ok {
+		shard, err := conn.shards.router(key)
+		if err != nil {
+			return nil, err
+		}
+		if shard < 0 || shard >= conn.shards.count {
+			return nil, errors.New("shard '" + strconv.Itoa(shard) + "' is out of range, shard count is " + strconv.Itoa(conn.shards.count))
+		}
+		at, err := conn.shards.at(conn, shard, sqlAndParams)
+		if err != nil {
+			return nil, err
+		}
+		return []shardSQL{at}, nil
+	}
+
+	if !hasShardPlaceholder(sqlAndParams) {
+		return nil, nil
+	}
+	if !isSelect || !conn.shards.scatter {
+		return nil, ErrShardKeyMissing
+	}
+
+	all := make([]shardSQL, 0, conn.shards.count)
+	for shard := 0; shard < conn.shards.count; shard++ {
+		at, err := conn.shards.at(conn, shard, sqlAndParams)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, at)
+	}
+	return all, nil
+}
@@ -0,0 +1,273 @@
+package gobatis
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// shardDriver 的数据源名为数据库的名称，它记录在各个库上执行的语句，查询时返回 shardRows 中对应的记录，
+// 按 id 查询时 id 只在分片 id % 4 上
+var (
+	shardDriver = newFakeDriver("gobatis_shard")
+	shardRows   = map[string][]int64{"db0": {1, 2}, "db1": {3}}
+)
+
+func init() {
+	shardDriver.exec = func(name, query string, args []driver.Value) (driver.Result, error) {
+		shardDriver.record(name, query)
+		return driver.RowsAffected(1), nil
+	}
+	shardDriver.query = func(name, query string, args []driver.Value) (driver.Rows, error) {
+		shardDriver.record(name, query)
+		rows := &fakeRows{columns: []string{"id"}}
+		for _, id := range shardRows[name] {
+			if strings.Contains(query, "WHERE id =") &&
+				(args[0] != id || !strings.Contains(query, "orders_"+strconv.FormatInt(id%4, 10))) {
+				continue
+			}
+			rows.values = append(rows.values, []driver.Value{id})
+		}
+		return rows, nil
+	}
+}
+
+type shardOrder struct {
+	TableName TableName `db:"orders_{shard}"`
+	ID        int64     `db:"id,pk,autoincr"`
+	UserID    int64     `db:"user_id"`
+	Amount    int       `db:"amount"`
+}
+
+func TestShardRouters(t *testing.T) {
+	hash := HashShards(4)
+	for _, test := range []struct {
+		key      interface{}
+		excepted int
+	}{
+		{key: int64(5), excepted: 1},
+		{key: int32(-5), excepted: 3},
+		{key: uint(8), excepted: 0},
+	} {
+		actual, err := hash(test.key)
+		if err != nil {
+			t.Error(test.key, err)
+			continue
+		}
+		if actual != test.excepted {
+			t.Error(test.key, "excepted is", test.excepted, "actual is", actual)
+		}
+	}
+
+	if a, err := hash("abc"); err != nil {
+		t.Error(err)
+	} else if b, _ := hash("abc"); a != b || a < 0 || a >= 4 {
+		t.Error(a, b)
+	}
+
+	ranges := RangeShards(ShardRange{Min: 0, Max: 100, Shard: 0}, ShardRange{Min: 100, Max: 200, Shard: 1})
+	if shard, err := ranges(150); err != nil || shard != 1 {
+		t.Error(shard, err)
+	}
+	if _, err := ranges(200); err == nil || err.Error() != "shard key '200' isnot in any range" {
+		t.Error(err)
+	}
+	if _, err := ranges("a"); err == nil || err.Error() != "shard key 'a' isnot a integer" {
+		t.Error(err)
+	}
+
+	if s := ShardTableName("orders_{shard}", 3); s != "orders_3" {
+		t.Error(s)
+	}
+}
+
+func TestSharding(t *testing.T) {
+	callbacks := SetInit([]func(ctx *InitContext) error{
+		func(ctx *InitContext) error {
+			insertSQL, err := GenerateInsertSQL(ctx.Dialect, ctx.Mapper, reflect.TypeOf(&shardOrder{}), true)
+			if err != nil {
+				return err
+			}
+
+			for _, stmt := range []struct {
+				id      string
+				sqlType StatementType
+				sql     string
+			}{
+				{id: "orders.insert", sqlType: StatementTypeInsert, sql: insertSQL},
+				{id: "orders.list", sqlType: StatementTypeSelect, sql: "SELECT id FROM orders_{shard} WHERE user_id = #{user_id}"},
+				{id: "orders.all", sqlType: StatementTypeSelect, sql: "SELECT id FROM orders_{shard}"},
+				{id: "orders.get", sqlType: StatementTypeSelect, sql: "SELECT id FROM orders_{shard} WHERE id = #{id}"},
+				{id: "orders.clear", sqlType: StatementTypeDelete, sql: "DELETE FROM orders_{shard}"},
+				{id: "users.all", sqlType: StatementTypeSelect, sql: "SELECT id FROM users"},
+			} {
+				s, err := NewMapppedStatement(ctx, stmt.id, stmt.sqlType, ResultStruct, stmt.sql)
+				if err != nil {
+					return err
+				}
+				if err := ctx.RegisterStatement(s); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+	defer SetInit(callbacks)
+
+	open := func(name string) *sql.DB {
+		db, err := sql.Open("gobatis_shard", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		db.SetMaxOpenConns(1)
+		return db
+	}
+	primary, db0, db1 := open("primary"), open("db0"), open("db1")
+	defer primary.Close()
+	defer db0.Close()
+	defer db1.Close()
+
+	conn, err := newConnection(&Config{DriverName: "postgres", DB: primary, Sharding: &Sharding{
+		Key:           "user_id",
+		Count:         4,
+		Shards:        []DBRunner{db0, db1},
+		ScatterGather: true,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	shardDriver.reset()
+
+	assertExecs := func(name string, excepted ...string) {
+		t.Helper()
+		actual := shardDriver.reset()
+		if !reflect.DeepEqual(actual, excepted) {
+			t.Error(name)
+			t.Error("excepted is", excepted)
+			t.Error("actual   is", actual)
+		}
+	}
+
+	ctx := context.Background()
+
+	_, err = conn.Insert(ctx, "orders.insert", []string{"order"}, []interface{}{&shardOrder{UserID: 6, Amount: 3}}, true)
+	if err != nil {
+		t.Error(err)
+	}
+	assertExecs("insert", "db0: INSERT INTO orders_2(user_id, amount) VALUES($1, $2)")
+
+	var ids []int64
+	err = conn.Select(ctx, "orders.list", []string{"user_id"}, []interface{}{int64(5)}).ScanSlice(&ids)
+	if err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(ids, []int64{3}) {
+		t.Error(ids)
+	}
+	assertExecs("select", "db1: SELECT id FROM orders_1 WHERE user_id = $1")
+
+	ids = nil
+	err = conn.Select(ctx, "orders.all", nil, nil).ScanSlice(&ids)
+	if err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(ids, []int64{1, 2, 3, 1, 2, 3}) {
+		t.Error(ids)
+	}
+	assertExecs("scatter",
+		"db0: SELECT id FROM orders_0",
+		"db1: SELECT id FROM orders_1",
+		"db0: SELECT id FROM orders_2",
+		"db1: SELECT id FROM orders_3")
+
+	ids = nil
+	results := conn.Select(ctx, "orders.all", nil, nil)
+	for results.Next() {
+		var id int64
+		if err := results.Scan(&id); err != nil {
+			t.Error(err)
+			break
+		}
+		ids = append(ids, id)
+	}
+	if err := results.Err(); err != nil {
+		t.Error(err)
+	}
+	results.Close()
+	if !reflect.DeepEqual(ids, []int64{1, 2, 3, 1, 2, 3}) {
+		t.Error(ids)
+	}
+	shardDriver.reset()
+
+	// SelectOne 只能有一个分片返回记录
+	var id int64
+	if err := conn.SelectOne(ctx, "orders.get", []string{"id"}, []interface{}{int64(3)}).Scan(&id); err != nil {
+		t.Error(err)
+	} else if id != 3 {
+		t.Error(id)
+	}
+	assertExecs("select one",
+		"db0: SELECT id FROM orders_0 WHERE id = $1",
+		"db1: SELECT id FROM orders_1 WHERE id = $1",
+		"db0: SELECT id FROM orders_2 WHERE id = $1",
+		"db1: SELECT id FROM orders_3 WHERE id = $1")
+
+	if err := conn.SelectOne(ctx, "orders.all", nil, nil).Scan(&id); err != ErrMultipleShardRows {
+		t.Error("excepted error is", ErrMultipleShardRows, "actual is", err)
+	}
+	assertExecs("select one from multiple shards",
+		"db0: SELECT id FROM orders_0",
+		"db1: SELECT id FROM orders_1")
+
+	if _, err := conn.Delete(ctx, "orders.clear", nil, nil); err != ErrShardKeyMissing {
+		t.Error("excepted error is", ErrShardKeyMissing, "actual is", err)
+	}
+	assertExecs("delete")
+
+	ids = nil
+	if err := conn.Select(ctx, "users.all", nil, nil).ScanSlice(&ids); err != nil {
+		t.Error(err)
+	}
+	assertExecs("not sharded", "primary: SELECT id FROM users")
+
+	// 事务是在 Config.DB 上打开的，只能访问在它上面的分片
+	txConn, err := newConnection(&Config{DriverName: "postgres", DB: db0, Sharding: &Sharding{
+		Key:    "user_id",
+		Count:  4,
+		Shards: []DBRunner{db0, db1},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	factory := &SessionFactory{Session: Session{base: *txConn}}
+	tx, err := factory.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.base.Delete(ctx, "orders.clear", []string{"user_id"}, []interface{}{int64(6)}); err != nil {
+		t.Error(err)
+	}
+	assertExecs("tx", "db0: DELETE FROM orders_2")
+
+	_, err = tx.base.Delete(ctx, "orders.clear", []string{"user_id"}, []interface{}{int64(7)})
+	if err == nil || err.Error() != "shard '3' isnot on the database of the transaction" {
+		t.Error(err)
+	}
+	assertExecs("tx on other database")
+	if err := tx.Rollback(); err != nil {
+		t.Error(err)
+	}
+
+	tx = factory.WithTx(primary)
+	if _, err := tx.base.Delete(ctx, "orders.clear", []string{"user_id"}, []interface{}{int64(6)}); err != nil {
+		t.Error(err)
+	}
+	assertExecs("tx with native", "primary: DELETE FROM orders_2")
+
+	conn.shards.scatter = false
+	if err := conn.Select(ctx, "orders.all", nil, nil).ScanSlice(&ids); err != ErrShardKeyMissing {
+		t.Error("excepted error is", ErrShardKeyMissing, "actual is", err)
+	}
+}
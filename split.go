@@ -35,15 +35,25 @@ func endsWithSemicolon(line string) bool {
 // 'StatementBegin' and 'StatementEnd' to allow the script to
 // tell us to ignore semicolons.
 func splitSQLStatements(r io.Reader) (stmts []string) {
+	stmts, _ = splitSQLStatementsWithLines(r)
+	return stmts
+}
+
+// splitSQLStatementsWithLines 同 splitSQLStatements, 同时返回每个语句的起始行号
+func splitSQLStatementsWithLines(r io.Reader) (stmts []string, lines []int) {
 	var buf strings.Builder
 	scanner := bufio.NewScanner(r)
 
+	lineNo := 0
+	startLine := 0
+
 	statementEnded := false
 	ignoreSemicolons := false
 
 	isFirst := true
 	for scanner.Scan() {
 		text := scanner.Text()
+		lineNo++
 
 		if line := strings.TrimSpace(text); strings.HasPrefix(line, "--") {
 			// handle any gobatis-specific commands
@@ -61,6 +71,13 @@ func splitSQLStatements(r io.Reader) (stmts []string) {
 				}
 			}
 		} else {
+			if startLine == 0 {
+				startLine = lineNo
+				if !isFirst {
+					// 语句的前面会多一个换行
+					startLine--
+				}
+			}
 			if isFirst {
 				isFirst = false
 			} else {
@@ -74,7 +91,9 @@ func splitSQLStatements(r io.Reader) (stmts []string) {
 		if !ignoreSemicolons && (statementEnded || endsWithSemicolon(text)) {
 			statementEnded = false
 			stmts = append(stmts, buf.String())
+			lines = append(lines, startLine)
 			buf.Reset()
+			startLine = 0
 		}
 	}
 
@@ -86,6 +105,7 @@ func splitSQLStatements(r io.Reader) (stmts []string) {
 		stmt := strings.TrimSpace(buf.String())
 		if stmt != "" {
 			stmts = append(stmts, buf.String())
+			lines = append(lines, startLine)
 		}
 	}
 
@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"regexp"
+	"strconv"
 	"strings"
 	"text/template"
 )
@@ -121,6 +123,12 @@ type MappedStatement struct {
 	result      ResultType
 	rawSQL      string
 	dynamicSQLs []DynamicSQL
+	source      StatementSource
+}
+
+// Source 返回语句定义的位置
+func (stmt *MappedStatement) Source() StatementSource {
+	return stmt.source
 }
 
 type DynamicSQL interface {
@@ -153,11 +161,14 @@ func NewMapppedStatement(ctx *InitContext, id string, statementType StatementTyp
 		ctx.Logger.Println("WARN: sql statement contains ${}, replace it with #{}?")
 	}
 
-	sqlList := splitSQLStatements(strings.NewReader(sqlStr))
+	sqlList, lines := splitSQLStatementsWithLines(strings.NewReader(sqlStr))
 	for idx := range sqlList {
 		sql, err := createSQL(ctx, id, sqlList[idx], sqlStr, len(sqlList) == 1)
 		if err != nil {
-			return nil, err
+			e := toSQLError(err)
+			e.shift(lines[idx], 1)
+			e.ID = id
+			return nil, e
 		}
 
 		stmt.dynamicSQLs = append(stmt.dynamicSQLs, sql)
@@ -165,16 +176,27 @@ func NewMapppedStatement(ctx *InitContext, id string, statementType StatementTyp
 	return stmt, nil
 }
 
+var templateErrorLine = regexp.MustCompile(`^template: [^:]*:(\d+):`)
+
 func createSQL(ctx *InitContext, id, sqlStr, fullText string, one bool) (DynamicSQL, error) {
 	if strings.Contains(sqlStr, "{{") {
+		if ctx.lenient {
+			// 检查引用时无法知道模板中引用了什么，也不知道自定义的模板函数
+			return &templateSQL{}, nil
+		}
 		funcMap := ctx.Config.TemplateFuncs
 		tpl, err := template.New(id).Funcs(funcMap).Parse(sqlStr)
 		if err != nil {
-			return nil, errors.New("sql is invalid go template of '" + id + "', " + err.Error() + "\r\n\t" + sqlStr)
+			e := &SQLError{Message: "sql is invalid go template of '" + id + "', " + err.Error() + "\r\n\t" + sqlStr}
+			if ss := templateErrorLine.FindStringSubmatch(err.Error()); len(ss) > 1 {
+				e.Line, _ = strconv.Atoi(ss[1])
+				e.Column = 1
+			}
+			return nil, e
 		}
 
 		if hasXMLTag(sqlStr) {
-			return nil, errors.New("sql is invalid go template of '" + id + "', becase xml tag is exists in:\r\n\t" + sqlStr)
+			return nil, &SQLError{Message: "sql is invalid go template of '" + id + "', becase xml tag is exists in:\r\n\t" + sqlStr}
 		}
 
 		return &templateSQL{sqlTemplate: tpl}, nil
@@ -182,22 +204,23 @@ func createSQL(ctx *InitContext, id, sqlStr, fullText string, one bool) (Dynamic
 
 	// http://www.mybatis.org/mybatis-3/dynamic-sql.html
 	if hasXMLTag(sqlStr) {
-		dynamicSQL, err := loadDynamicSQLFromXML(sqlStr)
+		dynamicSQL, err := loadDynamicSQLFromXML(ctx, sqlStr)
 		if err != nil {
-			return nil, errors.New("sql is invalid dynamic sql of '" + id + "', " + err.Error() + "\r\n\t" + sqlStr)
+			return nil, wrapSQLError(err, "sql is invalid dynamic sql of '"+id+"', ", "\r\n\t"+sqlStr)
 		}
 		return dynamicSQL, nil
 	}
 
 	fragments, bindParams, err := compileNamedQuery(sqlStr)
 	if err != nil {
-		return nil, errors.New("sql is invalid named sql of '" + id + "', " + err.Error())
+		return nil, wrapSQLError(err, "sql is invalid named sql of '"+id+"', ", "")
 	}
 	if len(bindParams) != 0 {
 		return &parameterizedSQL{
 			rawSQL:     sqlStr,
 			dollarSQL:  Dollar.Concat(fragments, bindParams, 0),
 			questSQL:   Question.Concat(fragments, bindParams, 0),
+			colonSQL:   Colon.Concat(fragments, bindParams, 0),
 			bindParams: bindParams,
 		}, nil
 	}
@@ -224,11 +247,13 @@ func compileNamedQuery(txt string) ([]string, Params, error) {
 		s = s[idx+len("#{"):]
 		end := strings.Index(s, "}")
 		if end < 0 {
-			return nil, nil, errors.New(MarkSQLError(txt, seekPos))
+			line, column := textPosition(txt, seekPos)
+			return nil, nil, &SQLError{Line: line, Column: column, Message: MarkSQLError(txt, seekPos)}
 		}
 		param, err := parseParam(s[:end])
 		if err != nil {
-			return nil, nil, err
+			line, column := textPosition(txt, seekPos+len("#{"))
+			return nil, nil, &SQLError{Line: line, Column: column, Message: err.Error()}
 		}
 		argments = append(argments, param)
 
@@ -327,6 +352,7 @@ type parameterizedSQL struct {
 	rawSQL     string
 	dollarSQL  string
 	questSQL   string
+	colonSQL   string
 	bindParams Params
 }
 
@@ -338,6 +364,10 @@ func (stmt *parameterizedSQL) WithDollar() string {
 	return stmt.dollarSQL
 }
 
+func (stmt *parameterizedSQL) WithColon() string {
+	return stmt.colonSQL
+}
+
 func (stmt *parameterizedSQL) String() string {
 	return stmt.rawSQL
 }
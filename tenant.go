@@ -0,0 +1,87 @@
+package gobatis
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+)
+
+type tenantKey struct{}
+
+// ErrTenantMissing 语句中有 <tenant/> 但 context 中没有租户时返回，这时语句不会被执行
+var ErrTenantMissing = errors.New("tenant isnot found in the context")
+
+// WithTenant 返回一个带有租户的 context。
+//
+// 结构中有 tenant 标记的字段(如 `db:"tenant_id,tenant"`)时，生成的语句会自动加上 tenant_id = 租户 的条件，
+// insert 语句会自动加上租户列，xml 中的语句可以用 <tenant/> 元素加上这个条件
+func WithTenant(ctx context.Context, tenant interface{}) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext 返回 context 中的租户
+func TenantFromContext(ctx context.Context) (interface{}, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	tenant := ctx.Value(tenantKey{})
+	if tenant == nil {
+		return nil, false
+	}
+	return tenant, true
+}
+
+func findTenantField(mapper *Mapper, rType reflect.Type) *FieldInfo {
+	structType := mapper.TypeMap(rType)
+	for idx := range structType.Index {
+		if _, ok := structType.Index[idx].Options["tenant"]; ok {
+			return structType.Index[idx]
+		}
+	}
+	return nil
+}
+
+// tenantCondition 返回生成语句中的租户条件
+func tenantCondition(dbType Dialect, mapper *Mapper, field *FieldInfo) string {
+	column := strings.Replace(quoteName(dbType, mapper, field.Name), `"`, "&quot;", -1)
+	return `<tenant column="` + column + `"/>`
+}
+
+const tenantPlaceholder = `<tenant placeholder="true"/>`
+
+// tenantExpression 是 <tenant column="tenant_id"/> 元素，它输出 tenant_id = 租户，
+// placeholder 为 true 时只输出租户的参数，用于 insert 语句
+type tenantExpression struct {
+	prefix      string
+	suffix      string
+	column      string
+	placeholder bool
+}
+
+func (expr *tenantExpression) String() string {
+	if expr.placeholder {
+		return expr.prefix + `<tenant placeholder="true" />` + expr.suffix
+	}
+	return expr.prefix + `<tenant column="` + expr.column + `" />` + expr.suffix
+}
+
+func (expr *tenantExpression) writeTo(printer *sqlPrinter) {
+	tenant, ok := TenantFromContext(printer.ctx.stdCtx)
+	if !ok {
+		// 检查结果列时所有的参数都为 nil
+		if _, isNull := printer.ctx.finder.(nullFinder); !isNull {
+			printer.err = ErrTenantMissing
+			return
+		}
+	}
+
+	printer.sb.WriteString(expr.prefix)
+	if !expr.placeholder {
+		printer.sb.WriteString(expr.column)
+		printer.sb.WriteString(" = ")
+	}
+	printer.sb.WriteString(printer.ctx.Dialect.Placeholder().Concat([]string{"", ""}, nil, len(printer.params)))
+	printer.sb.WriteString(expr.suffix)
+	printer.params = append(printer.params, tenant)
+}
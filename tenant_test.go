@@ -0,0 +1,137 @@
+package gobatis
+
+import (
+	"context"
+	"log"
+	"os"
+	"reflect"
+	"testing"
+)
+
+type tenantUser struct {
+	TableName TableName `db:"tenant_users"`
+	ID        int64     `db:"id,pk,autoincr"`
+	TenantID  int64     `db:"tenant_id,tenant"`
+	Name      string    `db:"name"`
+}
+
+func TestTenantGenerateSQL(t *testing.T) {
+	mapper := CreateMapper("", nil, nil)
+	rType := reflect.TypeOf(&tenantUser{})
+
+	for _, test := range []struct {
+		name     string
+		generate func() (string, error)
+		excepted string
+	}{
+		{name: "insert", generate: func() (string, error) {
+			return GenerateInsertSQL(DbTypePostgres, mapper, rType, false)
+		}, excepted: `INSERT INTO tenant_users(tenant_id, name) VALUES(<tenant placeholder="true"/>, #{name}) RETURNING id`},
+		{name: "insert2", generate: func() (string, error) {
+			return GenerateInsertSQL2(DbTypePostgres, mapper, rType, []string{"name"}, true)
+		}, excepted: `INSERT INTO tenant_users(tenant_id, name) VALUES(<tenant placeholder="true"/>, #{name})`},
+		{name: "update", generate: func() (string, error) {
+			return GenerateUpdateSQL(DbTypePostgres, mapper, "", rType, nil, nil)
+		}, excepted: `UPDATE tenant_users SET name=#{name} WHERE id=#{id} AND <tenant column="tenant_id"/>`},
+		{name: "update2", generate: func() (string, error) {
+			return GenerateUpdateSQL2(DbTypePostgres, mapper, rType, reflect.TypeOf(int64(0)), "id", []string{"tenant_id", "name"})
+		}, excepted: `UPDATE tenant_users SET name=#{name} WHERE id=#{id} AND <tenant column="tenant_id"/>`},
+		{name: "delete", generate: func() (string, error) {
+			return GenerateDeleteSQL(DbTypePostgres, mapper, rType, nil, nil, nil)
+		}, excepted: `DELETE FROM tenant_users WHERE <tenant column="tenant_id"/>`},
+		{name: "select", generate: func() (string, error) {
+			return GenerateSelectSQL(DbTypePostgres, mapper, rType, []string{"name"}, []reflect.Type{reflect.TypeOf("")}, nil, "")
+		}, excepted: `SELECT * FROM tenant_users WHERE name=#{name} AND <tenant column="tenant_id"/>`},
+		{name: "count", generate: func() (string, error) {
+			return GenerateCountSQL(DbTypePostgres, mapper, rType, nil, nil, []Filter{{Expression: "id > 10"}})
+		}, excepted: `SELECT count(*) FROM tenant_users WHERE id > 10 AND <tenant column="tenant_id"/>`},
+	} {
+		actual, err := test.generate()
+		if err != nil {
+			t.Error(test.name, err)
+			continue
+		}
+		if actual != test.excepted {
+			t.Error(test.name)
+			t.Error("excepted is", test.excepted)
+			t.Error("actual   is", actual)
+		}
+	}
+}
+
+func TestTenantElement(t *testing.T) {
+	initCtx := &InitContext{Config: &Config{},
+		Logger:     log.New(os.Stdout, "[gobatis] ", log.Flags()),
+		Dialect:    DbTypePostgres,
+		Mapper:     CreateMapper("", nil, nil),
+		Statements: make(map[string]*MappedStatement)}
+
+	for _, test := range []struct {
+		sql         string
+		stdCtx      context.Context
+		exceptedSQL string
+		params      []interface{}
+		err         error
+	}{
+		{
+			sql:         `SELECT * FROM users WHERE name = #{name} AND <tenant/>`,
+			stdCtx:      WithTenant(context.Background(), 3),
+			exceptedSQL: `SELECT * FROM users WHERE name = $1 AND tenant_id = $2`,
+			params:      []interface{}{"abc", 3},
+		},
+		{
+			sql:         `SELECT * FROM users u <where><tenant column="u.tenant_id"/> <if test="isNotEmpty(name)">AND u.name = #{name}</if></where>`,
+			stdCtx:      WithTenant(context.Background(), "t1"),
+			exceptedSQL: `SELECT * FROM users u  WHERE u.tenant_id = $1 AND u.name = $2`,
+			params:      []interface{}{"t1", "abc"},
+		},
+		{
+			sql:         `INSERT INTO users(tenant_id, name) VALUES(<tenant placeholder="true"/>, #{name})`,
+			stdCtx:      WithTenant(context.Background(), 3),
+			exceptedSQL: `INSERT INTO users(tenant_id, name) VALUES($1, $2)`,
+			params:      []interface{}{3, "abc"},
+		},
+		{
+			sql:    `SELECT * FROM users WHERE name = #{name} AND <tenant/>`,
+			stdCtx: context.Background(),
+			err:    ErrTenantMissing,
+		},
+		{
+			sql: `DELETE FROM users WHERE <tenant/>`,
+			err: ErrTenantMissing,
+		},
+	} {
+		stmt, err := NewMapppedStatement(initCtx, "tenant", StatementTypeSelect, ResultStruct, test.sql)
+		if err != nil {
+			t.Error(test.sql, err)
+			continue
+		}
+
+		ctx, err := NewContext(initCtx.Dialect, initCtx.Mapper, []string{"name"}, []interface{}{"abc"})
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		ctx.stdCtx = test.stdCtx
+
+		sqlAndParams, err := stmt.GenerateSQLs(ctx)
+		if test.err != nil {
+			if err != test.err {
+				t.Error(test.sql, "excepted error is", test.err, "actual is", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Error(test.sql, err)
+			continue
+		}
+		if sqlAndParams[0].SQL != test.exceptedSQL {
+			t.Error("excepted is", test.exceptedSQL)
+			t.Error("actual   is", sqlAndParams[0].SQL)
+		}
+		if !reflect.DeepEqual(sqlAndParams[0].Params, test.params) {
+			t.Error("excepted is", test.params)
+			t.Error("actual   is", sqlAndParams[0].Params)
+		}
+	}
+}
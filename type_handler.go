@@ -1,5 +1,247 @@
 package gobatis
 
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/runner-mei/GoBatis/reflectx"
+)
+
+// TypeHandler 负责自定义的 go 类型和数据库中的值之间的转换，
+// 如第三方库中的 decimal 或 uuid 类型，这样不用再给它们包一层 sql.Scanner 和 driver.Valuer
+//
+// 当字段或参数是指针时，TypeHandler 只会收到非 nil 的值，nil 指针总是对应 NULL
 type TypeHandler interface {
-	Convert()
+	// ToSQLValue 将 value 转换为数据库驱动能接受的值
+	ToSQLValue(dialect Dialect, param *Param, value interface{}) (interface{}, error)
+
+	// FromSQLValue 将数据库中的值 src 写入 dest，dest 是一个指向目标类型的指针，
+	// src 为 nil 时表示 NULL
+	FromSQLValue(dialect Dialect, column string, src interface{}, dest interface{}) error
+}
+
+// TypeHandlerFuncs 用两个函数实现 TypeHandler
+type TypeHandlerFuncs struct {
+	Encode func(dialect Dialect, value interface{}) (interface{}, error)
+	Decode func(dialect Dialect, src interface{}, dest interface{}) error
+}
+
+func (h TypeHandlerFuncs) ToSQLValue(dialect Dialect, param *Param, value interface{}) (interface{}, error) {
+	return h.Encode(dialect, value)
+}
+
+func (h TypeHandlerFuncs) FromSQLValue(dialect Dialect, column string, src interface{}, dest interface{}) error {
+	if err := h.Decode(dialect, src, dest); err != nil {
+		return fmt.Errorf("column %s convert to '%T' fail, %s", column, dest, err)
+	}
+	return nil
+}
+
+// TypeHandlers 是 TypeHandler 的注册表，可以按类型(也可以只针对某种数据库)注册，
+// 也可以按名称注册，然后在字段的 tag 中用 handler 选项指定，如 `db:"price,handler=money"`
+//
+// 注册应在创建 SessionFactory 之前完成，已经被缓存的结构的字段不会感知到之后的注册
+type TypeHandlers struct {
+	mutex sync.RWMutex
+	types map[reflect.Type]map[string]TypeHandler
+	names map[string]TypeHandler
+}
+
+func NewTypeHandlers() *TypeHandlers {
+	return &TypeHandlers{
+		types: map[reflect.Type]map[string]TypeHandler{},
+		names: map[string]TypeHandler{},
+	}
+}
+
+// Register 注册 typ 类型的 TypeHandler，它同样用于 *typ 类型，typ 为指针时按它指向的类型注册。
+// 当 dialects 为空时对所有的数据库都有效, 否则只对指定的数据库有效，并且优先于对所有数据库有效的 TypeHandler
+func (h *TypeHandlers) Register(typ reflect.Type, handler TypeHandler, dialects ...Dialect) {
+	typ = reflectx.Deref(typ)
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	byDialect := h.types[typ]
+	if byDialect == nil {
+		byDialect = map[string]TypeHandler{}
+		h.types[typ] = byDialect
+	}
+	if len(dialects) == 0 {
+		byDialect[""] = handler
+		return
+	}
+	for _, dialect := range dialects {
+		byDialect[dialect.Name()] = handler
+	}
+}
+
+// RegisterName 注册一个有名称的 TypeHandler，供字段的 tag 中的 handler 选项使用
+func (h *TypeHandlers) RegisterName(name string, handler TypeHandler) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.names[name] = handler
+}
+
+func (h *TypeHandlers) byName(name string) TypeHandler {
+	if h == nil {
+		return nil
+	}
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	return h.names[name]
+}
+
+func (h *TypeHandlers) hasType(typ reflect.Type) bool {
+	if h == nil {
+		return false
+	}
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	if len(h.types) == 0 {
+		return false
+	}
+	_, ok := h.types[reflectx.Deref(typ)]
+	return ok
+}
+
+func (h *TypeHandlers) byType(typ reflect.Type, dialect Dialect) TypeHandler {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	byDialect := h.types[typ]
+	if byDialect == nil {
+		return nil
+	}
+	if dialect != nil {
+		if handler, ok := byDialect[dialect.Name()]; ok {
+			return handler
+		}
+	}
+	return byDialect[""]
+}
+
+// lookup 查找 typ 的 TypeHandler，typ 是指针时查找它指向的类型
+func (h *TypeHandlers) lookup(typ reflect.Type, dialect Dialect) TypeHandler {
+	if !h.hasType(typ) {
+		return nil
+	}
+	return h.byType(reflectx.Deref(typ), dialect)
+}
+
+// encodeValue 用 handler 转换 value，value 为指针时先取它指向的值
+func encodeValue(handler TypeHandler, dialect Dialect, param *Param, value reflect.Value) (interface{}, error) {
+	if !value.IsValid() || (value.Kind() == reflect.Ptr && value.IsNil()) {
+		return toSQLType(dialect, param, nil)
+	}
+	if value.Kind() == reflect.Ptr {
+		value = value.Elem()
+	}
+	return handler.ToSQLValue(dialect, param, value.Interface())
+}
+
+// handlerScanner 将数据库中的值通过 TypeHandler 写入 field
+type handlerScanner struct {
+	dialect Dialect
+	name    string
+	handler TypeHandler
+	field   reflect.Value
+}
+
+func (s *handlerScanner) Scan(src interface{}) error {
+	if s.field.Kind() != reflect.Ptr {
+		return s.handler.FromSQLValue(s.dialect, s.name, src, s.field.Addr().Interface())
+	}
+	if src == nil {
+		s.field.Set(reflect.Zero(s.field.Type()))
+		return nil
+	}
+	value := reflect.New(s.field.Type().Elem())
+	if err := s.handler.FromSQLValue(s.dialect, s.name, src, value.Interface()); err != nil {
+		return err
+	}
+	s.field.Set(value)
+	return nil
+}
+
+// bindField 当字段的类型注册了 TypeHandler 或 tag 中指定了 handler 时，用它替换字段的 LValue 和 RValue
+func (h *TypeHandlers) bindField(fi *FieldInfo) {
+	typ := fi.Field.Type
+
+	var named TypeHandler
+	if name, ok := fi.Options["handler"]; ok {
+		named = h.byName(name)
+		if named == nil {
+			err := errors.New("type handler '" + name + "' of field '" + fi.Field.Name + "' isnot found")
+			fi.RValue = func(dialect Dialect, param *Param, v reflect.Value) (interface{}, error) {
+				return nil, err
+			}
+			fi.LValue = func(dialect Dialect, column string, v reflect.Value) (interface{}, error) {
+				return nil, err
+			}
+			return
+		}
+	} else if !h.hasType(typ) {
+		return
+	}
+
+	handlerOf := func(dialect Dialect) TypeHandler {
+		if named != nil {
+			return named
+		}
+		return h.lookup(typ, dialect)
+	}
+
+	rvalue, lvalue := fi.RValue, fi.LValue
+	fi.RValue = func(dialect Dialect, param *Param, v reflect.Value) (interface{}, error) {
+		handler := handlerOf(dialect)
+		if handler == nil {
+			return rvalue(dialect, param, v)
+		}
+		field := reflectx.FieldByIndexesReadOnly(v, fi.Index)
+		return encodeValue(handler, dialect, param, field)
+	}
+	fi.LValue = func(dialect Dialect, column string, v reflect.Value) (interface{}, error) {
+		handler := handlerOf(dialect)
+		if handler == nil {
+			return lvalue(dialect, column, v)
+		}
+		field := reflectx.FieldByIndexes(v, fi.Index)
+		return &handlerScanner{dialect: dialect, name: column, handler: handler, field: field}, nil
+	}
+}
+
+// toSQLType 先查找 value 的类型是否注册了 TypeHandler，没有时使用默认的转换
+func (m *Mapper) toSQLType(dialect Dialect, param *Param, value interface{}) (interface{}, error) {
+	if m != nil && value != nil {
+		if handler := m.typeHandlers.lookup(reflect.TypeOf(value), dialect); handler != nil {
+			return encodeValue(handler, dialect, param, reflect.ValueOf(value))
+		}
+	}
+	return toSQLType(dialect, param, value)
+}
+
+// scanValue 当 dest 指向的类型注册了 TypeHandler 时返回一个使用它的 sql.Scanner，否则返回 dest
+func (m *Mapper) scanValue(dialect Dialect, column string, dest interface{}) interface{} {
+	if m == nil || dest == nil {
+		return dest
+	}
+	rv := reflect.ValueOf(dest)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return dest
+	}
+	handler := m.typeHandlers.lookup(rv.Type().Elem(), dialect)
+	if handler == nil {
+		return dest
+	}
+	return &handlerScanner{dialect: dialect, name: column, handler: handler, field: rv.Elem()}
+}
+
+func (m *Mapper) hasTypeHandler(t reflect.Type) bool {
+	return m != nil && m.typeHandlers.hasType(t)
 }
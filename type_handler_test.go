@@ -0,0 +1,205 @@
+package gobatis
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+type testMoney struct {
+	cents int64
+}
+
+type testRow struct {
+	columns []string
+	values  []interface{}
+}
+
+func (r *testRow) Columns() ([]string, error) {
+	return r.columns, nil
+}
+
+func (r *testRow) Err() error {
+	return nil
+}
+
+func (r *testRow) Scan(dest ...interface{}) error {
+	if len(dest) != len(r.values) {
+		return errors.New("column count isnot match")
+	}
+	for idx := range dest {
+		if s, ok := dest[idx].(sql.Scanner); ok {
+			if err := s.Scan(r.values[idx]); err != nil {
+				return err
+			}
+			continue
+		}
+		switch d := dest[idx].(type) {
+		case *int64:
+			*d = r.values[idx].(int64)
+		default:
+			return fmt.Errorf("unsupported dest %T", dest[idx])
+		}
+	}
+	return nil
+}
+
+func TestTypeHandlers(t *testing.T) {
+	moneyType := reflect.TypeOf(testMoney{})
+
+	handlers := NewTypeHandlers()
+	handlers.Register(moneyType, TypeHandlerFuncs{
+		Encode: func(dialect Dialect, value interface{}) (interface{}, error) {
+			return fmt.Sprintf("%.2f", float64(value.(testMoney).cents)/100), nil
+		},
+		Decode: func(dialect Dialect, src interface{}, dest interface{}) error {
+			if src == nil {
+				*dest.(*testMoney) = testMoney{}
+				return nil
+			}
+			f, err := strconv.ParseFloat(string(src.([]byte)), 64)
+			if err != nil {
+				return err
+			}
+			*dest.(*testMoney) = testMoney{cents: int64(f*100 + 0.5)}
+			return nil
+		},
+	})
+	handlers.Register(moneyType, TypeHandlerFuncs{
+		Encode: func(dialect Dialect, value interface{}) (interface{}, error) {
+			return value.(testMoney).cents, nil
+		},
+		Decode: func(dialect Dialect, src interface{}, dest interface{}) error {
+			*dest.(*testMoney) = testMoney{cents: src.(int64)}
+			return nil
+		},
+	}, DbTypeMysql)
+	handlers.RegisterName("cents", TypeHandlerFuncs{
+		Encode: func(dialect Dialect, value interface{}) (interface{}, error) {
+			return "c" + strconv.FormatInt(value.(int64), 10), nil
+		},
+		Decode: func(dialect Dialect, src interface{}, dest interface{}) error {
+			i, err := strconv.ParseInt(string(src.([]byte))[1:], 10, 64)
+			*dest.(*int64) = i
+			return err
+		},
+	})
+
+	mapper := CreateMapper("", nil, nil)
+	mapper.typeHandlers = handlers
+
+	type order struct {
+		ID    int64      `db:"id"`
+		Price testMoney  `db:"price"`
+		Cost  *testMoney `db:"cost"`
+		Tax   int64      `db:"tax,handler=cents"`
+	}
+
+	t.Run("param", func(t *testing.T) {
+		for _, test := range []struct {
+			dialect  Dialect
+			names    []string
+			values   []interface{}
+			name     string
+			excepted interface{}
+		}{
+			{dialect: DbTypePostgres, values: []interface{}{testMoney{cents: 123}}, name: "a", excepted: "1.23"},
+			{dialect: DbTypeMysql, values: []interface{}{testMoney{cents: 123}}, name: "a", excepted: int64(123)},
+			{dialect: DbTypePostgres, values: []interface{}{map[string]interface{}{"a": &testMoney{cents: 5}}}, name: "a", excepted: "0.05"},
+			{dialect: DbTypePostgres, names: []string{"a"}, values: []interface{}{(*testMoney)(nil)}, name: "a", excepted: nil},
+			{dialect: DbTypePostgres, values: []interface{}{&order{Price: testMoney{cents: 100}, Tax: 7}}, name: "price", excepted: "1.00"},
+			{dialect: DbTypePostgres, values: []interface{}{&order{}}, name: "cost", excepted: nil},
+			{dialect: DbTypePostgres, values: []interface{}{&order{Tax: 7}}, name: "tax", excepted: "c7"},
+			{dialect: DbTypePostgres, names: []string{"o"}, values: []interface{}{&order{Cost: &testMoney{cents: 250}}}, name: "o.cost", excepted: "2.50"},
+		} {
+			ctx, err := NewContext(test.dialect, mapper, test.names, test.values)
+			if err != nil {
+				t.Error(err)
+				continue
+			}
+			value, err := ctx.RValue(&Param{Name: test.name})
+			if err != nil {
+				t.Error(test.name, err)
+				continue
+			}
+			if value != test.excepted {
+				t.Errorf("%s: excepted is %#v, actual is %#v", test.name, test.excepted, value)
+			}
+		}
+	})
+
+	t.Run("struct", func(t *testing.T) {
+		var o order
+		err := scanAny(DbTypePostgres, mapper, &testRow{
+			columns: []string{"id", "price", "cost", "tax"},
+			values:  []interface{}{int64(1), []byte("1.23"), []byte("4.56"), []byte("c9")},
+		}, &o, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if o.ID != 1 || o.Price.cents != 123 || o.Cost == nil || o.Cost.cents != 456 || o.Tax != 9 {
+			t.Errorf("%#v", o)
+		}
+
+		err = scanAny(DbTypeMysql, mapper, &testRow{
+			columns: []string{"price", "cost"},
+			values:  []interface{}{int64(77), nil},
+		}, &o, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if o.Price.cents != 77 || o.Cost != nil {
+			t.Errorf("%#v", o)
+		}
+	})
+
+	t.Run("basic", func(t *testing.T) {
+		var m testMoney
+		err := scanAny(DbTypePostgres, mapper, &testRow{
+			columns: []string{"sum"},
+			values:  []interface{}{[]byte("8.80")},
+		}, &m, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.cents != 880 {
+			t.Errorf("%#v", m)
+		}
+
+		var count int64
+		var total *testMoney
+		multiple := NewMultiple()
+		multiple.Set("count", &count)
+		multiple.Set("total", &total)
+		err = multiple.Scan(DbTypePostgres, mapper, &testRow{
+			columns: []string{"count", "total"},
+			values:  []interface{}{int64(3), []byte("0.10")},
+		}, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 3 || total == nil || total.cents != 10 {
+			t.Error(count, total)
+		}
+	})
+
+	t.Run("unknown handler", func(t *testing.T) {
+		type bad struct {
+			Tax int64 `db:"tax,handler=notexists"`
+		}
+		ctx, err := NewContext(DbTypePostgres, mapper, nil, []interface{}{&bad{}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = ctx.RValue(&Param{Name: "tax"})
+		if err == nil {
+			t.Error("want error")
+		} else if excepted := "type handler 'notexists' of field 'Tax' isnot found"; err.Error() != excepted {
+			t.Error("excepted is", excepted)
+			t.Error("actual   is", err)
+		}
+	})
+}
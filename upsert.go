@@ -0,0 +1,168 @@
+package gobatis
+
+import (
+	"strings"
+)
+
+// upsertOnConflict 是 postgres 的 upsert 语法: INSERT ... ON CONFLICT (keys) DO UPDATE SET ...
+func upsertOnConflict(stmt *UpsertStatement) string {
+	var sb strings.Builder
+	writeInsertValues(&sb, stmt)
+	sb.WriteString(" ON CONFLICT (")
+	sb.WriteString(strings.Join(stmt.Keys, ", "))
+	sb.WriteString(")")
+	if len(stmt.Updates) == 0 {
+		sb.WriteString(" DO NOTHING")
+	} else {
+		sb.WriteString(" DO UPDATE SET ")
+		for idx, column := range stmt.Updates {
+			if idx > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString(column)
+			sb.WriteString("=EXCLUDED.")
+			sb.WriteString(column)
+		}
+	}
+	if stmt.Returning != "" {
+		sb.WriteString(" RETURNING ")
+		sb.WriteString(stmt.Returning)
+	}
+	return sb.String()
+}
+
+// upsertOnDuplicateKey 是 mysql 的 upsert 语法: INSERT ... ON DUPLICATE KEY UPDATE ...，
+// 返回自增列时用 id=LAST_INSERT_ID(id) 让 LastInsertId() 在更新时也能返回记录的 id
+//
+// mysql 按任意一个唯一索引判断记录是否存在，而不是按 Keys，所以有租户列时每个赋值都加上
+// IF(tenant_id = VALUES(tenant_id), ...)，冲突的记录属于其它租户时保持原值，也不返回它的 id
+func upsertOnDuplicateKey(stmt *UpsertStatement) string {
+	var sb strings.Builder
+	writeInsertValues(&sb, stmt)
+	sb.WriteString(" ON DUPLICATE KEY UPDATE ")
+	writeValue := func(value, old string) {
+		if stmt.Tenant == "" {
+			sb.WriteString(value)
+			return
+		}
+		sb.WriteString("IF(")
+		sb.WriteString(stmt.Tenant)
+		sb.WriteString(" = VALUES(")
+		sb.WriteString(stmt.Tenant)
+		sb.WriteString("), ")
+		sb.WriteString(value)
+		sb.WriteString(", ")
+		sb.WriteString(old)
+		sb.WriteString(")")
+	}
+	isFirst := true
+	for _, column := range stmt.Updates {
+		if isFirst {
+			isFirst = false
+		} else {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(column)
+		sb.WriteString("=")
+		writeValue("VALUES("+column+")", column)
+	}
+	if stmt.Returning != "" {
+		if !isFirst {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(stmt.Returning)
+		sb.WriteString("=")
+		writeValue("LAST_INSERT_ID("+stmt.Returning+")", stmt.Returning)
+	} else if isFirst {
+		// 没有要更新的列时用一个不修改任何值的赋值
+		sb.WriteString(stmt.Keys[0])
+		sb.WriteString("=")
+		sb.WriteString(stmt.Keys[0])
+	}
+	return sb.String()
+}
+
+// upsertMerge 是 mssql 的 upsert 语法: MERGE ... USING (VALUES(...)) AS src(...)
+func upsertMerge(stmt *UpsertStatement) string {
+	var sb strings.Builder
+	sb.WriteString("MERGE INTO ")
+	sb.WriteString(stmt.Table)
+	sb.WriteString(" USING (VALUES(")
+	sb.WriteString(strings.Join(stmt.Values, ", "))
+	sb.WriteString(")) AS src(")
+	sb.WriteString(strings.Join(stmt.Columns, ", "))
+	sb.WriteString(")")
+	writeMergeBody(&sb, stmt)
+	if stmt.Returning != "" {
+		sb.WriteString(" OUTPUT inserted.")
+		sb.WriteString(stmt.Returning)
+	}
+	sb.WriteString(";")
+	return sb.String()
+}
+
+// upsertMergeDual 是 oracle 的 upsert 语法: MERGE ... USING (SELECT ... FROM dual) src，它不能返回自增列
+func upsertMergeDual(stmt *UpsertStatement) string {
+	var sb strings.Builder
+	sb.WriteString("MERGE INTO ")
+	sb.WriteString(stmt.Table)
+	sb.WriteString(" USING (SELECT ")
+	for idx, column := range stmt.Columns {
+		if idx > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(stmt.Values[idx])
+		sb.WriteString(" AS ")
+		sb.WriteString(column)
+	}
+	sb.WriteString(" FROM dual) src")
+	writeMergeBody(&sb, stmt)
+	return sb.String()
+}
+
+func writeInsertValues(sb *strings.Builder, stmt *UpsertStatement) {
+	sb.WriteString("INSERT INTO ")
+	sb.WriteString(stmt.Table)
+	sb.WriteString("(")
+	sb.WriteString(strings.Join(stmt.Columns, ", "))
+	sb.WriteString(") VALUES(")
+	sb.WriteString(strings.Join(stmt.Values, ", "))
+	sb.WriteString(")")
+}
+
+func writeMergeBody(sb *strings.Builder, stmt *UpsertStatement) {
+	sb.WriteString(" ON (")
+	for idx, key := range stmt.Keys {
+		if idx > 0 {
+			sb.WriteString(" AND ")
+		}
+		sb.WriteString(stmt.Table)
+		sb.WriteString(".")
+		sb.WriteString(key)
+		sb.WriteString(" = src.")
+		sb.WriteString(key)
+	}
+	sb.WriteString(")")
+	if len(stmt.Updates) > 0 {
+		sb.WriteString(" WHEN MATCHED THEN UPDATE SET ")
+		for idx, column := range stmt.Updates {
+			if idx > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString(column)
+			sb.WriteString(" = src.")
+			sb.WriteString(column)
+		}
+	}
+	sb.WriteString(" WHEN NOT MATCHED THEN INSERT (")
+	sb.WriteString(strings.Join(stmt.Columns, ", "))
+	sb.WriteString(") VALUES (")
+	for idx, column := range stmt.Columns {
+		if idx > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString("src.")
+		sb.WriteString(column)
+	}
+	sb.WriteString(")")
+}
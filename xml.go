@@ -6,7 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"os"
+	"io/fs"
 	"strings"
 	"unicode"
 )
@@ -17,57 +17,90 @@ type stmtXML struct {
 	SQL    string `xml:",innerxml"`
 }
 
-type xmlConfig struct {
-	Selects []stmtXML `xml:"select"`
-	Deletes []stmtXML `xml:"delete"`
-	Updates []stmtXML `xml:"update"`
-	Inserts []stmtXML `xml:"insert"`
+type stmtXMLAt struct {
+	stmtXML
+	sqlType StatementType
+	// line 和 column 为 sql 文本的起始位置
+	line, column int
 }
 
-func readMappedStatements(ctx *InitContext, path string) ([]*MappedStatement, error) {
-	statements := make([]*MappedStatement, 0)
-
-	xmlFile, err := os.Open(path)
+func readMappedStatements(ctx *InitContext, fsys fs.FS, path string) ([]*MappedStatement, error) {
+	xmlFile, err := openXMLFile(fsys, path)
 	if err != nil {
 		return nil, errors.New("Error opening file: " + err.Error())
 	}
 	defer xmlFile.Close()
 
-	xmlObj := xmlConfig{}
+	// 先读完整个文件，xml 有语法错误时不要去解析其中的语句
 	decoder := xml.NewDecoder(xmlFile)
-	if err = decoder.Decode(&xmlObj); err != nil {
-		return nil, errors.New("Error decode file '" + path + "': " + err.Error())
+	stmts, err := readStatementXMLs(decoder)
+	if err != nil {
+		line, column := decoder.InputPos()
+		return nil, &SQLError{File: path, Line: line, Column: column, Message: err.Error()}
 	}
 
-	for _, deleteStmt := range xmlObj.Deletes {
-		mapper, err := newMapppedStatement(ctx, deleteStmt, StatementTypeDelete)
+	statements := make([]*MappedStatement, 0, len(stmts))
+	for _, stmt := range stmts {
+		mapper, err := newMapppedStatement(ctx, stmt.stmtXML, stmt.sqlType)
 		if err != nil {
-			return nil, errors.New("Error parse file '" + path + "' on '" + deleteStmt.ID + "': " + err.Error())
-		}
-		statements = append(statements, mapper)
-	}
-	for _, insertStmt := range xmlObj.Inserts {
-		mapper, err := newMapppedStatement(ctx, insertStmt, StatementTypeInsert)
-		if err != nil {
-			return nil, errors.New("Error parse file '" + path + "' on '" + insertStmt.ID + "': " + err.Error())
+			e := toSQLError(err)
+			e.shift(stmt.line, stmt.column)
+			e.File = path
+			if e.ID == "" {
+				e.ID = stmt.ID
+			}
+			return nil, e
 		}
+		mapper.source = StatementSource{File: path, Line: stmt.line}
 		statements = append(statements, mapper)
 	}
-	for _, selectStmt := range xmlObj.Selects {
-		mapper, err := newMapppedStatement(ctx, selectStmt, StatementTypeSelect)
+	return statements, nil
+}
+
+func readStatementXMLs(decoder *xml.Decoder) ([]stmtXMLAt, error) {
+	var stmts []stmtXMLAt
+	inRoot := false
+	for {
+		token, err := decoder.Token()
 		if err != nil {
-			return nil, errors.New("Error parse file '" + path + "' on '" + selectStmt.ID + "': " + err.Error())
+			return nil, err
 		}
-		statements = append(statements, mapper)
-	}
-	for _, updateStmt := range xmlObj.Updates {
-		mapper, err := newMapppedStatement(ctx, updateStmt, StatementTypeUpdate)
-		if err != nil {
-			return nil, errors.New("Error parse file '" + path + "' on '" + updateStmt.ID + "': " + err.Error())
+
+		switch el := token.(type) {
+		case xml.StartElement:
+			if !inRoot {
+				inRoot = true
+				continue
+			}
+
+			var sqlType StatementType
+			switch el.Name.Local {
+			case "select":
+				sqlType = StatementTypeSelect
+			case "delete":
+				sqlType = StatementTypeDelete
+			case "update":
+				sqlType = StatementTypeUpdate
+			case "insert":
+				sqlType = StatementTypeInsert
+			default:
+				if err := decoder.Skip(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+
+			stmt := stmtXMLAt{sqlType: sqlType}
+			stmt.line, stmt.column = decoder.InputPos()
+			if err := decoder.DecodeElement(&stmt.stmtXML, &el); err != nil {
+				return nil, err
+			}
+			stmts = append(stmts, stmt)
+		case xml.EndElement:
+			// 只读第一个根元素，和 Decode 的行为一致
+			return stmts, nil
 		}
-		statements = append(statements, mapper)
 	}
-	return statements, nil
 }
 
 func newMapppedStatement(ctx *InitContext, stmt stmtXML, sqlType StatementType) (*MappedStatement, error) {
@@ -87,20 +120,45 @@ func newMapppedStatement(ctx *InitContext, stmt stmtXML, sqlType StatementType)
 	return NewMapppedStatement(ctx, stmt.ID, sqlType, resultType, stmt.SQL)
 }
 
-func loadDynamicSQLFromXML(sqlStr string) (DynamicSQL, error) {
-	segements, err := readSQLStatementForXML(sqlStr)
+func loadDynamicSQLFromXML(ctx *InitContext, sqlStr string) (DynamicSQL, error) {
+	segements, err := readSQLStatementForXML(ctx, sqlStr)
 	if err != nil {
 		return nil, err
 	}
 	return expressionArray(segements), nil
 }
 
-func readSQLStatementForXML(sqlStr string) ([]sqlExpression, error) {
+func readSQLStatementForXML(ctx *InitContext, sqlStr string) ([]sqlExpression, error) {
 	txtBegin := `<?xml version="1.0" encoding="utf-8"?>
 <statement>`
 	txtEnd := `</statement>`
 
 	decoder := xml.NewDecoder(strings.NewReader(txtBegin + sqlStr + txtEnd))
+	expressions, err := readRootElementForXML(ctx, decoder)
+	if err != nil {
+		line, column := decoder.InputPos()
+		e := toSQLError(xmlPosError(err, line, column))
+
+		// 转换为相对于 sqlStr 的位置, sqlStr 从第 2 行的 <statement> 之后开始
+		if e.Line == 2 {
+			e.Column -= len("<statement>")
+		}
+		e.Line--
+		return nil, e
+	}
+	return expressions, nil
+}
+
+// xmlPosError 给没有位置的错误加上位置
+func xmlPosError(err error, line, column int) error {
+	e := toSQLError(err)
+	if e.Line <= 0 {
+		e.Line, e.Column = line, column
+	}
+	return e
+}
+
+func readRootElementForXML(ctx *InitContext, decoder *xml.Decoder) ([]sqlExpression, error) {
 	for {
 		token, err := decoder.Token()
 		if err != nil {
@@ -113,7 +171,7 @@ func readSQLStatementForXML(sqlStr string) ([]sqlExpression, error) {
 		switch el := token.(type) {
 		case xml.StartElement:
 			if el.Name.Local == "statement" {
-				return readElementForXML(decoder, "")
+				return readElementForXML(ctx, decoder, "")
 			}
 		case xml.Directive, xml.ProcInst, xml.Comment:
 		case xml.CharData:
@@ -126,18 +184,22 @@ func readSQLStatementForXML(sqlStr string) ([]sqlExpression, error) {
 	}
 }
 
-func readElementForXML(decoder *xml.Decoder, tag string) ([]sqlExpression, error) {
+func readElementForXML(ctx *InitContext, decoder *xml.Decoder, tag string) ([]sqlExpression, error) {
 	var sb strings.Builder
 	var expressions []sqlExpression
-	var lastPrint *printExpression
+	// lastSuffix 是上一个 print 或 tenant 元素的 suffix，用于保留元素后面的空白
+	var lastSuffix *string
+	var textLine, textColumn int
 
 	for {
+		line, column := decoder.InputPos()
 		token, err := decoder.Token()
 		if err != nil {
 			if err == io.EOF {
-				return nil, fmt.Errorf("EOF isnot except in the '" + tag + "' element")
+				err = fmt.Errorf("EOF isnot except in the '" + tag + "' element")
 			}
-			return nil, err
+			line, column = decoder.InputPos()
+			return nil, xmlPosError(err, line, column)
 		}
 
 		switch el := token.(type) {
@@ -146,23 +208,25 @@ func readElementForXML(decoder *xml.Decoder, tag string) ([]sqlExpression, error
 			if s := sb.String(); strings.TrimSpace(s) != "" {
 				segement, err := newRawExpression(s)
 				if err != nil {
-					return nil, err
+					e := toSQLError(err)
+					e.shift(textLine, textColumn)
+					return nil, e
 				}
 
 				expressions = append(expressions, segement)
-			} else if lastPrint != nil {
-				lastPrint.suffix = sb.String()
+			} else if lastSuffix != nil {
+				*lastSuffix = sb.String()
 			} else {
 				prefix = sb.String()
 			}
-			lastPrint = nil
+			lastSuffix = nil
 			sb.Reset()
 
 			switch el.Name.Local {
 			case "if":
-				contents, err := readElementForXML(decoder, tag+"/if")
+				contents, err := readElementForXML(ctx, decoder, tag+"/if")
 				if err != nil {
-					return nil, err
+					return nil, xmlPosError(err, line, column)
 				}
 				if len(contents) == 0 {
 					break
@@ -174,15 +238,15 @@ func readElementForXML(decoder *xml.Decoder, tag string) ([]sqlExpression, error
 				} else if len(contents) > 1 {
 					content = expressionArray(contents)
 				}
-				segement, err := newIFExpression(readElementAttrForXML(el.Attr, "test"), content)
+				segement, err := newIFExpression(ctx, readElementAttrForXML(el.Attr, "test"), content)
 				if err != nil {
-					return nil, err
+					return nil, xmlPosError(err, line, column)
 				}
 				expressions = append(expressions, segement)
 			case "foreach":
-				contents, err := readElementForXML(decoder, tag+"/foreach")
+				contents, err := readElementForXML(ctx, decoder, tag+"/foreach")
 				if err != nil {
-					return nil, err
+					return nil, xmlPosError(err, line, column)
 				}
 
 				foreach, err := newForEachExpression(xmlForEachElement{
@@ -195,72 +259,126 @@ func readElementForXML(decoder *xml.Decoder, tag string) ([]sqlExpression, error
 					contents:     contents,
 				})
 				if err != nil {
-					return nil, err
+					return nil, xmlPosError(err, line, column)
 				}
 
 				expressions = append(expressions, foreach)
 			case "chose":
-				choseEl, err := loadChoseElementForXML(decoder, tag+"/chose")
+				choseEl, err := loadChoseElementForXML(ctx, decoder, tag+"/chose")
 				if err != nil {
-					return nil, err
+					return nil, xmlPosError(err, line, column)
 				}
-				chose, err := newChoseExpression(*choseEl)
+				chose, err := newChoseExpression(ctx, *choseEl)
 				if err != nil {
-					return nil, err
+					return nil, xmlPosError(err, line, column)
 				}
 				expressions = append(expressions, chose)
 			case "where":
-				array, err := readElementForXML(decoder, tag+"/where")
+				array, err := readElementForXML(ctx, decoder, tag+"/where")
 				if err != nil {
-					return nil, err
+					return nil, xmlPosError(err, line, column)
 				}
 
 				expressions = append(expressions, &whereExpression{expressions: array})
 			case "set":
-				array, err := readElementForXML(decoder, tag+"/set")
+				array, err := readElementForXML(ctx, decoder, tag+"/set")
 				if err != nil {
-					return nil, err
+					return nil, xmlPosError(err, line, column)
 				}
 
 				expressions = append(expressions, &setExpression{expressions: array})
 			case "print":
 				content, err := readElementTextForXML(decoder, tag+"/print")
 				if err != nil {
-					return nil, err
+					return nil, xmlPosError(err, line, column)
 				}
 				if strings.TrimSpace(content) != "" {
-					return nil, errors.New("element print must is empty element")
+					return nil, xmlPosError(errors.New("element print must is empty element"), line, column)
 				}
-				lastPrint = &printExpression{
+				printExpr := &printExpression{
 					prefix: prefix,
 					value:  readElementAttrForXML(el.Attr, "value"),
 					fmt:    readElementAttrForXML(el.Attr, "fmt")}
-				expressions = append(expressions, lastPrint)
+				lastSuffix = &printExpr.suffix
+				expressions = append(expressions, printExpr)
+			case "tenant":
+				content, err := readElementTextForXML(decoder, tag+"/tenant")
+				if err != nil {
+					return nil, xmlPosError(err, line, column)
+				}
+				if strings.TrimSpace(content) != "" {
+					return nil, xmlPosError(errors.New("element tenant must is empty element"), line, column)
+				}
+				tenant := &tenantExpression{
+					prefix:      prefix,
+					column:      readElementAttrForXML(el.Attr, "column"),
+					placeholder: readElementAttrForXML(el.Attr, "placeholder") == "true"}
+				if tenant.column == "" {
+					tenant.column = "tenant_id"
+				}
+				lastSuffix = &tenant.suffix
+				expressions = append(expressions, tenant)
+			case "page":
+				content, err := readElementTextForXML(decoder, tag+"/page")
+				if err != nil {
+					return nil, xmlPosError(err, line, column)
+				}
+				if strings.TrimSpace(content) != "" {
+					return nil, xmlPosError(errors.New("element page must is empty element"), line, column)
+				}
+				page := &pageExpression{
+					offset: readElementAttrForXML(el.Attr, "offset"),
+					limit:  readElementAttrForXML(el.Attr, "limit")}
+				if page.offset == "" && page.limit == "" {
+					return nil, xmlPosError(errors.New("element page must has offset or limit attribute"), line, column)
+				}
+				expressions = append(expressions, page)
+			case "criteria":
+				content, err := readElementTextForXML(decoder, tag+"/criteria")
+				if err != nil {
+					return nil, xmlPosError(err, line, column)
+				}
+				if strings.TrimSpace(content) != "" {
+					return nil, xmlPosError(errors.New("element criteria must is empty element"), line, column)
+				}
+				criteria := &criteriaExpression{value: readElementAttrForXML(el.Attr, "value")}
+				if criteria.value == "" {
+					return nil, xmlPosError(errors.New("element criteria must has value attribute"), line, column)
+				}
+				expressions = append(expressions, criteria)
 			default:
-				return nil, fmt.Errorf("StartElement(" + el.Name.Local + ") isnot except '" + tag + "'")
+				return nil, xmlPosError(errors.New("StartElement("+el.Name.Local+") isnot except '"+tag+"'"), line, column)
 			}
 
 		case xml.EndElement:
 			if s := sb.String(); strings.TrimSpace(s) != "" {
 				segement, err := newRawExpression(s)
 				if err != nil {
-					return nil, err
+					e := toSQLError(err)
+					e.shift(textLine, textColumn)
+					return nil, e
 				}
 
 				expressions = append(expressions, segement)
-			} else if lastPrint != nil {
-				lastPrint.suffix = sb.String()
+			} else if lastSuffix != nil {
+				*lastSuffix = sb.String()
 			}
 			sb.Reset()
-			lastPrint = nil
+			lastSuffix = nil
 
 			return expressions, nil
 		case xml.CharData:
+			if sb.Len() == 0 {
+				textLine, textColumn = line, column
+			}
 			sb.Write(el)
 		case xml.Directive, xml.ProcInst, xml.Comment:
+			if sb.Len() == 0 {
+				textLine, textColumn = line, column
+			}
 			sb.WriteString(" ")
 		default:
-			return nil, fmt.Errorf("%T isnot except element in the '"+tag+"'", token)
+			return nil, xmlPosError(fmt.Errorf("%T isnot except element in the '"+tag+"'", token), line, column)
 		}
 	}
 }
@@ -300,7 +418,7 @@ func readElementAttrForXML(attrs []xml.Attr, name string) string {
 	return ""
 }
 
-func loadChoseElementForXML(decoder *xml.Decoder, tag string) (*xmlChoseElement, error) {
+func loadChoseElementForXML(ctx *InitContext, decoder *xml.Decoder, tag string) (*xmlChoseElement, error) {
 	var segement xmlChoseElement
 	for {
 		token, err := decoder.Token()
@@ -314,7 +432,7 @@ func loadChoseElementForXML(decoder *xml.Decoder, tag string) (*xmlChoseElement,
 		switch el := token.(type) {
 		case xml.StartElement:
 			if el.Name.Local == "when" {
-				contents, err := readElementForXML(decoder, "when")
+				contents, err := readElementForXML(ctx, decoder, "when")
 				if err != nil {
 					return nil, err
 				}
@@ -338,7 +456,7 @@ func loadChoseElementForXML(decoder *xml.Decoder, tag string) (*xmlChoseElement,
 			}
 
 			if el.Name.Local == "otherwise" {
-				contents, err := readElementForXML(decoder, "otherwise")
+				contents, err := readElementForXML(ctx, decoder, "otherwise")
 				if err != nil {
 					return nil, err
 				}
@@ -435,13 +553,13 @@ func (foreach *xmlForEachElement) String() string {
 }
 
 func hasXMLTag(sqlStr string) bool {
-	for _, tag := range []string{"<where>", "<set>", "<chose>", "<if>", "<foreach>"} {
+	for _, tag := range []string{"<where>", "<set>", "<chose>", "<if>", "<foreach>", "<tenant/>"} {
 		if strings.Contains(sqlStr, tag) {
 			return true
 		}
 	}
 
-	for _, tag := range []string{"<if", "<foreach", "<print"} {
+	for _, tag := range []string{"<if", "<foreach", "<print", "<tenant", "<page", "<criteria"} {
 		idx := strings.Index(sqlStr, tag)
 		exceptIndex := idx + len(tag)
 		if idx >= 0 && len(sqlStr) > exceptIndex && unicode.IsSpace(rune(sqlStr[exceptIndex])) {
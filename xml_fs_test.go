@@ -0,0 +1,73 @@
+package gobatis
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadXMLFromFS(t *testing.T) {
+	callbacks := ClearInit()
+	defer SetInit(callbacks)
+
+	xmlText := func(id, sqlStr string) *fstest.MapFile {
+		return &fstest.MapFile{Data: []byte(`<?xml version="1.0" encoding="utf-8"?>
+<gobatis>
+  <select id="` + id + `">` + sqlStr + `</select>
+</gobatis>`)}
+	}
+
+	fsys := fstest.MapFS{
+		"mappers/common.xml":         xmlText("fs.common", "SELECT 1"),
+		"mappers/readme.txt":         &fstest.MapFile{Data: []byte("aa")},
+		"mappers/postgres/users.xml": xmlText("fs.users", "SELECT 2"),
+		"mappers/mysql/users.xml":    xmlText("fs.users", "SELECT 3"),
+		"other/single.xml":           xmlText("fs.single", "SELECT 4"),
+		"root.xml":                   xmlText("fs.root", "SELECT 5"),
+	}
+
+	for _, test := range []struct {
+		xmlPaths []string
+		excepted map[string]string
+	}{
+		{
+			xmlPaths: []string{"mappers", "other/single.xml", "notexists"},
+			excepted: map[string]string{
+				"fs.common": "SELECT 1",
+				"fs.users":  "SELECT 2",
+				"fs.single": "SELECT 4",
+			},
+		},
+		{
+			xmlPaths: nil,
+			excepted: map[string]string{
+				"fs.root": "SELECT 5",
+			},
+		},
+	} {
+		conn, err := newConnection(&Config{DriverName: "postgres",
+			DataSource: "aa",
+			XMLFS:      fsys,
+			XMLPaths:   test.xmlPaths})
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+
+		statements := conn.sqlStatements.all()
+		if len(statements) != len(test.excepted) {
+			t.Error(test.xmlPaths, "excepted is", len(test.excepted), "statements")
+			t.Error(test.xmlPaths, "actual   is", len(statements), "statements")
+		}
+		for id, sqlStr := range test.excepted {
+			stmt, ok := statements[id]
+			if !ok {
+				t.Error(test.xmlPaths, id, "isnot found")
+				continue
+			}
+			if stmt.rawSQL != sqlStr {
+				t.Error(test.xmlPaths, id, "excepted is", sqlStr)
+				t.Error(test.xmlPaths, id, "actual   is", stmt.rawSQL)
+			}
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package gobatis_test
 
 import (
+	"database/sql"
 	"fmt"
 	"log"
 	"os"
@@ -757,3 +758,271 @@ func TestXmlFail(t *testing.T) {
 	}
 
 }
+
+func TestXmlErrorPosition(t *testing.T) {
+	cfg := &gobatis.Config{DriverName: "postgres",
+		DataSource: "aa",
+		Logger:     log.New(os.Stdout, "[gobatis] ", log.Flags()),
+	}
+
+	initCtx := &gobatis.InitContext{Config: cfg,
+		Logger:     cfg.Logger,
+		Dialect:    gobatis.DbTypePostgres,
+		Mapper:     gobatis.CreateMapper("", nil, nil),
+		Statements: make(map[string]*gobatis.MappedStatement)}
+
+	for idx, test := range []struct {
+		name         string
+		sql          string
+		line, column int
+	}{
+		{
+			name:   "named param",
+			sql:    `SELECT * FROM a WHERE id = #{id`,
+			line:   1,
+			column: 28,
+		},
+		{
+			name:   "named param in second line",
+			sql:    "SELECT *\r\nFROM a WHERE id = #{id",
+			line:   2,
+			column: 19,
+		},
+		{
+			name:   "multiple statements",
+			sql:    "SELECT 1;\nSELECT #{a",
+			line:   2,
+			column: 8,
+		},
+		{
+			name:   "if test",
+			sql:    "SELECT *\nFROM a <if test=\"a+++\">\n aa</if>",
+			line:   2,
+			column: 8,
+		},
+		{
+			name:   "named param in if",
+			sql:    "SELECT * <where>\n  <if test=\"a\">#{b</if></where>",
+			line:   2,
+			column: 16,
+		},
+		{
+			name:   "unknown element",
+			sql:    "SELECT * <where><abc/></where>",
+			line:   1,
+			column: 17,
+		},
+		{
+			name:   "template",
+			sql:    "SELECT *\n{{if}}",
+			line:   2,
+			column: 1,
+		},
+	} {
+		_, err := gobatis.NewMapppedStatement(initCtx, "ddd", gobatis.StatementTypeSelect, gobatis.ResultStruct, test.sql)
+		if err == nil {
+			t.Error("[", idx, "] ", test.name, ": except return a error")
+			continue
+		}
+
+		sqlErr, ok := err.(*gobatis.SQLError)
+		if !ok {
+			t.Errorf("[%d] %s: except *SQLError got %T", idx, test.name, err)
+			continue
+		}
+		if sqlErr.ID != "ddd" || sqlErr.Line != test.line || sqlErr.Column != test.column {
+			t.Errorf("[%d] %s: except ddd %d:%d got %s %d:%d", idx, test.name,
+				test.line, test.column, sqlErr.ID, sqlErr.Line, sqlErr.Column)
+			t.Error(err)
+		}
+	}
+}
+
+func TestXmlExpressionFunctions(t *testing.T) {
+	cfg := &gobatis.Config{DriverName: "postgres",
+		DataSource: "aa",
+		Logger:     log.New(os.Stdout, "[gobatis] ", log.Flags()),
+		ExpressionFuncs: map[string]func(args ...interface{}) (interface{}, error){
+			"double": func(args ...interface{}) (interface{}, error) {
+				return args[0].(float64) * 2, nil
+			},
+		},
+	}
+
+	initCtx := &gobatis.InitContext{Config: cfg,
+		Logger:     cfg.Logger,
+		Dialect:    gobatis.DbTypePostgres,
+		Mapper:     gobatis.CreateMapper("", nil, nil),
+		Statements: make(map[string]*gobatis.MappedStatement)}
+
+	name := "  "
+	var nilName *string
+	for idx, test := range []struct {
+		test     string
+		value    interface{}
+		excepted bool
+	}{
+		{test: "isBlank(a)", value: "  ", excepted: true},
+		{test: "isBlank(a)", value: " a ", excepted: false},
+		{test: "isBlank(a)", value: &name, excepted: true},
+		{test: "isBlank(a)", value: nilName, excepted: true},
+		{test: "isBlank(a)", value: sql.NullString{String: "a"}, excepted: true},
+		{test: "isNotBlank(a)", value: sql.NullString{String: "a", Valid: true}, excepted: true},
+		{test: "isZero(a)", value: time.Time{}, excepted: true},
+		{test: "isZero(a)", value: time.Now(), excepted: false},
+		{test: "isZero(a)", value: 0, excepted: true},
+		{test: "isNotZero(a)", value: sql.NullInt64{Int64: 2, Valid: true}, excepted: true},
+		{test: "isZero(a)", value: sql.NullInt64{Int64: 2}, excepted: true},
+		{test: "contains(a, 2)", value: []int64{1, 2, 3}, excepted: true},
+		{test: "contains(a, 4)", value: []int64{1, 2, 3}, excepted: false},
+		{test: "contains(a, 'bc')", value: "abcd", excepted: true},
+		{test: "contains(a, 'b')", value: map[string]int{"b": 1}, excepted: true},
+		{test: "in(a, 1, 2, 3)", value: int8(3), excepted: true},
+		{test: "in(a, 'x', 'y')", value: "z", excepted: false},
+		{test: "in(2, a)", value: []int{1, 2}, excepted: true},
+		{test: "hasPrefix(a, 'ab')", value: "abc", excepted: true},
+		{test: "hasSuffix(a, 'ab')", value: "abc", excepted: false},
+		{test: "lower(a) == 'abc'", value: "ABC", excepted: true},
+		{test: "upper(a) == 'ABC'", value: "abc", excepted: true},
+		{test: "trim(a) == 'abc'", value: " abc ", excepted: true},
+		{test: "coalesce(a, 3) == 3", value: sql.NullInt64{Int64: 2}, excepted: true},
+		{test: "coalesce(a, 3) == 2", value: sql.NullInt64{Int64: 2, Valid: true}, excepted: true},
+		{test: "isnull(a)", value: sql.NullString{}, excepted: true},
+		{test: "isnotnull(a)", value: sql.NullString{Valid: true}, excepted: true},
+		{test: "isnull(a)", value: nilName, excepted: true},
+		{test: "double(a) == 4", value: 2, excepted: true},
+	} {
+		sqlStr := `aa<if test="` + test.test + `">ok</if>`
+		stmt, err := gobatis.NewMapppedStatement(initCtx, "ddd", gobatis.StatementTypeSelect, gobatis.ResultStruct, sqlStr)
+		if err != nil {
+			t.Error("[", idx, "] ", test.test, err)
+			continue
+		}
+
+		ctx, err := gobatis.NewContext(initCtx.Dialect, initCtx.Mapper, []string{"a"}, []interface{}{test.value})
+		if err != nil {
+			t.Error("[", idx, "] ", test.test, err)
+			continue
+		}
+
+		sqlParams, err := stmt.GenerateSQLs(ctx)
+		if err != nil {
+			t.Error("[", idx, "] ", test.test, err)
+			continue
+		}
+
+		if actual := sqlParams[0].SQL == "aaok"; actual != test.excepted {
+			t.Error("[", idx, "] ", test.test, "except", test.excepted, "got", actual)
+		}
+	}
+}
+
+type pathAddress struct {
+	City string `db:"city"`
+}
+
+type pathCustomer struct {
+	Name    string       `db:"name"`
+	Address *pathAddress `db:"address"`
+}
+
+type pathItem struct {
+	Sku string `db:"sku"`
+}
+
+type pathOrder struct {
+	Customer  pathCustomer `db:"customer"`
+	Items     []pathItem   `db:"items"`
+	CreatedAt time.Time    `db:"created_at"`
+}
+
+func TestXmlPropertyPath(t *testing.T) {
+	initCtx := &gobatis.InitContext{Config: &gobatis.Config{},
+		Logger:     log.New(os.Stdout, "[gobatis] ", log.Flags()),
+		Dialect:    gobatis.DbTypePostgres,
+		Mapper:     gobatis.CreateMapper("", nil, nil),
+		Statements: make(map[string]*gobatis.MappedStatement)}
+
+	order := &pathOrder{
+		Customer:  pathCustomer{Name: "tom", Address: &pathAddress{City: "sh"}},
+		Items:     []pathItem{{Sku: "a1"}, {Sku: "b2"}},
+		CreatedAt: time.Now(),
+	}
+	attrs := map[string]interface{}{"color": "red", "sizes": []int{1, 2}}
+
+	for idx, test := range []struct {
+		sql      string
+		names    []string
+		values   []interface{}
+		excepted string
+		params   []interface{}
+		err      string
+	}{
+		{sql: `city = #{order.customer.address.city}`, names: []string{"order"}, values: []interface{}{order},
+			excepted: "city = $1", params: []interface{}{"sh"}},
+		{sql: `city = #{customer.address.city}`, values: []interface{}{order},
+			excepted: "city = $1", params: []interface{}{"sh"}},
+		{sql: `sku = #{order.items[1].sku}`, names: []string{"order", "attrs"}, values: []interface{}{order, attrs},
+			excepted: "sku = $1", params: []interface{}{"b2"}},
+		{sql: `color = #{attrs['color']} AND size = #{attrs.sizes[0]}`, names: []string{"order", "attrs"}, values: []interface{}{order, attrs},
+			excepted: "color = $1 AND size = $2", params: []interface{}{"red", 1}},
+		{sql: `color = #{color}`, values: []interface{}{attrs},
+			excepted: "color = $1", params: []interface{}{"red"}},
+		{sql: `name = '<print value="order.customer.name" />'`, names: []string{"order"}, values: []interface{}{order},
+			excepted: "name = 'tom'"},
+		{sql: `sku in (<foreach collection="order.items" item="item" separator=",">#{item.sku}</foreach>)`, names: []string{"order"}, values: []interface{}{order},
+			excepted: "sku in ($1,$2)", params: []interface{}{"a1", "b2"}},
+		{sql: `a<if test="order.customer.address.city == 'sh' &amp;&amp; attrs['color'] == &quot;red&quot;">b</if>`, names: []string{"order", "attrs"}, values: []interface{}{order, attrs},
+			excepted: "ab"},
+		{sql: `a<if test="!order.CreatedAt.IsZero &amp;&amp; order.items[0].sku == 'a1'">b</if>`, names: []string{"order"}, values: []interface{}{order},
+			excepted: "ab"},
+		{sql: `a<if test="isnull(order.customer.address.city)">b</if>`, names: []string{"order"}, values: []interface{}{&pathOrder{}},
+			excepted: "ab"},
+		{sql: `a<if test="isnull(attrs.size)">b</if>`, names: []string{"attrs"}, values: []interface{}{attrs},
+			excepted: "ab"},
+		{sql: `zip = #{order.customer.zip}`, names: []string{"order"}, values: []interface{}{order},
+			err: "property 'order.customer.zip' is invalid, 'zip' isnot found in gobatis_test.pathCustomer"},
+		{sql: `sku = #{order.items[5].sku}`, names: []string{"order"}, values: []interface{}{order},
+			err: "property 'order.items[5].sku' is invalid at 'order.items[5]', index out of range, len is 2"},
+		{sql: `sku = #{order.customer[0]}`, names: []string{"order"}, values: []interface{}{order},
+			err: "property 'order.customer[0]' is invalid, gobatis_test.pathCustomer isnot slice, array or map"},
+		{sql: `a<if test="order.customer.zip == 1">b</if>`, names: []string{"order"}, values: []interface{}{order},
+			err: "'zip' isnot found in gobatis_test.pathCustomer"},
+	} {
+		stmt, err := gobatis.NewMapppedStatement(initCtx, "ddd", gobatis.StatementTypeSelect, gobatis.ResultStruct, test.sql)
+		if err != nil {
+			t.Error("[", idx, "] ", test.sql, err)
+			continue
+		}
+
+		ctx, err := gobatis.NewContext(initCtx.Dialect, initCtx.Mapper, test.names, test.values)
+		if err != nil {
+			t.Error("[", idx, "] ", test.sql, err)
+			continue
+		}
+
+		sqlParams, err := stmt.GenerateSQLs(ctx)
+		if test.err != "" {
+			if err == nil {
+				t.Error("[", idx, "] ", test.sql, "want error")
+			} else if !strings.Contains(err.Error(), test.err) {
+				t.Error("[", idx, "] ", test.sql, "excepted is", test.err)
+				t.Error("[", idx, "] ", test.sql, "actual   is", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Error("[", idx, "] ", test.sql, err)
+			continue
+		}
+
+		if sqlParams[0].SQL != test.excepted {
+			t.Error("[", idx, "] ", test.sql, "excepted is", test.excepted)
+			t.Error("[", idx, "] ", test.sql, "actual   is", sqlParams[0].SQL)
+		}
+		if len(sqlParams[0].Params) != len(test.params) || (len(test.params) > 0 && !reflect.DeepEqual(sqlParams[0].Params, test.params)) {
+			t.Error("[", idx, "] ", test.sql, "excepted is", test.params)
+			t.Error("[", idx, "] ", test.sql, "actual   is", sqlParams[0].Params)
+		}
+	}
+}